@@ -43,6 +43,11 @@ func (c *Cache) Get(key string) (interface{}, error) {
 	return v, nil
 }
 
+// Delete removes a cached value
+func (c *Cache) Delete(key string) {
+	c.cache.Del(key)
+}
+
 // Clear clears cached value
 func (c *Cache) Clear() {
 	c.cache.Clear()
@@ -0,0 +1,323 @@
+// Package search - embedded full-text product index with fuzzy, prefix and synonym matching
+package search
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agnivade/levenshtein"
+)
+
+const (
+	// match weights, an exact term always outranks a prefix or a typo
+	exactMatchWeight  = 1.0
+	prefixMatchWeight = 0.8
+	fuzzyMatchWeight  = 0.6
+
+	// minimum query term length before prefix and fuzzy matching kicks in
+	minPrefixLength = 2
+	minFuzzyLength  = 4
+)
+
+const (
+	FieldName        = "Name"
+	FieldBrand       = "Brand"
+	FieldDescription = "Description"
+)
+
+// FieldBoosts weights matches per field, Name > Brand > Description
+var FieldBoosts = map[string]float64{
+	FieldName:        3,
+	FieldBrand:       2,
+	FieldDescription: 1,
+}
+
+// Document is a product as stored in the index
+type Document struct {
+	ID          string
+	Name        string
+	Brand       string
+	Description string
+}
+
+// Index in-memory inverted index
+type Index struct {
+	mu            sync.RWMutex
+	docs          map[string]Document
+	postings      map[string]map[string]float64 // term -> document id -> field boost
+	terms         []string                      // indexed terms, sorted for prefix lookups
+	termsByLength map[int][]string              // indexed terms by rune count, for typo lookups
+	synonyms      map[string][]string
+}
+
+// New creates an empty index using the given synonyms
+func New(synonyms map[string][]string) *Index {
+	return &Index{
+		docs:          make(map[string]Document),
+		postings:      make(map[string]map[string]float64),
+		termsByLength: make(map[int][]string),
+		synonyms:      synonyms,
+	}
+}
+
+// Replace swaps the indexed documents for docs
+func (idx *Index) Replace(docs []Document) {
+	indexedDocs := make(map[string]Document, len(docs))
+	postings := make(map[string]map[string]float64)
+
+	for _, doc := range docs {
+		indexedDocs[doc.ID] = doc
+		addPostings(postings, doc.ID, doc.Name, FieldBoosts[FieldName])
+		addPostings(postings, doc.ID, doc.Brand, FieldBoosts[FieldBrand])
+		addPostings(postings, doc.ID, doc.Description, FieldBoosts[FieldDescription])
+	}
+
+	terms := make([]string, 0, len(postings))
+	termsByLength := make(map[int][]string)
+	for term := range postings {
+		terms = append(terms, term)
+		length := utf8.RuneCountInString(term)
+		termsByLength[length] = append(termsByLength[length], term)
+	}
+	sort.Strings(terms)
+
+	idx.mu.Lock()
+	idx.docs = indexedDocs
+	idx.postings = postings
+	idx.terms = terms
+	idx.termsByLength = termsByLength
+	idx.mu.Unlock()
+}
+
+// Size returns the number of indexed documents
+func (idx *Index) Size() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search returns ids of documents matching every term of query, best match first
+func (idx *Index) Search(query string) []string {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var scores map[string]float64
+	for _, term := range terms {
+		termScores := idx.scoreTerm(term)
+
+		// documents must match all query terms
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id, score := range scores {
+			termScore, ok := termScores[id]
+			if !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] = score + termScore
+		}
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+
+	// deterministic order for equal scores
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		nameI, nameJ := idx.docs[ids[i]].Name, idx.docs[ids[j]].Name
+		if nameI != nameJ {
+			return nameI < nameJ
+		}
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
+
+// SuggestNames returns documents whose name, or a word in it, starts with prefix.
+// Names starting with the prefix come first, then alphabetical order
+func (idx *Index) SuggestNames(prefix string, limit int) []Document {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var leading, inner []Document
+	for _, doc := range idx.docs {
+		name := strings.ToLower(doc.Name)
+		if strings.HasPrefix(name, prefix) {
+			leading = append(leading, doc)
+		} else if strings.Contains(name, " "+prefix) {
+			inner = append(inner, doc)
+		}
+	}
+
+	byName := func(docs []Document) {
+		sort.Slice(docs, func(i, j int) bool {
+			if docs[i].Name != docs[j].Name {
+				return docs[i].Name < docs[j].Name
+			}
+			return docs[i].ID < docs[j].ID
+		})
+	}
+	byName(leading)
+	byName(inner)
+
+	result := append(leading, inner...)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// scoreTerm scores documents for a single query term and its synonyms
+func (idx *Index) scoreTerm(term string) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, candidate := range append([]string{term}, idx.synonyms[term]...) {
+		for indexedTerm, weight := range idx.matchingTerms(candidate) {
+			weights[indexedTerm] = maxWeight(weights[indexedTerm], weight)
+		}
+	}
+
+	scores := make(map[string]float64)
+	for indexedTerm, weight := range weights {
+		for id, boost := range idx.postings[indexedTerm] {
+			if score := weight * boost; score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+
+	return scores
+}
+
+// matchingTerms returns the indexed terms matching queryTerm by their best match weight. Prefix matches are found by
+// binary search of the sorted terms and typos only among terms whose length is within the allowed edits, so a query
+// is not compared against the whole vocabulary
+func (idx *Index) matchingTerms(queryTerm string) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := idx.postings[queryTerm]; ok {
+		matches[queryTerm] = exactMatchWeight
+	}
+
+	if len(queryTerm) >= minPrefixLength {
+		for i := sort.SearchStrings(idx.terms, queryTerm); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], queryTerm); i++ {
+			if idx.terms[i] != queryTerm {
+				matches[idx.terms[i]] = prefixMatchWeight
+			}
+		}
+	}
+
+	if len(queryTerm) >= minFuzzyLength {
+		length, edits := utf8.RuneCountInString(queryTerm), maxEdits(queryTerm)
+		for candidateLength := length - edits; candidateLength <= length+edits; candidateLength++ {
+			for _, indexedTerm := range idx.termsByLength[candidateLength] {
+				if _, ok := matches[indexedTerm]; ok {
+					continue
+				}
+				if levenshtein.ComputeDistance(queryTerm, indexedTerm) <= edits {
+					matches[indexedTerm] = fuzzyMatchWeight
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// maxEdits allows one typo for short terms and two for long ones
+func maxEdits(term string) int {
+	if len(term) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+func maxWeight(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func addPostings(postings map[string]map[string]float64, id, text string, boost float64) {
+	for _, term := range Tokenize(text) {
+		docs, ok := postings[term]
+		if !ok {
+			docs = make(map[string]float64)
+			postings[term] = docs
+		}
+		if boost > docs[id] {
+			docs[id] = boost
+		}
+	}
+}
+
+// Tokenize splits text into lower-cased alphanumeric terms
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// LoadSynonyms reads a synonyms file where each line is a comma separated group of equivalent terms,
+// e.g. "paracetamol, acetaminophen, panadol"
+func LoadSynonyms(path string) (map[string][]string, error) {
+	synonyms := make(map[string][]string)
+	if path == "" {
+		return synonyms, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var group []string
+		for _, term := range strings.Split(line, ",") {
+			group = append(group, Tokenize(term)...)
+		}
+
+		for _, term := range group {
+			for _, synonym := range group {
+				if synonym != term {
+					synonyms[term] = append(synonyms[term], synonym)
+				}
+			}
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return synonyms, nil
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+
+	//data test
+	index := New(map[string][]string{
+		"acetaminophen": {"paracetamol"},
+	})
+	index.Replace([]Document{
+		{ID: "1", Name: "Panadol Paracetamol 500mg", Brand: "GSK", Description: "Pain relief tablet"},
+		{ID: "2", Name: "Nurofen Ibuprofen 200mg", Brand: "Reckitt", Description: "Pain relief capsule"},
+		{ID: "3", Name: "Vitamin C", Brand: "Panadol", Description: "Immune support"},
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "exact match", query: "ibuprofen", expected: []string{"2"}},
+		{name: "name outranks brand", query: "panadol", expected: []string{"1", "3"}},
+		{name: "prefix match", query: "nuro", expected: []string{"2"}},
+		{name: "typo tolerance", query: "paracetamool", expected: []string{"1"}},
+		{name: "typo of a missing letter", query: "ibuprofn", expected: []string{"2"}},
+		{name: "too many typos", query: "ibupfn", expected: []string{}},
+		{name: "synonym", query: "acetaminophen", expected: []string{"1"}},
+		{name: "all terms must match", query: "pain capsule", expected: []string{"2"}},
+		{name: "no match", query: "insulin", expected: []string{}},
+	}
+
+	for _, test := range tests {
+		result := index.Search(test.query)
+		if len(result) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.name, test.expected, result)
+				break
+			}
+		}
+	}
+}
+
+func TestLoadSynonyms(t *testing.T) {
+
+	//data test
+	path := filepath.Join(t.TempDir(), "synonyms.txt")
+	err := os.WriteFile(path, []byte("# comment\nparacetamol, acetaminophen, panadol\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	synonyms, err := LoadSynonyms(path)
+	if err != nil {
+		t.Fatal("test failed error: ", err)
+	}
+
+	if len(synonyms["paracetamol"]) != 2 || len(synonyms["panadol"]) != 2 {
+		t.Errorf("test failed: unexpected synonyms %v", synonyms)
+	}
+}
@@ -2,6 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/dgraph-io/ristretto"
 	"mpmy-product-service/client/cache"
 	"mpmy-product-service/client/db"
@@ -13,7 +20,16 @@ import (
 	"mpmy-product-service/utils"
 )
 
+const (
+	// shutdownTimeout is how long in-flight requests and queued search logs get to finish on shutdown
+	shutdownTimeout = 30 * time.Second
+)
+
 func main() {
+	// stop on interrupt or termination
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// init config
 	appConfig := config.Init()
 	name := appConfig.General.Name
@@ -39,32 +55,90 @@ func main() {
 
 	// init services
 	loginService := service.NewLoginService()
-	productService := service.NewProductService(dbClient, appConfig.DB, appConfig.OrderCloud, cacheClient)
-	categoryProductService := service.NewCategoryProductService(appConfig.OrderCloud)
+	searchIndexService, err := service.NewSearchIndexService(dbClient, appConfig.DB, appConfig.OrderCloud, appConfig.Search, appConfig.Markets)
+	if err != nil {
+		panic(err)
+	}
+	recentSearchService := service.NewRecentSearchService(dbClient, appConfig.DB, appConfig.Search)
+	searchLogService := service.NewSearchLogService(dbClient, appConfig.DB, appConfig.Search)
+	productService := service.NewProductService(dbClient, appConfig.DB, appConfig.OrderCloud, appConfig.Trending, appConfig.Similarity, cacheClient, searchIndexService, searchLogService)
+	categoryService := service.NewCategoryService(appConfig.OrderCloud, appConfig.Category, cacheClient)
+	categoryProductService := service.NewCategoryProductService(appConfig.OrderCloud, categoryService, productService)
 	priceScheduleService := service.NewPriceScheduleService()
-	categoryService := service.NewCategoryService(appConfig.OrderCloud)
-	recentSearchService := service.NewRecentSearchService(dbClient, appConfig.DB)
+	searchSuggestionService := service.NewSearchSuggestionService(dbClient, appConfig.DB, appConfig.OrderCloud, cacheClient, searchIndexService)
+	searchAnalyticsService := service.NewSearchAnalyticsService(dbClient, appConfig.DB)
+	productListService := service.NewProductListService(dbClient, appConfig.DB, productService)
+	orderEventService := service.NewOrderEventService(dbClient, appConfig.DB, appConfig.OrderCloud, appConfig.Markets)
+	translationService := service.NewTranslationService()
+	promotionService := service.NewPromotionService(dbClient, appConfig.DB)
+	recommendationService := service.NewRecommendationService(dbClient, appConfig.DB, appConfig.Recommend)
 
 	// fetch order cloud access token asynchronously
 	go func() {
-		loginService.StartAccessTokenFetcher(context.Background(), appConfig.OrderCloud)
+		loginService.StartAccessTokenFetcher(ctx, appConfig.OrderCloud)
 	}()
 
 	// start trending products processor
 	go func() {
-		productService.StartTrendingProductsProcessor(context.Background())
+		productService.StartTrendingProductsProcessor(ctx)
+	}()
+
+	// start recommendation builder
+	go func() {
+		recommendationService.StartRecommendationBuilder(ctx)
+	}()
+
+	// start recent search pruner
+	go func() {
+		recentSearchService.StartRecentSearchPruner(ctx)
+	}()
+
+	// start search index syncer
+	go func() {
+		searchIndexService.StartSearchIndexSyncer(ctx, loginService)
+	}()
+
+	// start search log writer, stopped only after the server so searches of in-flight requests are kept
+	searchLogCtx, stopSearchLog := context.WithCancel(context.Background())
+	go func() {
+		searchLogService.StartSearchLogWriter(searchLogCtx)
 	}()
 
 	// init server
-	appServer := server.NewServer(loginService, productService, categoryProductService, priceScheduleService, categoryService, recentSearchService)
+	appServer := server.NewServer(loginService, productService, categoryProductService, priceScheduleService, categoryService, recentSearchService, searchSuggestionService, searchAnalyticsService, productListService, orderEventService, translationService, promotionService)
 	r := appServer.RoutesHandler(appConfig)
 
 	// start server
 	utils.Logger(constants.DefaultLogLevel, name+" start listening at http://localhost:"+port)
 	utils.Logger(constants.DefaultLogLevel, "==> 🚀 %s listening at %s\n"+name+port)
-	err = r.Run(":" + port)
+	httpServer := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+	go func() {
+		err := httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.Logger(constants.ErrorLogLevel, "server stopped: ", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	utils.Logger(constants.DefaultLogLevel, name+" shutting down")
+
+	// graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = httpServer.Shutdown(shutdownCtx)
 	if err != nil {
-		return
+		utils.Logger(constants.ErrorLogLevel, "server shutdown: ", err)
 	}
 
+	// flush queued search logs
+	stopSearchLog()
+	err = searchLogService.Wait(shutdownCtx)
+	if err != nil {
+		utils.Logger(constants.ErrorLogLevel, "search log flush: ", err)
+	}
 }
@@ -1,14 +1,24 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 )
 
 type AppConfig struct {
 	General    GeneralConfig
 	OrderCloud OrderCloudConfig
 	DB         DBConfig
+	Search     SearchConfig
+	Trending   TrendingConfig
+	Recommend  RecommendationConfig
+	Similarity SimilarityConfig
+	Category   CategoryConfig
+	Markets    MarketsConfig
 }
 
 type GeneralConfig struct {
@@ -26,6 +36,7 @@ type OrderCloudConfig struct {
 	AccessTokenFetchDuration int
 	OrderCloudEngine         string
 	SellerCenterMiddleware   string
+	CatalogFacets            map[string][]string
 }
 
 type DBConfig struct {
@@ -38,6 +49,93 @@ type DBConfig struct {
 	SSLMode  string
 }
 
+type SearchConfig struct {
+	IndexEnabled              bool
+	SynonymsFile              string
+	IndexSyncDuration         int
+	RecentSearchLimit         int
+	RecentSearchRetentionDays int
+	RecentSearchPruneDuration int
+	SearchLogQueueSize        int
+	SearchLogBatchSize        int
+	SearchLogFlushInterval    int
+}
+
+// TrendingConfig - trending products are scored over the last WindowHours of orders, every order line adds
+// its quantity raised to QuantityExponent, halved for every HalfLifeHours of its age
+type TrendingConfig struct {
+	WindowHours      int
+	HalfLifeHours    float64
+	QuantityExponent float64
+	Limit            int
+	GroupLimit       int
+	RefreshDuration  int
+}
+
+// RecommendationConfig - products are related when they share at least MinSupport orders or users within the
+// last WindowDays, only the best MaxPerProduct related products are kept. Rebuilt every RefreshDuration minutes
+type RecommendationConfig struct {
+	WindowDays      int
+	MinSupport      int
+	MaxPerProduct   int
+	RefreshDuration int
+}
+
+// SimilarityConfig - similar products are products of the product's category ranked by the summed weights of
+// attributes they share with it, prices within PriceBandRatio of its price share its price band. Up to
+// CandidateLimit candidates are fetched per weighted attribute, and a weight of 0 switches an attribute off
+type SimilarityConfig struct {
+	TherapeuticClassWeight float64
+	ManufacturerWeight     float64
+	BrandWeight            float64
+	ProductTypeWeight      float64
+	UnitOfMeasureWeight    float64
+	PriceBandWeight        float64
+	PriceBandRatio         float64
+	CandidateLimit         int
+}
+
+// CategoryConfig - category trees are cached per catalog for CacheTTL minutes
+type CategoryConfig struct {
+	CacheTTL int
+}
+
+// MarketConfig - a market is served from its own order cloud catalog, prices are in its currency, content in its
+// locale and sale windows follow its timezone
+type MarketConfig struct {
+	ID        string
+	CatalogID string
+	Currency  string
+	Locale    string
+	Timezone  string
+}
+
+// Location returns the timezone of the market, UTC when it cannot be loaded
+func (market MarketConfig) Location() *time.Location {
+	location, err := time.LoadLocation(market.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// MarketsConfig - markets by id, requests that select no market are served for DefaultMarket
+type MarketsConfig struct {
+	DefaultMarket string
+	Markets       map[string]MarketConfig
+}
+
+// Market returns the market with the id, ok is false when there is none
+func (markets MarketsConfig) Market(id string) (market MarketConfig, ok bool) {
+	market, ok = markets.Markets[strings.ToLower(strings.TrimSpace(id))]
+	return market, ok
+}
+
+// Default returns the default market
+func (markets MarketsConfig) Default() MarketConfig {
+	return markets.Markets[markets.DefaultMarket]
+}
+
 // Init - prepares the config from environmental variables
 func Init() AppConfig {
 	appConfig := AppConfig{
@@ -53,6 +151,7 @@ func Init() AppConfig {
 			AccessTokenFetchDuration: panicIfEmpty(GetInt(os.Getenv("ORDER_CLOUD_ACCESS_TOKEN_FETCH_DURATION")), "ORDER_CLOUD_ACCESS_TOKEN_FETCH_DURATION").(int),
 			OrderCloudEngine:         panicIfEmpty(os.Getenv("ORDER_CLOUD_ENGINE"), "ORDER_CLOUD_ENGINE").(string),
 			SellerCenterMiddleware:   panicIfEmpty(os.Getenv("SELLER_CENTER_MIDDLEWARE"), "SELLER_CENTER_MIDDLEWARE").(string),
+			CatalogFacets:            GetStringSliceMap(os.Getenv("ORDER_CLOUD_CATALOG_FACETS")),
 		},
 		DB: DBConfig{
 			Host:     panicIfEmpty(os.Getenv("DB_HOST"), "DB_HOST").(string),
@@ -63,6 +162,48 @@ func Init() AppConfig {
 			Schema:   panicIfEmpty(os.Getenv("DB_SCHEMA"), "DB_SCHEMA").(string),
 			SSLMode:  panicIfEmpty(os.Getenv("DB_SSL_MODE"), "DB_SSL_MODE").(string),
 		},
+		Search: SearchConfig{
+			IndexEnabled:              GetBool(os.Getenv("SEARCH_INDEX_ENABLED")),
+			SynonymsFile:              os.Getenv("SEARCH_SYNONYMS_FILE"),
+			IndexSyncDuration:         GetNonEmptyData(GetInt(os.Getenv("SEARCH_INDEX_SYNC_DURATION")), 30).(int),
+			RecentSearchLimit:         GetNonEmptyData(GetInt(os.Getenv("RECENT_SEARCH_LIMIT")), 50).(int),
+			RecentSearchRetentionDays: GetNonEmptyData(GetInt(os.Getenv("RECENT_SEARCH_RETENTION_DAYS")), 90).(int),
+			RecentSearchPruneDuration: GetNonEmptyData(GetInt(os.Getenv("RECENT_SEARCH_PRUNE_DURATION")), 360).(int),
+			SearchLogQueueSize:        GetNonEmptyData(GetInt(os.Getenv("SEARCH_LOG_QUEUE_SIZE")), 1000).(int),
+			SearchLogBatchSize:        GetNonEmptyData(GetInt(os.Getenv("SEARCH_LOG_BATCH_SIZE")), 100).(int),
+			SearchLogFlushInterval:    GetNonEmptyData(GetInt(os.Getenv("SEARCH_LOG_FLUSH_INTERVAL")), 5).(int),
+		},
+		Trending: TrendingConfig{
+			WindowHours:      GetNonEmptyData(GetInt(os.Getenv("TRENDING_WINDOW_HOURS")), 48).(int),
+			HalfLifeHours:    GetNonEmptyData(GetFloat(os.Getenv("TRENDING_HALF_LIFE_HOURS")), 24.0).(float64),
+			QuantityExponent: GetNonEmptyData(GetFloat(os.Getenv("TRENDING_QUANTITY_EXPONENT")), 0.5).(float64),
+			Limit:            GetNonEmptyData(GetInt(os.Getenv("TRENDING_LIMIT")), 10).(int),
+			GroupLimit:       GetNonEmptyData(GetInt(os.Getenv("TRENDING_GROUP_LIMIT")), 10).(int),
+			RefreshDuration:  GetNonEmptyData(GetInt(os.Getenv("TRENDING_REFRESH_DURATION")), 60).(int),
+		},
+		Recommend: RecommendationConfig{
+			WindowDays:      GetNonEmptyData(GetInt(os.Getenv("RECOMMENDATION_WINDOW_DAYS")), 180).(int),
+			MinSupport:      GetNonEmptyData(GetInt(os.Getenv("RECOMMENDATION_MIN_SUPPORT")), 2).(int),
+			MaxPerProduct:   GetNonEmptyData(GetInt(os.Getenv("RECOMMENDATION_MAX_PER_PRODUCT")), 20).(int),
+			RefreshDuration: GetNonEmptyData(GetInt(os.Getenv("RECOMMENDATION_REFRESH_DURATION")), 360).(int),
+		},
+		Similarity: SimilarityConfig{
+			TherapeuticClassWeight: GetFloatOrDefault("SIMILARITY_THERAPEUTIC_CLASS_WEIGHT", 4.0),
+			ManufacturerWeight:     GetFloatOrDefault("SIMILARITY_MANUFACTURER_WEIGHT", 2.0),
+			BrandWeight:            GetFloatOrDefault("SIMILARITY_BRAND_WEIGHT", 2.0),
+			ProductTypeWeight:      GetFloatOrDefault("SIMILARITY_PRODUCT_TYPE_WEIGHT", 1.5),
+			UnitOfMeasureWeight:    GetFloatOrDefault("SIMILARITY_UNIT_OF_MEASURE_WEIGHT", 1.0),
+			PriceBandWeight:        GetFloatOrDefault("SIMILARITY_PRICE_BAND_WEIGHT", 1.0),
+			PriceBandRatio:         GetNonEmptyData(GetFloat(os.Getenv("SIMILARITY_PRICE_BAND_RATIO")), 0.25).(float64),
+			CandidateLimit:         GetNonEmptyData(GetInt(os.Getenv("SIMILARITY_CANDIDATE_LIMIT")), 100).(int),
+		},
+		Category: CategoryConfig{
+			CacheTTL: GetNonEmptyData(GetInt(os.Getenv("CATEGORY_CACHE_TTL")), 30).(int),
+		},
+		Markets: GetMarkets(
+			GetNonEmptyData(os.Getenv("MARKETS"), "my:zp-my,MYR,ms-MY,Asia/Kuala_Lumpur").(string),
+			GetNonEmptyData(os.Getenv("DEFAULT_MARKET"), "my").(string),
+		),
 	}
 
 	return appConfig
@@ -76,6 +217,28 @@ func GetInt(val string) int {
 	return i
 }
 
+func GetFloat(val string) float64 {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// GetFloatOrDefault - reads the float environment variable key, defaultVal when it is unset or not a number.
+// Unlike GetNonEmptyData an explicit 0 is kept, e.g. to switch off a weight
+func GetFloatOrDefault(key string, defaultVal float64) float64 {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		return defaultVal
+	}
+	return f
+}
+
 func GetBool(val string) bool {
 	b, err := strconv.ParseBool(val)
 	if err != nil {
@@ -84,6 +247,55 @@ func GetBool(val string) bool {
 	return b
 }
 
+// GetStringSliceMap - parses values of the form "key1:a,b;key2:c" into a map of string slices
+func GetStringSliceMap(val string) map[string][]string {
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(val, ";") {
+		key, values, found := strings.Cut(entry, ":")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		for _, value := range strings.Split(values, ",") {
+			value = strings.TrimSpace(value)
+			if value != "" {
+				result[key] = append(result[key], value)
+			}
+		}
+	}
+	return result
+}
+
+// GetMarkets - parses markets of the form "my:zp-my,MYR,ms-MY,Asia/Kuala_Lumpur;sg:zp-sg,SGD,en-SG,Asia/Singapore",
+// that is id:catalog,currency,locale,timezone, and panics on malformed markets or a missing default market
+func GetMarkets(val string, defaultMarket string) MarketsConfig {
+	markets := MarketsConfig{
+		DefaultMarket: strings.ToLower(defaultMarket),
+		Markets:       make(map[string]MarketConfig),
+	}
+	for id, values := range GetStringSliceMap(val) {
+		if len(values) != 4 {
+			panic(fmt.Sprintf("MARKETS config of %s must be catalog,currency,locale,timezone", id))
+		}
+		if _, err := time.LoadLocation(values[3]); err != nil {
+			panic(fmt.Sprintf("MARKETS config of %s has invalid timezone: %v", id, err))
+		}
+
+		id = strings.ToLower(id)
+		markets.Markets[id] = MarketConfig{
+			ID:        id,
+			CatalogID: values[0],
+			Currency:  strings.ToUpper(values[1]),
+			Locale:    values[2],
+			Timezone:  values[3],
+		}
+	}
+	if _, ok := markets.Markets[markets.DefaultMarket]; !ok {
+		panic("DEFAULT_MARKET config " + defaultMarket + " is not one of MARKETS")
+	}
+	return markets
+}
+
 func GetNonEmptyData(val interface{}, defaultVal interface{}) interface{} {
 	switch val.(type) {
 	case string:
@@ -94,6 +306,10 @@ func GetNonEmptyData(val interface{}, defaultVal interface{}) interface{} {
 		if val.(int) == 0 {
 			return defaultVal
 		}
+	case float64:
+		if val.(float64) == 0 {
+			return defaultVal
+		}
 	}
 
 	return val
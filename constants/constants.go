@@ -3,8 +3,27 @@ package constants
 const (
 	TokenString = "tokenString"
 	RequestBody = "RequestBody"
-	CatalogID   = "zp-my"
 	Depth       = "all"
+	AdminRole   = "admin"
+	BuyerClaim  = "buyer_id"
+	MarketClaim = "market"
+
+	// UserGroupsClaim lists the order cloud user groups of the user's buyer the user is in, in order of preference
+	UserGroupsClaim = "user_groups"
+)
+
+const (
+	// MarketHeader selects the market of a request whose token carries no market claim
+	MarketHeader = "X-Market"
+
+	// MarketKey is the gin context key of the market a request is served for
+	MarketKey = "MARKET"
+
+	// AcceptLanguageHeader lists the locales content is preferred in
+	AcceptLanguageHeader = "Accept-Language"
+
+	// LocalesKey is the gin context key of the request's preferred locales
+	LocalesKey = "LOCALES"
 )
 
 const (
@@ -26,5 +45,22 @@ const (
 )
 
 const (
-	MinimumCategoriesShown int = 50
+	// CategoriesPageSize is the number of categories requested per order cloud page, the most order cloud allows
+	CategoriesPageSize int = 100
+
+	// MaxConcurrentCategoryPages is the most category pages requested from order cloud at once
+	MaxConcurrentCategoryPages int = 4
+
+	// CategoryProductIDBatchSize is the most product ids a category product assignment request filters on
+	CategoryProductIDBatchSize int = 50
+
+	// ProductAssignmentsPageSize is the number of product assignments requested per order cloud page, the most order
+	// cloud allows
+	ProductAssignmentsPageSize int = 100
+
+	// MaxConcurrentProductAssignmentPages is the most product assignment pages requested from order cloud at once
+	MaxConcurrentProductAssignmentPages int = 4
+
+	// ProductAssignmentIDBatchSize is the most product ids a product assignment request filters on
+	ProductAssignmentIDBatchSize int = 50
 )
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
 	"strconv"
+	"strings"
 )
 
 func GinContextFromContext(ctx context.Context) (*gin.Context, error) {
@@ -23,13 +26,141 @@ func GinContextFromContext(ctx context.Context) (*gin.Context, error) {
 	return gc, nil
 }
 
+// GetCurrentUserID returns the user of the request's token, or nil when the request has no user
 func GetCurrentUserID(ctx context.Context) (*string, error) {
 	gc, err := GinContextFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	claims := gc.Value("USER_CLAIM").(jwt.MapClaims)
+	claims, ok := gc.Value("USER_CLAIM").(jwt.MapClaims)
+	if !ok {
+		return nil, nil
+	}
+	userID, ok := claims["id"].(float64)
+	if !ok {
+		return nil, nil
+	}
 
-	id := strconv.FormatFloat(claims["id"].(float64), 'f', 0, 64)
+	id := strconv.FormatFloat(userID, 'f', 0, 64)
 	return &id, nil
 }
+
+// IsCurrentUserAdmin reports whether the user's token carries the admin role in its "role" or "roles" claim
+func IsCurrentUserAdmin(ctx context.Context) (bool, error) {
+	gc, err := GinContextFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	claims, ok := gc.Value("USER_CLAIM").(jwt.MapClaims)
+	if !ok {
+		return false, nil
+	}
+
+	if role, ok := claims["role"].(string); ok && role == constants.AdminRole {
+		return true, nil
+	}
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if role == constants.AdminRole {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// GetCurrentBuyerID returns the buyer organization from the user's token, or nil when the token has none
+func GetCurrentBuyerID(ctx context.Context) (*string, error) {
+	gc, err := GinContextFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := gc.Value("USER_CLAIM").(jwt.MapClaims)
+	if !ok {
+		return nil, nil
+	}
+
+	var buyerID string
+	switch value := claims[constants.BuyerClaim].(type) {
+	case string:
+		buyerID = value
+	case float64:
+		buyerID = strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	if buyerID == "" {
+		return nil, nil
+	}
+
+	return &buyerID, nil
+}
+
+// GetCurrentPriceParty returns who products are priced for, the buyer and user groups of the user's token. User
+// groups are read from a list or a comma separated claim, and are left out without a buyer
+func GetCurrentPriceParty(ctx context.Context) (buyerID string, userGroupIDs []string, err error) {
+	buyer, err := GetCurrentBuyerID(ctx)
+	if err != nil || buyer == nil {
+		return "", nil, err
+	}
+
+	gc, err := GinContextFromContext(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	claims, _ := gc.Value("USER_CLAIM").(jwt.MapClaims)
+
+	var claimedUserGroupIDs []string
+	switch value := claims[constants.UserGroupsClaim].(type) {
+	case []interface{}:
+		for _, userGroupID := range value {
+			if id, ok := userGroupID.(string); ok {
+				claimedUserGroupIDs = append(claimedUserGroupIDs, id)
+			}
+		}
+	case string:
+		claimedUserGroupIDs = strings.Split(value, ",")
+	}
+	for _, userGroupID := range claimedUserGroupIDs {
+		if userGroupID = strings.TrimSpace(userGroupID); userGroupID != "" {
+			userGroupIDs = append(userGroupIDs, userGroupID)
+		}
+	}
+
+	return *buyer, userGroupIDs, nil
+}
+
+// GetCurrentMarket returns the market selected for the request by the market middleware
+func GetCurrentMarket(ctx context.Context) (config.MarketConfig, error) {
+	gc, err := GinContextFromContext(ctx)
+	if err != nil {
+		return config.MarketConfig{}, err
+	}
+	market, ok := gc.Value(constants.MarketKey).(config.MarketConfig)
+	if !ok {
+		return config.MarketConfig{}, fmt.Errorf("market not selected")
+	}
+
+	return market, nil
+}
+
+// GetCurrentLocales returns the locales content is requested in by preference: the locale argument, the locales of
+// the request's Accept-Language header, then the market's locale
+func GetCurrentLocales(ctx context.Context, locale *string) ([]string, error) {
+	gc, err := GinContextFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locales []string
+	if locale != nil && *locale != "" {
+		locales = append(locales, *locale)
+	}
+	if acceptLocales, ok := gc.Value(constants.LocalesKey).([]string); ok {
+		locales = append(locales, acceptLocales...)
+	}
+	if market, ok := gc.Value(constants.MarketKey).(config.MarketConfig); ok && market.Locale != "" {
+		locales = append(locales, market.Locale)
+	}
+
+	return locales, nil
+}
@@ -3,19 +3,24 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
 type CategoryItems struct {
-	ID          string           `json:"ID"`
-	Name        string           `json:"Name"`
-	Description string           `json:"Description"`
-	ListOrder   int              `json:"ListOrder"`
-	Active      bool             `json:"Active"`
-	ParentID    string           `json:"ParentID"`
-	ChildCount  int              `json:"ChildCount"`
-	Xp          interface{}      `json:"Xp"`
-	ChildData   []*CategoryItems `json:"ChildData"`
+	ID           string                `json:"ID"`
+	Name         string                `json:"Name"`
+	Description  string                `json:"Description"`
+	ListOrder    int                   `json:"ListOrder"`
+	Active       bool                  `json:"Active"`
+	ParentID     string                `json:"ParentID"`
+	ChildCount   int                   `json:"ChildCount"`
+	Xp           interface{}           `json:"Xp"`
+	ChildData    []*CategoryItems      `json:"ChildData"`
+	Path         []*CategoryItems      `json:"Path"`
+	ProductCount *CategoryProductCount `json:"ProductCount"`
 }
 
 type CategoryMeta struct {
@@ -27,6 +32,24 @@ type CategoryMeta struct {
 	NextPageKey string `json:"NextPageKey"`
 }
 
+type CategoryProductConnection struct {
+	Edges      []*CategoryProductEdge `json:"Edges"`
+	PageInfo   *PageInfo              `json:"PageInfo"`
+	TotalCount int                    `json:"TotalCount"`
+}
+
+type CategoryProductCount struct {
+	Direct int `json:"Direct"`
+	Total  int `json:"Total"`
+}
+
+type CategoryProductEdge struct {
+	Cursor     string       `json:"Cursor"`
+	CategoryID string       `json:"CategoryID"`
+	ListOrder  int          `json:"ListOrder"`
+	Product    *ProductItem `json:"Product"`
+}
+
 type CategoryResponse struct {
 	Meta  *CategoryMeta    `json:"Meta"`
 	Items []*CategoryItems `json:"Items"`
@@ -38,6 +61,40 @@ type Draft struct {
 	RejectionReason *string `json:"RejectionReason"`
 }
 
+type FavoriteChangeInput struct {
+	ProductID  string    `json:"ProductID"`
+	IsFavorite bool      `json:"IsFavorite"`
+	ChangedAt  time.Time `json:"ChangedAt"`
+}
+
+type FavoriteProductConnection struct {
+	Edges                 []*FavoriteProductEdge `json:"Edges"`
+	PageInfo              *PageInfo              `json:"PageInfo"`
+	TotalCount            int                    `json:"TotalCount"`
+	UnavailableProductIDs []string               `json:"UnavailableProductIDs"`
+}
+
+type FavoriteProductEdge struct {
+	Cursor      string       `json:"Cursor"`
+	FavoritedAt *time.Time   `json:"FavoritedAt"`
+	Product     *ProductItem `json:"Product"`
+}
+
+type FavoriteProductPayload struct {
+	ProductID  string               `json:"ProductID"`
+	IsFavorite bool                 `json:"IsFavorite"`
+	Favorite   *UserProductFavorite `json:"Favorite"`
+}
+
+type FrequentlyPurchasedProduct struct {
+	ProductID           string       `json:"ProductID" db:"product_id"`
+	OrderCount          int          `json:"OrderCount" db:"order_count"`
+	LastOrderedQuantity int          `json:"LastOrderedQuantity" db:"last_ordered_quantity"`
+	LastOrderedAt       time.Time    `json:"LastOrderedAt" db:"last_ordered_at"`
+	Score               float64      `json:"Score" db:"score"`
+	Product             *ProductItem `json:"Product"`
+}
+
 type GetBuySku struct {
 	Sku *string `json:"SKU"`
 	Qty *string `json:"Qty"`
@@ -59,6 +116,19 @@ type LatestProductItems struct {
 	Specs         []*string                `json:"Specs"`
 	PriceSchedule *NewProductPriceSchedule `json:"PriceSchedule"`
 	Product       *ProductItem             `json:"Product"`
+	Reason        *RecommendationReason    `json:"Reason"`
+}
+
+type ListFacet struct {
+	Name   *string           `json:"Name"`
+	XpPath *string           `json:"XpPath"`
+	Values []*ListFacetValue `json:"Values"`
+}
+
+type ListFacetValue struct {
+	Value      *string `json:"Value"`
+	Count      *int    `json:"Count"`
+	IsSelected bool    `json:"IsSelected"`
 }
 
 type NewProductPriceSchedule struct {
@@ -87,10 +157,51 @@ type OrderCloudMeta struct {
 	NextPageKey *string `json:"NextPageKey"`
 }
 
+type OrderEventInput struct {
+	OrderID      string                 `json:"OrderID"`
+	UserID       string                 `json:"UserID"`
+	SubmittedAt  time.Time              `json:"SubmittedAt"`
+	Lines        []*OrderLineEventInput `json:"Lines"`
+	MarketID     *string                `json:"MarketID"`
+	PromotionIDs []string               `json:"PromotionIDs"`
+}
+
+type OrderLineEventInput struct {
+	LineID     string  `json:"LineID"`
+	ProductID  string  `json:"ProductID"`
+	Quantity   int     `json:"Quantity"`
+	CategoryID *string `json:"CategoryID"`
+	SupplierID *string `json:"SupplierID"`
+}
+
+type PageInfo struct {
+	EndCursor   *string `json:"EndCursor"`
+	HasNextPage bool    `json:"HasNextPage"`
+}
+
 type PriceBreak struct {
-	Quantity  *int     `json:"Quantity"`
-	Price     *float64 `json:"Price"`
-	SalePrice *float64 `json:"SalePrice"`
+	Quantity       *int     `json:"Quantity"`
+	Price          *float64 `json:"Price"`
+	SalePrice      *float64 `json:"SalePrice"`
+	PriceMoney     *Money   `json:"PriceMoney"`
+	SalePriceMoney *Money   `json:"SalePriceMoney"`
+}
+
+type PriceQuote struct {
+	ProductID          string  `json:"ProductID"`
+	PriceScheduleID    *string `json:"PriceScheduleID"`
+	Currency           *string `json:"Currency"`
+	Quantity           int     `json:"Quantity"`
+	BreakQuantity      int     `json:"BreakQuantity"`
+	ListUnitPrice      float64 `json:"ListUnitPrice"`
+	UnitPrice          float64 `json:"UnitPrice"`
+	ExtendedPrice      float64 `json:"ExtendedPrice"`
+	Savings            float64 `json:"Savings"`
+	IsOnSale           bool    `json:"IsOnSale"`
+	ListUnitPriceMoney *Money  `json:"ListUnitPriceMoney"`
+	UnitPriceMoney     *Money  `json:"UnitPriceMoney"`
+	ExtendedPriceMoney *Money  `json:"ExtendedPriceMoney"`
+	SavingsMoney       *Money  `json:"SavingsMoney"`
 }
 
 type PriceScheduleItem struct {
@@ -147,38 +258,67 @@ type ProductImage struct {
 }
 
 type ProductItem struct {
-	OwnerID                *string            `json:"OwnerID"`
-	DefaultPriceScheduleID *string            `json:"DefaultPriceScheduleID"`
-	AutoForward            *bool              `json:"AutoForward"`
-	ID                     *string            `json:"ID"`
-	Name                   *string            `json:"Name"`
-	Description            *string            `json:"Description"`
-	QuantityMultiplier     *int               `json:"QuantityMultiplier"`
-	ShipWeight             *float64           `json:"ShipWeight"`
-	ShipHeight             *float64           `json:"ShipHeight"`
-	ShipWidth              *float64           `json:"ShipWidth"`
-	ShipLength             *float64           `json:"ShipLength"`
-	Active                 *bool              `json:"Active"`
-	SpecCount              *int               `json:"SpecCount"`
-	VariantCount           *int               `json:"VariantCount"`
-	ShipFromAddressID      *string            `json:"ShipFromAddressID"`
-	Inventory              *Inventory         `json:"Inventory"`
-	DefaultSupplierID      *string            `json:"DefaultSupplierID"`
-	AllSuppliersCanSell    *bool              `json:"AllSuppliersCanSell"`
-	Returnable             *bool              `json:"Returnable"`
-	Xp                     *ProductXp         `json:"XP"`
-	IsFavorite             bool               `json:"IsFavorite"`
-	PriceSchedule          *PriceScheduleItem `json:"PriceSchedule"`
+	OwnerID                *string              `json:"OwnerID"`
+	DefaultPriceScheduleID *string              `json:"DefaultPriceScheduleID"`
+	AutoForward            *bool                `json:"AutoForward"`
+	ID                     *string              `json:"ID"`
+	Name                   *string              `json:"Name"`
+	Description            *string              `json:"Description"`
+	QuantityMultiplier     *int                 `json:"QuantityMultiplier"`
+	ShipWeight             *float64             `json:"ShipWeight"`
+	ShipHeight             *float64             `json:"ShipHeight"`
+	ShipWidth              *float64             `json:"ShipWidth"`
+	ShipLength             *float64             `json:"ShipLength"`
+	Active                 *bool                `json:"Active"`
+	SpecCount              *int                 `json:"SpecCount"`
+	VariantCount           *int                 `json:"VariantCount"`
+	ShipFromAddressID      *string              `json:"ShipFromAddressID"`
+	Inventory              *Inventory           `json:"Inventory"`
+	DefaultSupplierID      *string              `json:"DefaultSupplierID"`
+	AllSuppliersCanSell    *bool                `json:"AllSuppliersCanSell"`
+	Returnable             *bool                `json:"Returnable"`
+	Xp                     *ProductXp           `json:"XP"`
+	IsFavorite             bool                 `json:"IsFavorite"`
+	PriceSchedule          *PriceScheduleItem   `json:"PriceSchedule"`
+	Categories             []*CategoryItems     `json:"Categories"`
+	EffectivePrice         *PriceQuote          `json:"EffectivePrice"`
+	ApplicablePromotions   []*PromotionDiscount `json:"ApplicablePromotions"`
+	BestPromotion          *PromotionDiscount   `json:"BestPromotion"`
+}
+
+type ProductList struct {
+	ID          int                `json:"ID" db:"id"`
+	Name        string             `json:"Name" db:"name"`
+	Description *string            `json:"Description" db:"description"`
+	ShareToken  *string            `json:"ShareToken" db:"share_token"`
+	ItemCount   int                `json:"ItemCount" db:"item_count"`
+	IsOwner     bool               `json:"IsOwner"`
+	CreatedAt   time.Time          `json:"CreatedAt" db:"created_at"`
+	UpdatedAt   time.Time          `json:"UpdatedAt" db:"updated_at"`
+	Items       []*ProductListItem `json:"Items"`
+}
+
+type ProductListItem struct {
+	ID        int          `json:"ID" db:"id"`
+	ListID    int          `json:"ListID" db:"list_id"`
+	ProductID string       `json:"ProductID" db:"product_id"`
+	Quantity  int          `json:"Quantity" db:"quantity"`
+	Notes     *string      `json:"Notes" db:"notes"`
+	Position  int          `json:"Position" db:"position"`
+	CreatedAt time.Time    `json:"CreatedAt" db:"created_at"`
+	UpdatedAt time.Time    `json:"UpdatedAt" db:"updated_at"`
+	Product   *ProductItem `json:"Product"`
 }
 
 type ProductMeta struct {
-	Facets      interface{} `json:"Facets"`
-	Page        *int        `json:"Page"`
-	PageSize    *int        `json:"PageSize"`
-	TotalCount  *int        `json:"TotalCount"`
-	TotalPages  *int        `json:"TotalPages"`
-	ItemRange   []*int      `json:"ItemRange"`
-	NextPageKey *string     `json:"NextPageKey"`
+	Facets      []*ListFacet `json:"Facets"`
+	Page        *int         `json:"Page"`
+	PageSize    *int         `json:"PageSize"`
+	TotalCount  *int         `json:"TotalCount"`
+	TotalPages  *int         `json:"TotalPages"`
+	ItemRange   []*int       `json:"ItemRange"`
+	NextPageKey *string      `json:"NextPageKey"`
+	SearchID    *string      `json:"SearchID"`
 }
 
 type ProductPromotions struct {
@@ -244,49 +384,123 @@ type ProductTax struct {
 }
 
 type ProductXp struct {
-	Company                   *string              `json:"Company"`
-	Status                    *string              `json:"Status"`
-	ArtworkRequired           *bool                `json:"ArtworkRequired"`
-	ProductRegistrationNumber *string              `json:"ProductRegistrationNumber"`
-	Facets                    *ProductFacet        `json:"Facets"`
-	Manufacturer              *string              `json:"Manufacturer"`
-	FreeShipping              *bool                `json:"FreeShipping"`
-	UnitOfMeasure             *UnitOfMeasure       `json:"UnitOfMeasure"`
-	Images                    []*ProductImage      `json:"Images"`
-	ProductType               *string              `json:"ProductType"`
-	CountryOfOrigin           *string              `json:"CountryOfOrigin"`
-	PromotionEligible         *bool                `json:"PromotionEligible"`
-	PromotionExists           *bool                `json:"PromotionExists"`
-	Workflow                  *Workflow            `json:"Workflow"`
-	ProductBatch              *ProductBatch        `json:"ProductBatch"`
-	Tax                       *ProductTax          `json:"Tax"`
-	TherapeuticClass          *string              `json:"TherapeuticClass"`
-	HasVariants               *bool                `json:"HasVariants"`
-	Accessorials              []*string            `json:"Accessorials"`
-	FreeShippingMessage       *string              `json:"FreeShippingMessage"`
-	IsResale                  *bool                `json:"IsResale"`
-	Brand                     *string              `json:"Brand"`
-	CustomerRating            *string              `json:"CustomerRating"`
-	SizeTier                  *string              `json:"SizeTier"`
-	IntegrationData           *string              `json:"IntegrationData"`
-	Notes                     *string              `json:"Notes"`
-	Currency                  *string              `json:"Currency"`
-	Documents                 []*ProductDocument   `json:"Documents"`
-	Promotions                []*ProductPromotions `json:"Promotions"`
-	SupplierImage             *string              `json:"SupplierImage"`
+	Company                   *string                `json:"Company"`
+	Status                    *string                `json:"Status"`
+	ArtworkRequired           *bool                  `json:"ArtworkRequired"`
+	ProductRegistrationNumber *string                `json:"ProductRegistrationNumber"`
+	Facets                    *ProductFacet          `json:"Facets"`
+	Manufacturer              *string                `json:"Manufacturer"`
+	FreeShipping              *bool                  `json:"FreeShipping"`
+	UnitOfMeasure             *UnitOfMeasure         `json:"UnitOfMeasure"`
+	Images                    []*ProductImage        `json:"Images"`
+	ProductType               *string                `json:"ProductType"`
+	CountryOfOrigin           *string                `json:"CountryOfOrigin"`
+	PromotionEligible         *bool                  `json:"PromotionEligible"`
+	PromotionExists           *bool                  `json:"PromotionExists"`
+	Workflow                  *Workflow              `json:"Workflow"`
+	ProductBatch              *ProductBatch          `json:"ProductBatch"`
+	Tax                       *ProductTax            `json:"Tax"`
+	TherapeuticClass          *string                `json:"TherapeuticClass"`
+	HasVariants               *bool                  `json:"HasVariants"`
+	Accessorials              []*string              `json:"Accessorials"`
+	FreeShippingMessage       *string                `json:"FreeShippingMessage"`
+	IsResale                  *bool                  `json:"IsResale"`
+	Brand                     *string                `json:"Brand"`
+	CustomerRating            *string                `json:"CustomerRating"`
+	SizeTier                  *string                `json:"SizeTier"`
+	IntegrationData           *string                `json:"IntegrationData"`
+	Notes                     *string                `json:"Notes"`
+	Currency                  *string                `json:"Currency"`
+	Documents                 []*ProductDocument     `json:"Documents"`
+	Promotions                []*ProductPromotions   `json:"Promotions"`
+	SupplierImage             *string                `json:"SupplierImage"`
+	Translations              map[string]interface{} `json:"Translations"`
+}
+
+type PromotionDiscount struct {
+	Promotion            *ProductPromotions `json:"Promotion"`
+	Discount             float64            `json:"Discount"`
+	DiscountedPrice      float64            `json:"DiscountedPrice"`
+	RequiresCode         bool               `json:"RequiresCode"`
+	DiscountMoney        *Money             `json:"DiscountMoney"`
+	DiscountedPriceMoney *Money             `json:"DiscountedPriceMoney"`
 }
 
 type RecentSearch struct {
-	ID            *int       `json:"ID" structs:"id" db:"id"`
-	UserID        *string    `json:"UserID" structs:"user_id" db:"user_id"`
-	SearchKeyword *string    `json:"SearchKeyword" structs:"search_keyword" db:"search_keyword"`
-	CreatedAt     *time.Time `json:"CreatedAt" structs:"created_at" db:"created_at"`
+	ID             *int       `json:"ID" structs:"id" db:"id"`
+	UserID         *string    `json:"UserID" structs:"user_id" db:"user_id"`
+	SearchKeyword  *string    `json:"SearchKeyword" structs:"search_keyword" db:"search_keyword"`
+	SearchCount    *int       `json:"SearchCount" structs:"search_count" db:"search_count"`
+	CreatedAt      *time.Time `json:"CreatedAt" structs:"created_at" db:"created_at"`
+	LastSearchedAt *time.Time `json:"LastSearchedAt" structs:"last_searched_at" db:"last_searched_at"`
+}
+
+type RecordOrderEventsPayload struct {
+	RecordedLineCount  int                   `json:"RecordedLineCount"`
+	DuplicateLineCount int                   `json:"DuplicateLineCount"`
+	RejectedOrders     []*RejectedOrderEvent `json:"RejectedOrders"`
+}
+
+type RejectedOrderEvent struct {
+	OrderID string `json:"OrderID"`
+	Reason  string `json:"Reason"`
+}
+
+type SearchAnalytics struct {
+	From               time.Time          `json:"From"`
+	To                 time.Time          `json:"To"`
+	TotalSearches      int                `json:"TotalSearches" db:"total_searches"`
+	ZeroResultSearches int                `json:"ZeroResultSearches" db:"zero_result_searches"`
+	ClickThroughRate   float64            `json:"ClickThroughRate" db:"click_through_rate"`
+	TopQueries         []*SearchQueryStat `json:"TopQueries"`
+	ZeroResultQueries  []*SearchQueryStat `json:"ZeroResultQueries"`
+}
+
+type SearchQueryStat struct {
+	Keyword            string  `json:"Keyword" db:"keyword"`
+	SearchCount        int     `json:"SearchCount" db:"search_count"`
+	AverageResultCount float64 `json:"AverageResultCount" db:"average_result_count"`
+	ClickCount         int     `json:"ClickCount" db:"click_count"`
+	ClickThroughRate   float64 `json:"ClickThroughRate" db:"click_through_rate"`
+}
+
+type SearchSuggestion struct {
+	Type SearchSuggestionType `json:"Type"`
+	Text string               `json:"Text"`
+	ID   *string              `json:"ID"`
+}
+
+type SetFavoritesPayload struct {
+	Results            []*FavoriteProductPayload `json:"Results"`
+	RejectedProductIDs []string                  `json:"RejectedProductIDs"`
+}
+
+type SyncFavoritesPayload struct {
+	FavoriteProductIDs []string  `json:"FavoriteProductIDs"`
+	RejectedProductIDs []string  `json:"RejectedProductIDs"`
+	SyncedAt           time.Time `json:"SyncedAt"`
 }
 
 type TrendingProduct struct {
-	ProductID  *string `json:"ProductID" structs:"product_id" db:"product_id"`
-	OrderCount *int    `json:"OrderCount" structs:"order_count" db:"order_count"`
-	Quantity   *int    `json:"Quantity" structs:"quantity" db:"quantity"`
+	ProductID  *string  `json:"ProductID" structs:"product_id" db:"product_id"`
+	OrderCount *int     `json:"OrderCount" structs:"order_count" db:"order_count"`
+	Quantity   *int     `json:"Quantity" structs:"quantity" db:"quantity"`
+	Score      *float64 `json:"Score" structs:"score" db:"score"`
+}
+
+type TrendingProductConnection struct {
+	Edges      []*TrendingProductEdge `json:"Edges"`
+	PageInfo   *PageInfo              `json:"PageInfo"`
+	TotalCount int                    `json:"TotalCount"`
+}
+
+type TrendingProductEdge struct {
+	Cursor     string       `json:"Cursor"`
+	Rank       int          `json:"Rank"`
+	OrderCount int          `json:"OrderCount"`
+	Quantity   int          `json:"Quantity"`
+	Score      float64      `json:"Score"`
+	Product    *ProductItem `json:"Product"`
 }
 
 type UnitOfMeasure struct {
@@ -304,3 +518,132 @@ type UserProductFavorite struct {
 type Workflow struct {
 	RejectionReasons *string `json:"RejectionReasons"`
 }
+
+type FavoriteProductSortBy string
+
+const (
+	FavoriteProductSortByNewest FavoriteProductSortBy = "NEWEST"
+	FavoriteProductSortByOldest FavoriteProductSortBy = "OLDEST"
+)
+
+var AllFavoriteProductSortBy = []FavoriteProductSortBy{
+	FavoriteProductSortByNewest,
+	FavoriteProductSortByOldest,
+}
+
+func (e FavoriteProductSortBy) IsValid() bool {
+	switch e {
+	case FavoriteProductSortByNewest, FavoriteProductSortByOldest:
+		return true
+	}
+	return false
+}
+
+func (e FavoriteProductSortBy) String() string {
+	return string(e)
+}
+
+func (e *FavoriteProductSortBy) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FavoriteProductSortBy(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FavoriteProductSortBy", str)
+	}
+	return nil
+}
+
+func (e FavoriteProductSortBy) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RecommendationReason string
+
+const (
+	RecommendationReasonBoughtTogether RecommendationReason = "BOUGHT_TOGETHER"
+	RecommendationReasonAlsoBought     RecommendationReason = "ALSO_BOUGHT"
+	RecommendationReasonSameCategory   RecommendationReason = "SAME_CATEGORY"
+)
+
+var AllRecommendationReason = []RecommendationReason{
+	RecommendationReasonBoughtTogether,
+	RecommendationReasonAlsoBought,
+	RecommendationReasonSameCategory,
+}
+
+func (e RecommendationReason) IsValid() bool {
+	switch e {
+	case RecommendationReasonBoughtTogether, RecommendationReasonAlsoBought, RecommendationReasonSameCategory:
+		return true
+	}
+	return false
+}
+
+func (e RecommendationReason) String() string {
+	return string(e)
+}
+
+func (e *RecommendationReason) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RecommendationReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RecommendationReason", str)
+	}
+	return nil
+}
+
+func (e RecommendationReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SearchSuggestionType string
+
+const (
+	SearchSuggestionTypeRecentSearch  SearchSuggestionType = "RECENT_SEARCH"
+	SearchSuggestionTypePopularSearch SearchSuggestionType = "POPULAR_SEARCH"
+	SearchSuggestionTypeProduct       SearchSuggestionType = "PRODUCT"
+	SearchSuggestionTypeCategory      SearchSuggestionType = "CATEGORY"
+)
+
+var AllSearchSuggestionType = []SearchSuggestionType{
+	SearchSuggestionTypeRecentSearch,
+	SearchSuggestionTypePopularSearch,
+	SearchSuggestionTypeProduct,
+	SearchSuggestionTypeCategory,
+}
+
+func (e SearchSuggestionType) IsValid() bool {
+	switch e {
+	case SearchSuggestionTypeRecentSearch, SearchSuggestionTypePopularSearch, SearchSuggestionTypeProduct, SearchSuggestionTypeCategory:
+		return true
+	}
+	return false
+}
+
+func (e SearchSuggestionType) String() string {
+	return string(e)
+}
+
+func (e *SearchSuggestionType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SearchSuggestionType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SearchSuggestionType", str)
+	}
+	return nil
+}
+
+func (e SearchSuggestionType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
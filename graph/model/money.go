@@ -0,0 +1,29 @@
+package model
+
+import (
+	"math/big"
+)
+
+// Money is an amount in the minor units of an ISO 4217 currency, Scale being the number of minor unit digits,
+// e.g. 1250 MYR of scale 2 is RM 12.50
+type Money struct {
+	MinorUnits int64
+	Currency   string
+	Scale      int
+}
+
+// Decimal returns the exact amount in major units
+func (m Money) Decimal() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.MinorUnits), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(m.Scale)), nil))
+}
+
+// Amount returns the amount in major units with Scale digits, e.g. "12.50"
+func (m Money) Amount() string {
+	return m.Decimal().FloatString(m.Scale)
+}
+
+// Float64 returns the amount in major units, nearest float
+func (m Money) Float64() float64 {
+	f, _ := m.Decimal().Float64()
+	return f
+}
@@ -7,10 +7,16 @@ import "mpmy-product-service/service"
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	LoginService           *service.LoginService
-	ProductService         *service.ProductService
-	CategoryProductService *service.CategoryProductService
-	PriceScheduleService   *service.PriceScheduleService
-	CategoryService        *service.CategoryService
-	RecentSearchService    *service.RecentSearchService
+	LoginService            *service.LoginService
+	ProductService          *service.ProductService
+	CategoryProductService  *service.CategoryProductService
+	PriceScheduleService    *service.PriceScheduleService
+	CategoryService         *service.CategoryService
+	RecentSearchService     *service.RecentSearchService
+	SearchSuggestionService *service.SearchSuggestionService
+	SearchAnalyticsService  *service.SearchAnalyticsService
+	ProductListService      *service.ProductListService
+	OrderEventService       *service.OrderEventService
+	TranslationService      *service.TranslationService
+	PromotionService        *service.PromotionService
 }
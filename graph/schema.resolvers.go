@@ -8,8 +8,30 @@ import (
 	"errors"
 	"mpmy-product-service/graph/generated"
 	"mpmy-product-service/graph/model"
+	"mpmy-product-service/service"
+	"time"
 )
 
+// Name is the resolver for the Name field.
+func (r *categoryItemsResolver) Name(ctx context.Context, obj *model.CategoryItems, locale *string) (string, error) {
+	locales, err := GetCurrentLocales(ctx, locale)
+	if err != nil {
+		return "", err
+	}
+
+	return r.TranslationService.CategoryName(obj, locales), nil
+}
+
+// Formatted is the resolver for the formatted field.
+func (r *moneyResolver) Formatted(ctx context.Context, obj *model.Money, locale *string) (string, error) {
+	locales, err := GetCurrentLocales(ctx, locale)
+	if err != nil {
+		return "", err
+	}
+
+	return r.PriceScheduleService.FormatMoney(*obj, locales), nil
+}
+
 // FavoriteProduct is the resolver for the favoriteProduct field.
 func (r *mutationResolver) FavoriteProduct(ctx context.Context, productID string, isFavorite bool) (*model.UserProductFavorite, error) {
 	userID, err := GetCurrentUserID(ctx)
@@ -20,16 +42,689 @@ func (r *mutationResolver) FavoriteProduct(ctx context.Context, productID string
 		return nil, err
 	}
 
-	result, err := r.ProductService.FavoriteProduct(userID, productID, isFavorite)
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.ProductService.FavoriteProduct(userID, productID, isFavorite, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	// unfavoriting has no favorite to return
+	return result.Favorite, nil
+}
+
+// SetFavorite is the resolver for the setFavorite field.
+func (r *mutationResolver) SetFavorite(ctx context.Context, productID string, isFavorite bool) (*model.FavoriteProductPayload, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.ProductService.FavoriteProduct(userID, productID, isFavorite, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SetFavorites is the resolver for the setFavorites field.
+func (r *mutationResolver) SetFavorites(ctx context.Context, productIDs []string, isFavorite bool) (*model.SetFavoritesPayload, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.ProductService.SetFavorites(userID, productIDs, isFavorite, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SyncFavorites is the resolver for the syncFavorites field.
+func (r *mutationResolver) SyncFavorites(ctx context.Context, changes []*model.FavoriteChangeInput) (*model.SyncFavoritesPayload, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.ProductService.SyncFavorites(userID, changes, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CreateProductList is the resolver for the createProductList field.
+func (r *mutationResolver) CreateProductList(ctx context.Context, name string, description *string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buyerID, err := GetCurrentBuyerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.CreateProductList(userID, buyerID, name, description, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateProductList is the resolver for the updateProductList field.
+func (r *mutationResolver) UpdateProductList(ctx context.Context, id int, name *string, description *string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.UpdateProductList(userID, id, name, description, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteProductList is the resolver for the deleteProductList field.
+func (r *mutationResolver) DeleteProductList(ctx context.Context, id int) (bool, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return false, errors.New("invalid user")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.ProductListService.DeleteProductList(userID, id)
+	if err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// AddProductListItem is the resolver for the addProductListItem field.
+func (r *mutationResolver) AddProductListItem(ctx context.Context, listID int, productID string, quantity *int, notes *string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.AddProductListItem(userID, listID, productID, quantity, notes, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateProductListItem is the resolver for the updateProductListItem field.
+func (r *mutationResolver) UpdateProductListItem(ctx context.Context, listID int, productID string, quantity *int, notes *string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.UpdateProductListItem(userID, listID, productID, quantity, notes, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RemoveProductListItem is the resolver for the removeProductListItem field.
+func (r *mutationResolver) RemoveProductListItem(ctx context.Context, listID int, productID string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.RemoveProductListItem(userID, listID, productID, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReorderProductListItems is the resolver for the reorderProductListItems field.
+func (r *mutationResolver) ReorderProductListItems(ctx context.Context, listID int, productIDs []string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.ReorderProductListItems(userID, listID, productIDs, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ShareProductList is the resolver for the shareProductList field.
+func (r *mutationResolver) ShareProductList(ctx context.Context, id int) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.ShareProductList(userID, id, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UnshareProductList is the resolver for the unshareProductList field.
+func (r *mutationResolver) UnshareProductList(ctx context.Context, id int) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.UnshareProductList(userID, id, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CopyProductList is the resolver for the copyProductList field.
+func (r *mutationResolver) CopyProductList(ctx context.Context, shareToken string, name *string) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buyerID, err := GetCurrentBuyerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.CopyProductList(userID, buyerID, shareToken, name, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RecordOrderEvents is the resolver for the recordOrderEvents field.
+func (r *mutationResolver) RecordOrderEvents(ctx context.Context, events []*model.OrderEventInput) (*model.RecordOrderEventsPayload, error) {
+	isAdmin, err := IsCurrentUserAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, errors.New("unauthorized")
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.OrderEventService.RecordOrderEvents(events, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// InvalidateCategories is the resolver for the invalidateCategories field.
+func (r *mutationResolver) InvalidateCategories(ctx context.Context, catalogID *string) (bool, error) {
+	isAdmin, err := IsCurrentUserAdmin(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !isAdmin {
+		return false, errors.New("unauthorized")
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	r.CategoryService.InvalidateCategories(catalogID, market)
+
+	return true, nil
+}
+
+// DeleteRecentSearch is the resolver for the deleteRecentSearch field.
+func (r *mutationResolver) DeleteRecentSearch(ctx context.Context, id int) (bool, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return false, errors.New("invalid user")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.RecentSearchService.DeleteRecentSearch(userID, id)
+	if err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// ClearRecentSearches is the resolver for the clearRecentSearches field.
+func (r *mutationResolver) ClearRecentSearches(ctx context.Context) (bool, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return false, errors.New("invalid user")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.RecentSearchService.ClearRecentSearches(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// RecordSearchClick is the resolver for the recordSearchClick field.
+func (r *mutationResolver) RecordSearchClick(ctx context.Context, productID string, searchID string) (bool, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return false, errors.New("invalid user")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.SearchAnalyticsService.RecordSearchClick(userID, productID, searchID)
+	if err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// Name is the resolver for the Name field.
+func (r *productItemResolver) Name(ctx context.Context, obj *model.ProductItem, locale *string) (*string, error) {
+	locales, err := GetCurrentLocales(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.TranslationService.ProductName(obj, locales), nil
+}
+
+// Description is the resolver for the Description field.
+func (r *productItemResolver) Description(ctx context.Context, obj *model.ProductItem, locale *string) (*string, error) {
+	locales, err := GetCurrentLocales(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.TranslationService.ProductDescription(obj, locales), nil
+}
+
+// Categories is the resolver for the Categories field.
+func (r *productItemResolver) Categories(ctx context.Context, obj *model.ProductItem) ([]*model.CategoryItems, error) {
+	if obj.ID == nil {
+		return []*model.CategoryItems{}, nil
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.CategoryService.GetProductCategories(*obj.ID, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// EffectivePrice is the resolver for the EffectivePrice field.
+func (r *productItemResolver) EffectivePrice(ctx context.Context, obj *model.ProductItem, quantity int) (*model.PriceQuote, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.PriceScheduleService.QuotePrice(obj, quantity, market)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ApplicablePromotions is the resolver for the ApplicablePromotions field.
+func (r *productItemResolver) ApplicablePromotions(ctx context.Context, obj *model.ProductItem, quantity int) ([]*model.PromotionDiscount, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := GetCurrentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buyerID, err := GetCurrentBuyerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.PromotionService.GetApplicablePromotions(obj, quantity, userID, buyerID, market)
+}
+
+// BestPromotion is the resolver for the BestPromotion field.
+func (r *productItemResolver) BestPromotion(ctx context.Context, obj *model.ProductItem, quantity int) (*model.PromotionDiscount, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := GetCurrentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buyerID, err := GetCurrentBuyerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.PromotionService.GetBestPromotion(obj, quantity, userID, buyerID, market)
+}
+
+// Tax is the resolver for the Tax field.
+func (r *productXPResolver) Tax(ctx context.Context, obj *model.ProductXp, locale *string) (*model.ProductTax, error) {
+	locales, err := GetCurrentLocales(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.TranslationService.ProductTax(obj, locales), nil
+}
+
+// ProductsV2 is the resolver for the productsV2 field.
+func (r *queryResolver) ProductsV2(ctx context.Context, catalogID *string, categoryID *string, supplierID *string, isFavorite *bool, search *string, page *string, pageSize *string, sortBy *string, extraFilters map[string]interface{}) (*model.ProductResponseV2, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.ProductService.GetProductsV2(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search, isFavorite, extraFilters, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context, catalogID *string, categoryID *string, supplierID *string, isFavorite *bool, search *string, page *string, pageSize *string, sortBy *string, extraFilters map[string]interface{}) (*model.ProductResponse, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetProducts(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search, isFavorite, extraFilters, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SimilarProducts is the resolver for the similarProducts field.
+func (r *queryResolver) SimilarProducts(ctx context.Context, productID string, page *string, pageSize *string) (*model.ProductResponse, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetSimilarProducts(productID, userID, page, pageSize, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// RecommendProducts is the resolver for the recommendProducts field.
+func (r *queryResolver) RecommendProducts(ctx context.Context, productID string, page *string, pageSize *string) (*model.ProductResponseV2, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetRecommendProducts(productID, page, pageSize, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
 }
 
-// ProductsV2 is the resolver for the productsV2 field.
-func (r *queryResolver) ProductsV2(ctx context.Context, catalogID *string, categoryID *string, supplierID *string, isFavorite *bool, search *string, page *string, pageSize *string, sortBy *string, extraFilters map[string]interface{}) (*model.ProductResponseV2, error) {
+// Product is the resolver for the product field.
+func (r *queryResolver) Product(ctx context.Context, id string) (*model.ProductItem, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -38,7 +733,18 @@ func (r *queryResolver) ProductsV2(ctx context.Context, catalogID *string, categ
 		return nil, err
 	}
 
-	result, err := r.ProductService.GetProductsV2(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search, isFavorite, extraFilters, r.LoginService.AccessToken)
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetProduct(id, userID, market, party, r.LoginService.GetAccessToken())
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +752,8 @@ func (r *queryResolver) ProductsV2(ctx context.Context, catalogID *string, categ
 	return &result, nil
 }
 
-// Products is the resolver for the products field.
-func (r *queryResolver) Products(ctx context.Context, catalogID *string, categoryID *string, supplierID *string, isFavorite *bool, search *string, page *string, pageSize *string, sortBy *string, extraFilters map[string]interface{}) (*model.ProductResponse, error) {
+// ProductV2 is the resolver for the productV2 field.
+func (r *queryResolver) ProductV2(ctx context.Context, id string) (*model.LatestProductItems, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -56,7 +762,18 @@ func (r *queryResolver) Products(ctx context.Context, catalogID *string, categor
 		return nil, err
 	}
 
-	result, err := r.ProductService.GetProducts(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search, isFavorite, extraFilters, r.LoginService.AccessToken)
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetProductV2(id, userID, market, party, r.LoginService.GetAccessToken())
 	if err != nil {
 		return nil, err
 	}
@@ -64,8 +781,92 @@ func (r *queryResolver) Products(ctx context.Context, catalogID *string, categor
 	return &result, nil
 }
 
-// SimilarProducts is the resolver for the similarProducts field.
-func (r *queryResolver) SimilarProducts(ctx context.Context, productID string, page *string, pageSize *string) (*model.ProductResponse, error) {
+// PriceSchedules is the resolver for the priceSchedules field.
+func (r *queryResolver) PriceSchedules(ctx context.Context, productID string, page *string, pageSize *string) (*model.PriceScheduleResponse, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetProductPriceSchedules(productID, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// PriceQuote is the resolver for the priceQuote field.
+func (r *queryResolver) PriceQuote(ctx context.Context, productID string, quantity int) (*model.PriceQuote, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetPriceQuote(productID, quantity, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Categories is the resolver for the categories field.
+func (r *queryResolver) Categories(ctx context.Context, catalogID *string, depth *string) (*model.CategoryResponse, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.CategoryService.GetCategories(catalogID, depth, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Category is the resolver for the category field.
+func (r *queryResolver) Category(ctx context.Context, id string, catalogID *string) (*model.CategoryItems, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.CategoryService.GetCategory(catalogID, id, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CategoryPath is the resolver for the categoryPath field.
+func (r *queryResolver) CategoryPath(ctx context.Context, id string, catalogID *string) ([]*model.CategoryItems, error) {
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.CategoryService.GetCategoryPath(catalogID, id, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CategoryProducts is the resolver for the categoryProducts field.
+func (r *queryResolver) CategoryProducts(ctx context.Context, categoryID string, includeDescendants *bool, first *int, after *string) (*model.CategoryProductConnection, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -74,16 +875,63 @@ func (r *queryResolver) SimilarProducts(ctx context.Context, productID string, p
 		return nil, err
 	}
 
-	result, err := r.ProductService.GetSimilarProducts(productID, userID, page, pageSize, r.LoginService.AccessToken)
+	market, err := GetCurrentMarket(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.CategoryProductService.GetCategoryProductConnection(categoryID, includeDescendants, userID, first, after, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// RecommendProducts is the resolver for the recommendProducts field.
-func (r *queryResolver) RecommendProducts(ctx context.Context, productID string, page *string, pageSize *string) (*model.ProductResponseV2, error) {
+// TrendingProducts is the resolver for the trendingProducts field.
+func (r *queryResolver) TrendingProducts(ctx context.Context, categoryID *string, supplierID *string, first *int, after *string) (*model.TrendingProductConnection, error) {
+	// the user is optional, trending products are only marked as favorites for a signed in user
+	userID, err := GetCurrentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetTrendingProducts(categoryID, supplierID, userID, first, after, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetProductFilter is the resolver for the getProductFilter field.
+func (r *queryResolver) GetProductFilter(ctx context.Context, search string) ([]*model.ProductFilter, error) {
+	result, err := r.ProductService.GetProductFilterMiddleWare(search, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RecentSearches is the resolver for the recentSearches field.
+func (r *queryResolver) RecentSearches(ctx context.Context, page *string, pageSize *string) ([]*model.RecentSearch, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -92,16 +940,16 @@ func (r *queryResolver) RecommendProducts(ctx context.Context, productID string,
 		return nil, err
 	}
 
-	result, err := r.ProductService.GetRecommendProducts(productID, page, pageSize, r.LoginService.AccessToken)
+	result, err := r.RecentSearchService.GetRecentSearches(userID, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	return result, nil
 }
 
-// Product is the resolver for the product field.
-func (r *queryResolver) Product(ctx context.Context, id string) (*model.ProductItem, error) {
+// SearchSuggestions is the resolver for the searchSuggestions field.
+func (r *queryResolver) SearchSuggestions(ctx context.Context, prefix string, limit *int) ([]*model.SearchSuggestion, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -110,16 +958,39 @@ func (r *queryResolver) Product(ctx context.Context, id string) (*model.ProductI
 		return nil, err
 	}
 
-	result, err := r.ProductService.GetProduct(id, userID, r.LoginService.AccessToken)
+	market, err := GetCurrentMarket(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	result, err := r.SearchSuggestionService.GetSearchSuggestions(userID, prefix, limit, market, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// ProductV2 is the resolver for the productV2 field.
-func (r *queryResolver) ProductV2(ctx context.Context, id string) (*model.LatestProductItems, error) {
+// SearchAnalytics is the resolver for the searchAnalytics field.
+func (r *queryResolver) SearchAnalytics(ctx context.Context, from time.Time, to time.Time, limit *int) (*model.SearchAnalytics, error) {
+	isAdmin, err := IsCurrentUserAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, errors.New("unauthorized")
+	}
+
+	result, err := r.SearchAnalyticsService.GetSearchAnalytics(from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// FavoriteProducts is the resolver for the favoriteProducts field.
+func (r *queryResolver) FavoriteProducts(ctx context.Context, first *int, after *string, sortBy *model.FavoriteProductSortBy) (*model.FavoriteProductConnection, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -128,54 +999,114 @@ func (r *queryResolver) ProductV2(ctx context.Context, id string) (*model.Latest
 		return nil, err
 	}
 
-	result, err := r.ProductService.GetProductV2(id, userID, r.LoginService.AccessToken)
+	market, err := GetCurrentMarket(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
-}
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
 
-// PriceSchedules is the resolver for the priceSchedules field.
-func (r *queryResolver) PriceSchedules(ctx context.Context, productID string, page *string, pageSize *string) (*model.PriceScheduleResponse, error) {
-	result, err := r.PriceScheduleService.GetPriceSchedule(productID, page, pageSize, r.LoginService.AccessToken)
+	result, err := r.ProductService.GetFavoriteProducts(userID, first, after, sortBy, market, party, r.LoginService.GetAccessToken())
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	return result, nil
 }
 
-// Categories is the resolver for the categories field.
-func (r *queryResolver) Categories(ctx context.Context, catalogID *string, depth *string) (*model.CategoryResponse, error) {
-	result, err := r.CategoryService.GetCategories(catalogID, depth, r.LoginService.AccessToken)
+// ProductLists is the resolver for the productLists field.
+func (r *queryResolver) ProductLists(ctx context.Context) ([]*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &result, nil
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.GetProductLists(userID, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// TrendingProducts is the resolver for the trendingProducts field.
-func (r *queryResolver) TrendingProducts(ctx context.Context) (*model.ProductResponse, error) {
-	result, err := r.ProductService.GetTrendingProducts(r.LoginService.AccessToken)
+// FrequentlyPurchased is the resolver for the frequentlyPurchased field.
+func (r *queryResolver) FrequentlyPurchased(ctx context.Context, first *int) ([]*model.FrequentlyPurchasedProduct, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductService.GetFrequentlyPurchasedProducts(userID, first, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// GetProductFilter is the resolver for the getProductFilter field.
-func (r *queryResolver) GetProductFilter(ctx context.Context, search string) ([]*model.ProductFilter, error) {
-	result, err := r.ProductService.GetProductFilterMiddleWare(search, r.LoginService.AccessToken)
+// ProductList is the resolver for the productList field.
+func (r *queryResolver) ProductList(ctx context.Context, id int) (*model.ProductList, error) {
+	userID, err := GetCurrentUserID(ctx)
+	if userID == nil {
+		return nil, errors.New("invalid user")
+	}
 	if err != nil {
 		return nil, err
 	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.GetProductList(userID, id, market, party, r.LoginService.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
-// RecentSearches is the resolver for the recentSearches field.
-func (r *queryResolver) RecentSearches(ctx context.Context, page *string, pageSize *string) ([]*model.RecentSearch, error) {
+// SharedProductList is the resolver for the sharedProductList field.
+func (r *queryResolver) SharedProductList(ctx context.Context, shareToken string) (*model.ProductList, error) {
 	userID, err := GetCurrentUserID(ctx)
 	if userID == nil {
 		return nil, errors.New("invalid user")
@@ -184,7 +1115,23 @@ func (r *queryResolver) RecentSearches(ctx context.Context, page *string, pageSi
 		return nil, err
 	}
 
-	result, err := r.RecentSearchService.GetRecentSearches(userID, page, pageSize)
+	buyerID, err := GetCurrentBuyerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	market, err := GetCurrentMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partyBuyerID, userGroupIDs, err := GetCurrentPriceParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	party := service.PriceParty{BuyerID: partyBuyerID, UserGroupIDs: userGroupIDs}
+
+	result, err := r.ProductListService.GetSharedProductList(userID, buyerID, shareToken, market, party, r.LoginService.GetAccessToken())
 	if err != nil {
 		return nil, err
 	}
@@ -192,11 +1139,27 @@ func (r *queryResolver) RecentSearches(ctx context.Context, page *string, pageSi
 	return result, nil
 }
 
+// CategoryItems returns generated.CategoryItemsResolver implementation.
+func (r *Resolver) CategoryItems() generated.CategoryItemsResolver { return &categoryItemsResolver{r} }
+
+// Money returns generated.MoneyResolver implementation.
+func (r *Resolver) Money() generated.MoneyResolver { return &moneyResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// ProductItem returns generated.ProductItemResolver implementation.
+func (r *Resolver) ProductItem() generated.ProductItemResolver { return &productItemResolver{r} }
+
+// ProductXP returns generated.ProductXPResolver implementation.
+func (r *Resolver) ProductXP() generated.ProductXPResolver { return &productXPResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+type categoryItemsResolver struct{ *Resolver }
+type moneyResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type productItemResolver struct{ *Resolver }
+type productXPResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
@@ -82,17 +82,16 @@ type RequestSpecifications struct {
 	Log            *logrus.Entry
 }
 
-// RequestHandler - holds request handler information
+// RequestHandler - holds request handler information, it is safe for concurrent use as every request is sent
+// with its own agent
 type RequestHandler struct {
 	appName string
-	Handler *gorequest.SuperAgent
 }
 
 // NewRequestHandler  - return RequestHandler object
 func NewRequestHandler(app string) *RequestHandler {
 	return &RequestHandler{
 		appName: app,
-		Handler: gorequest.New(),
 	}
 }
 
@@ -172,15 +171,16 @@ func (r *RequestHandler) prepareRequest(specs *RequestSpecifications, logFields
 	// set transport
 	timeout := GetValue(specs.Timeout, defaultTimeout).(int)
 	logFields["http_timeout"] = timeout
-	r.Handler.Transport = DefaultTransport
-	r.Handler.Client.Timeout = time.Duration(timeout) * time.Second
+	newHandler := gorequest.New()
+	newHandler.Transport = DefaultTransport
+	newHandler.Client.Timeout = time.Duration(timeout) * time.Second
 
 	if specs.RetryCondition == nil {
 		specs.RetryCondition = ExactResponseCodeMatch
 	}
 	// specify authorization for request
 	if specs.UseAuth {
-		r.Handler = r.Handler.SetBasicAuth(specs.Username, specs.Password)
+		newHandler = newHandler.SetBasicAuth(specs.Username, specs.Password)
 		logFields["http_require_auth"] = specs.UseAuth
 	}
 	// checks if request retry is enabled
@@ -198,9 +198,9 @@ func (r *RequestHandler) prepareRequest(specs *RequestSpecifications, logFields
 		specs.HTTPMethod = http.MethodGet
 	}
 	logFields["http_method"] = specs.HTTPMethod
-	r.Handler = addHeadersAndBody(specs, r.Handler)
+	newHandler = addHeadersAndBody(specs, newHandler)
 	specs.Log.WithFields(logFields).Info("prepared and sending http request")
-	return r.Handler
+	return newHandler
 }
 
 // addHeadersAndBody - adds headers and body to request handler
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"mpmy-product-service/constants"
+)
+
+// LocaleMiddleware keeps the locales of the request's Accept-Language header in preference order
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(constants.LocalesKey, ParseAcceptLanguage(c.GetHeader(constants.AcceptLanguageHeader)))
+		c.Next()
+	}
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header such as "ms-MY,ms;q=0.9,en;q=0.8" by
+// descending quality, locales of the same quality in header order. The wildcard, malformed language tags and locales
+// of quality 0 are left out, and a malformed quality counts as 1
+func ParseAcceptLanguage(header string) []string {
+	type weightedLocale struct {
+		locale  string
+		quality float64
+	}
+
+	var weightedLocales []weightedLocale
+	for _, part := range strings.Split(header, ",") {
+		locale, params, _ := strings.Cut(part, ";")
+		locale = strings.TrimSpace(locale)
+		if locale == "*" || !isLanguageTag(locale) {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q >= 0 && q <= 1 {
+				quality = q
+			}
+		}
+		if quality == 0 {
+			continue
+		}
+
+		weightedLocales = append(weightedLocales, weightedLocale{locale: locale, quality: quality})
+	}
+
+	sort.SliceStable(weightedLocales, func(i, j int) bool {
+		return weightedLocales[i].quality > weightedLocales[j].quality
+	})
+
+	locales := make([]string, len(weightedLocales))
+	for i, weighted := range weightedLocales {
+		locales[i] = weighted.locale
+	}
+	return locales
+}
+
+// isLanguageTag reports whether the locale is shaped like a language tag: subtags of 1 to 8 letters or digits joined
+// by hyphens, the first of letters only
+func isLanguageTag(locale string) bool {
+	if locale == "" {
+		return false
+	}
+	for i, subtag := range strings.Split(locale, "-") {
+		if len(subtag) == 0 || len(subtag) > 8 {
+			return false
+		}
+		for _, r := range subtag {
+			isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			isDigit := r >= '0' && r <= '9'
+			if !isLetter && (i == 0 || !isDigit) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+
+	//data test
+	var tests = []struct {
+		name     string
+		header   string
+		expected []string
+	}{
+		{name: "empty header", header: "", expected: []string{}},
+		{name: "single locale", header: "ms-MY", expected: []string{"ms-MY"}},
+		{name: "ordered by quality", header: "en;q=0.5,ms-MY,ms;q=0.9", expected: []string{"ms-MY", "ms", "en"}},
+		{name: "missing quality counts as 1", header: "en;q=0.8, ms", expected: []string{"ms", "en"}},
+		{name: "same quality keeps header order", header: "zh;q=0.7,en;q=0.7,ms;q=0.7", expected: []string{"zh", "en", "ms"}},
+		{name: "quality 0 is left out", header: "ms,en;q=0,zh;q=0.000", expected: []string{"ms"}},
+		{name: "wildcard is left out", header: "*,ms;q=0.5", expected: []string{"ms"}},
+		{name: "wildcard with quality is left out", header: "ms, *;q=0.1", expected: []string{"ms"}},
+		{name: "malformed quality counts as 1", header: "en;q=0.5,ms;q=high,zh;q=2", expected: []string{"ms", "zh", "en"}},
+		{name: "quality parameter with spaces", header: "en ; q = 0.5 ,ms", expected: []string{"ms", "en"}},
+		{name: "other parameters are ignored", header: "en;level=1;q=0.4,ms;q=0.6", expected: []string{"ms", "en"}},
+		{name: "empty parts are skipped", header: ",,ms,,", expected: []string{"ms"}},
+		{name: "malformed tags are left out", header: "en US,ms_MY,12,en-,-en,verylongtag,<script>,zh-Hant-TW", expected: []string{"zh-Hant-TW"}},
+		{name: "digits after the language subtag", header: "es-419", expected: []string{"es-419"}},
+	}
+
+	for _, test := range tests {
+		locales := ParseAcceptLanguage(test.header)
+		if !reflect.DeepEqual(locales, test.expected) {
+			t.Errorf("test failed: %s, expected %v, got %v", test.name, test.expected, locales)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
+)
+
+func marketUnknown(c *gin.Context) {
+	c.JSON(400, gin.H{
+		"errors": []string{
+			"unknown market",
+		},
+		"data": nil,
+	})
+	c.Abort()
+}
+
+// MarketMiddleware selects the market of the request from the token's market claim, then the market header,
+// then the default market
+func MarketMiddleware(markets config.MarketsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		marketID := c.GetHeader(constants.MarketHeader)
+		if claims, ok := c.Value("USER_CLAIM").(jwt.MapClaims); ok {
+			if claim, ok := claims[constants.MarketClaim].(string); ok && claim != "" {
+				marketID = claim
+			}
+		}
+
+		market := markets.Default()
+		if marketID != "" {
+			var ok bool
+			market, ok = markets.Market(marketID)
+			if !ok {
+				marketUnknown(c)
+				return
+			}
+		}
+
+		c.Set(constants.MarketKey, market)
+		c.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
+)
+
+func TestMarketMiddleware(t *testing.T) {
+
+	//data test
+	gin.SetMode(gin.TestMode)
+	var markets = config.MarketsConfig{
+		DefaultMarket: "my",
+		Markets: map[string]config.MarketConfig{
+			"my": {ID: "my", CatalogID: "zp-my", Currency: "MYR"},
+			"sg": {ID: "sg", CatalogID: "zp-sg", Currency: "SGD"},
+		},
+	}
+
+	var tests = []struct {
+		name           string
+		header         string
+		claims         jwt.MapClaims
+		expectedStatus int
+		expectedMarket string
+	}{
+		{name: "default market", expectedStatus: http.StatusOK, expectedMarket: "my"},
+		{name: "market header", header: "sg", expectedStatus: http.StatusOK, expectedMarket: "sg"},
+		{name: "market header in any case", header: " SG ", expectedStatus: http.StatusOK, expectedMarket: "sg"},
+		{name: "market claim", claims: jwt.MapClaims{constants.MarketClaim: "sg"}, expectedStatus: http.StatusOK, expectedMarket: "sg"},
+		{name: "market claim over header", header: "my", claims: jwt.MapClaims{constants.MarketClaim: "sg"}, expectedStatus: http.StatusOK, expectedMarket: "sg"},
+		{name: "empty market claim falls back to header", header: "sg", claims: jwt.MapClaims{constants.MarketClaim: ""}, expectedStatus: http.StatusOK, expectedMarket: "sg"},
+		{name: "claims without market", claims: jwt.MapClaims{"id": float64(42)}, expectedStatus: http.StatusOK, expectedMarket: "my"},
+		{name: "unknown market header", header: "th", expectedStatus: http.StatusBadRequest},
+		{name: "unknown market claim", header: "sg", claims: jwt.MapClaims{constants.MarketClaim: "th"}, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		var selectedMarket string
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if test.claims != nil {
+				c.Set("USER_CLAIM", test.claims)
+			}
+			c.Next()
+		})
+		router.Use(MarketMiddleware(markets))
+		router.GET("/", func(c *gin.Context) {
+			if market, ok := c.Value(constants.MarketKey).(config.MarketConfig); ok {
+				selectedMarket = market.ID
+			}
+			c.Status(http.StatusOK)
+		})
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.header != "" {
+			request.Header.Set(constants.MarketHeader, test.header)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.expectedStatus {
+			t.Errorf("test failed: %s, expected status %d, got %d", test.name, test.expectedStatus, recorder.Code)
+		}
+		if selectedMarket != test.expectedMarket {
+			t.Errorf("test failed: %s, expected market %q, got %q", test.name, test.expectedMarket, selectedMarket)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- De-duplicates recent searches per user and normalized keyword so repeated searches
+-- update a single row with a last used timestamp and a search count.
+
+ALTER TABLE recent_searches
+    ADD COLUMN IF NOT EXISTS normalized_keyword TEXT,
+    ADD COLUMN IF NOT EXISTS search_count INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS last_searched_at TIMESTAMP;
+
+-- keep the latest row of every duplicate group, carrying over the group's count
+WITH grouped AS (
+    SELECT user_id,
+           LOWER(REGEXP_REPLACE(TRIM(search_keyword), '\s+', ' ', 'g')) AS normalized_keyword,
+           MAX(id)         AS id,
+           COUNT(*)        AS search_count,
+           MAX(created_at) AS last_searched_at
+    FROM recent_searches
+    GROUP BY 1, 2
+)
+UPDATE recent_searches rs
+SET normalized_keyword = g.normalized_keyword,
+    search_count       = g.search_count,
+    last_searched_at   = g.last_searched_at
+FROM grouped g
+WHERE rs.id = g.id;
+
+DELETE FROM recent_searches WHERE normalized_keyword IS NULL;
+
+ALTER TABLE recent_searches
+    ALTER COLUMN normalized_keyword SET NOT NULL,
+    ALTER COLUMN last_searched_at SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS recent_searches_user_id_normalized_keyword_key
+    ON recent_searches (user_id, normalized_keyword);
+
+CREATE INDEX IF NOT EXISTS recent_searches_last_searched_at_idx
+    ON recent_searches (last_searched_at);
@@ -0,0 +1,29 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Stores every logged search with its outcome and the products clicked from its results,
+-- used by the searchAnalytics query.
+
+CREATE TABLE IF NOT EXISTS search_events (
+    id                 TEXT PRIMARY KEY,
+    user_id            TEXT        NOT NULL,
+    search_keyword     TEXT        NOT NULL,
+    normalized_keyword TEXT        NOT NULL,
+    catalog_id         TEXT        NOT NULL,
+    filters            JSONB       NOT NULL DEFAULT '{}',
+    result_count       INTEGER     NOT NULL,
+    latency_ms         INTEGER     NOT NULL,
+    created_at         TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_events_created_at_idx ON search_events (created_at);
+
+-- search_id is not a foreign key, clicks can arrive before the asynchronous search log is flushed
+CREATE TABLE IF NOT EXISTS search_clicks (
+    id         SERIAL PRIMARY KEY,
+    search_id  TEXT        NOT NULL,
+    product_id TEXT        NOT NULL,
+    user_id    TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_clicks_search_id_idx ON search_clicks (search_id);
@@ -0,0 +1,6 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Supports paging through a user's favorites ordered by created_at.
+
+CREATE INDEX IF NOT EXISTS user_product_favorites_user_id_created_at_idx
+    ON user_product_favorites (user_id, created_at, id);
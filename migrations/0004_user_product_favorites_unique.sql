@@ -0,0 +1,13 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Makes favorites unique per user and product so favoriting a product again is a no-op.
+
+-- keep the earliest favorite of every duplicate group
+DELETE FROM user_product_favorites f
+USING user_product_favorites d
+WHERE f.user_id = d.user_id
+  AND f.product_id = d.product_id
+  AND (f.created_at, f.id) > (d.created_at, d.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_product_favorites_user_id_product_id_key
+    ON user_product_favorites (user_id, product_id);
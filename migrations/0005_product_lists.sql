@@ -0,0 +1,34 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Named product lists (wishlists) owned by a user, shareable within the user's buyer organization.
+
+CREATE TABLE IF NOT EXISTS product_lists (
+    id          SERIAL PRIMARY KEY,
+    user_id     TEXT        NOT NULL,
+    buyer_id    TEXT,
+    name        TEXT        NOT NULL,
+    description TEXT,
+    share_token TEXT,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS product_lists_user_id_idx
+    ON product_lists (user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_lists_share_token_key
+    ON product_lists (share_token);
+
+CREATE TABLE IF NOT EXISTS product_list_items (
+    id         SERIAL PRIMARY KEY,
+    list_id    INTEGER     NOT NULL REFERENCES product_lists (id) ON DELETE CASCADE,
+    product_id TEXT        NOT NULL,
+    quantity   INTEGER     NOT NULL DEFAULT 1,
+    notes      TEXT,
+    position   INTEGER     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_list_items_list_id_product_id_key
+    ON product_list_items (list_id, product_id);
@@ -0,0 +1,13 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Records which user ordered every trending product line so order history can be ranked per user.
+--
+-- Existing rows carry no user and cannot be backfilled, trending product lines never recorded who ordered
+-- them. user_id is only written by recordOrderEvents (0008), so frequentlyPurchased returns nothing for a
+-- user until their orders are recorded through it.
+
+ALTER TABLE trending_products
+    ADD COLUMN IF NOT EXISTS user_id TEXT;
+
+CREATE INDEX IF NOT EXISTS trending_products_user_id_created_at_idx
+    ON trending_products (user_id, created_at);
@@ -0,0 +1,15 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Records the category and supplier of every trending product line so trending products can be
+-- ranked per category and per supplier.
+--
+-- Existing rows cannot be backfilled, trending product lines never recorded their category or supplier.
+-- Both are only written by recordOrderEvents (0008), so per category and per supplier trending products
+-- only rank orders recorded through it.
+
+ALTER TABLE trending_products
+    ADD COLUMN IF NOT EXISTS category_id TEXT,
+    ADD COLUMN IF NOT EXISTS supplier_id TEXT;
+
+CREATE INDEX IF NOT EXISTS trending_products_created_at_idx
+    ON trending_products (created_at);
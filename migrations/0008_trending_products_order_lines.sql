@@ -0,0 +1,11 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Identifies the order line behind every trending product row so recorded order events are
+-- written once however often they are delivered.
+
+ALTER TABLE trending_products
+    ADD COLUMN IF NOT EXISTS order_id TEXT,
+    ADD COLUMN IF NOT EXISTS line_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS trending_products_order_id_line_id_key
+    ON trending_products (order_id, line_id);
@@ -0,0 +1,15 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Co-occurrence of products in order history, rebuilt periodically by the recommendation builder.
+-- relation is 'bought_together' for products in the same order and 'also_bought' for products
+-- ordered by the same user.
+
+CREATE TABLE IF NOT EXISTS product_recommendations (
+    product_id         TEXT             NOT NULL,
+    related_product_id TEXT             NOT NULL,
+    relation           TEXT             NOT NULL,
+    support            INTEGER          NOT NULL,
+    score              DOUBLE PRECISION NOT NULL,
+    updated_at         TIMESTAMPTZ      NOT NULL,
+    PRIMARY KEY (product_id, relation, related_product_id)
+);
@@ -0,0 +1,7 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Stores favorite times with their time zone so favorite cursors compare the same instant whatever the
+-- session time zone. Existing times were written in UTC.
+
+ALTER TABLE user_product_favorites
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
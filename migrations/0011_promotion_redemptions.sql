@@ -0,0 +1,15 @@
+-- Run against the service schema (DB_SCHEMA), e.g. SET search_path TO <schema>;
+--
+-- Promotions redeemed on recorded orders, counted per user against a promotion's
+-- RedemptionLimitPerUser. An order redeems a promotion once however often its event is delivered.
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    order_id     TEXT        NOT NULL,
+    promotion_id TEXT        NOT NULL,
+    user_id      TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (order_id, promotion_id)
+);
+
+CREATE INDEX IF NOT EXISTS promotion_redemptions_user_id_promotion_id_idx
+    ON promotion_redemptions (user_id, promotion_id);
@@ -4,16 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
 	"mpmy-product-service/httprequest"
 )
 
-const (
-	MYCatalogID = "zp-my"
-)
-
 type CategoryProductParams struct {
+	CatalogID  string `json:"catalogID"`
 	CategoryID string `json:"categoryID"`
 	ProductID  string `json:"productID"`
 	Page       string `json:"page"`
@@ -42,7 +41,9 @@ type CategoryProductItem struct {
 
 type ICategoryProductRepository interface {
 	GetCategoryProducts(params CategoryProductParams, accessToken string) (CategoryProductResponse, error)
-	GetCategoryProductByProductID(productID string, accessToken string) (CategoryProductItem, error)
+	GetCategoryProductByProductID(catalogID string, productID string, accessToken string) (CategoryProductItem, error)
+	GetCatalogCategoryProducts(catalogID string, accessToken string) ([]CategoryProductItem, error)
+	GetCategoryProductsByProductIDs(catalogID string, productIDs []string, accessToken string) ([]CategoryProductItem, error)
 }
 
 type CategoryProductRepository struct {
@@ -59,7 +60,7 @@ func NewCategoryProductRepository(orderConfig config.OrderCloudConfig) *Category
 
 func (repo *CategoryProductRepository) GetCategoryProducts(params CategoryProductParams, accessToken string) (CategoryProductResponse, error) {
 	// prepare request specifications
-	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+MYCatalogID+"/categories/productassignments")
+	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+params.CatalogID+"/categories/productassignments")
 	requestSpecifications := &httprequest.RequestSpecifications{
 		HTTPMethod: http.MethodGet,
 		URL:        url,
@@ -88,9 +89,103 @@ func (repo *CategoryProductRepository) GetCategoryProducts(params CategoryProduc
 	return assignmentResp, nil
 }
 
-func (repo *CategoryProductRepository) GetCategoryProductByProductID(productID string, accessToken string) (CategoryProductItem, error) {
+// GetCatalogCategoryProducts fetches every product assignment of the catalog's categories, pages after the first
+// concurrently
+func (repo *CategoryProductRepository) GetCatalogCategoryProducts(catalogID string, accessToken string) ([]CategoryProductItem, error) {
+	return repo.fetchCatalogCategoryProducts(catalogID, "", accessToken)
+}
+
+// GetCategoryProductsByProductIDs fetches the category assignments of the products in the catalog, filtering on
+// constants.CategoryProductIDBatchSize product ids per request
+func (repo *CategoryProductRepository) GetCategoryProductsByProductIDs(catalogID string, productIDs []string, accessToken string) ([]CategoryProductItem, error) {
+	var assignments []CategoryProductItem
+	for start := 0; start < len(productIDs); start += constants.CategoryProductIDBatchSize {
+		end := start + constants.CategoryProductIDBatchSize
+		if end > len(productIDs) {
+			end = len(productIDs)
+		}
+
+		batch, err := repo.fetchCatalogCategoryProducts(catalogID, strings.Join(productIDs[start:end], "|"), accessToken)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, batch...)
+	}
+
+	return assignments, nil
+}
+
+// fetchCatalogCategoryProducts fetches every page of the catalog's category assignments, of the products matching
+// the productID filter when one is given
+func (repo *CategoryProductRepository) fetchCatalogCategoryProducts(catalogID string, productID string, accessToken string) ([]CategoryProductItem, error) {
+	firstPage, err := repo.fetchCatalogCategoryProductPage(catalogID, productID, 1, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := 1
+	if firstPage.Meta.TotalPages > 1 {
+		totalPages = firstPage.Meta.TotalPages
+	}
+
+	pages := make([][]CategoryProductItem, totalPages+1)
+	pages[1] = firstPage.Items
+
+	err = fetchRemainingPages(totalPages, constants.MaxConcurrentCategoryPages, func(page int) error {
+		assignments, err := repo.fetchCatalogCategoryProductPage(catalogID, productID, page, accessToken)
+		if err != nil {
+			return err
+		}
+		pages[page] = assignments.Items
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []CategoryProductItem
+	for _, items := range pages {
+		assignments = append(assignments, items...)
+	}
+
+	return assignments, nil
+}
+
+func (repo *CategoryProductRepository) fetchCatalogCategoryProductPage(catalogID string, productID string, page int, accessToken string) (CategoryProductResponse, error) {
+	// prepare request specifications
+	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+catalogID+"/categories/productassignments")
+	requestSpecifications := &httprequest.RequestSpecifications{
+		HTTPMethod: http.MethodGet,
+		URL:        url,
+		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
+		Params: map[string]interface{}{
+			"page":     page,
+			"pageSize": constants.CategoriesPageSize,
+		},
+	}
+	if productID != "" {
+		requestSpecifications.Params["productID"] = productID
+	}
+
+	// make request
+	statusCode, response, _ := repo.httpRequestHandler.MakeRequest(requestSpecifications)
+	if statusCode != http.StatusOK {
+		return CategoryProductResponse{}, fmt.Errorf("failed to fetch category product assignments")
+	}
+
+	var assignmentResp CategoryProductResponse
+
+	err := json.Unmarshal(response, &assignmentResp)
+	if err != nil {
+		return CategoryProductResponse{}, err
+	}
+
+	return assignmentResp, nil
+}
+
+func (repo *CategoryProductRepository) GetCategoryProductByProductID(catalogID string, productID string, accessToken string) (CategoryProductItem, error) {
 	// prepare request specifications
-	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+MYCatalogID+"/categories/productassignments")
+	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+catalogID+"/categories/productassignments")
 	requestSpecifications := &httprequest.RequestSpecifications{
 		HTTPMethod: http.MethodGet,
 		URL:        url,
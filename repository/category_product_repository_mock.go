@@ -21,7 +21,7 @@ type CategoryProductRepositoryMock struct {
 
 func (repo *CategoryProductRepositoryMock) GetCategoryProducts(params CategoryProductParams, accessToken string) (CategoryProductResponse, error) {
 	// prepare request specifications
-	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+MYCatalogID+"/categories/productassignments")
+	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+params.CatalogID+"/categories/productassignments")
 	requestSpecifications := &httprequest.RequestSpecifications{
 		HTTPMethod: http.MethodGet,
 		URL:        url,
@@ -50,9 +50,21 @@ func (repo *CategoryProductRepositoryMock) GetCategoryProducts(params CategoryPr
 	return assignmentResp, nil
 }
 
-func (repo *CategoryProductRepositoryMock) GetCategoryProductByProductID(productID string, accessToken string) (CategoryProductItem, error) {
+func (repo *CategoryProductRepositoryMock) GetCategoryProductByProductID(catalogID string, productID string, accessToken string) (CategoryProductItem, error) {
 
-	args := repo.Called(productID, accessToken)
+	args := repo.Called(catalogID, productID, accessToken)
 
 	return args.Get(0).(CategoryProductItem), args.Error(1)
 }
+
+func (repo *CategoryProductRepositoryMock) GetCategoryProductsByProductIDs(catalogID string, productIDs []string, accessToken string) ([]CategoryProductItem, error) {
+	args := repo.Called(catalogID, productIDs, accessToken)
+
+	return args.Get(0).([]CategoryProductItem), args.Error(1)
+}
+
+func (repo *CategoryProductRepositoryMock) GetCatalogCategoryProducts(catalogID string, accessToken string) ([]CategoryProductItem, error) {
+	args := repo.Called(catalogID, accessToken)
+
+	return args.Get(0).([]CategoryProductItem), args.Error(1)
+}
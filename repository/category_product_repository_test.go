@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
+)
+
+func TestGetCategoryProductsByProductIDs(t *testing.T) {
+
+	//data test
+	var productIDs []string
+	for i := 0; i < constants.CategoryProductIDBatchSize+1; i++ {
+		productIDs = append(productIDs, "p"+strconv.Itoa(i))
+	}
+
+	var mutex sync.Mutex
+	var filters []string
+
+	// the first batch has its assignments on two pages
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		productID := r.URL.Query().Get("productID")
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+		mutex.Lock()
+		filters = append(filters, productID+"@"+strconv.Itoa(page))
+		mutex.Unlock()
+
+		ids := strings.Split(productID, "|")
+		totalPages := 1
+		if len(ids) > 1 {
+			totalPages = 2
+		}
+		_ = json.NewEncoder(w).Encode(CategoryProductResponse{
+			Meta:  categoryProductMeta{Page: page, TotalPages: totalPages},
+			Items: []CategoryProductItem{{CategoryID: "c" + strconv.Itoa(page), ProductID: ids[0]}},
+		})
+	}))
+	defer server.Close()
+
+	repo := NewCategoryProductRepository(config.OrderCloudConfig{OrderCloudEngine: server.URL})
+
+	assignments, err := repo.GetCategoryProductsByProductIDs("catalog", productIDs, "token")
+	if err != nil {
+		t.Fatal("test failed error: ", err)
+	}
+
+	if len(filters) != 3 {
+		t.Fatal("test failed: two pages of the first batch and one of the second must be requested, got", filters)
+	}
+	firstBatch := strings.Join(productIDs[:constants.CategoryProductIDBatchSize], "|")
+	if filters[0] != firstBatch+"@1" || filters[1] != firstBatch+"@2" || filters[2] != productIDs[constants.CategoryProductIDBatchSize]+"@1" {
+		t.Error("test failed: product ids must be filtered in batches, got", filters)
+	}
+	if len(assignments) != 3 {
+		t.Error("test failed: assignments of every page must be returned, got", len(assignments))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"mpmy-product-service/graph/model"
 	"mpmy-product-service/httprequest"
 	"net/http"
+	"strings"
 )
 
 type GetCategoryParams struct {
@@ -26,7 +27,62 @@ func NewCategoryRepository(orderConfig config.OrderCloudConfig) *CategoryReposit
 	}
 }
 
+// FetchCategories fetches every page of the catalog's categories, pages after the first concurrently, and
+// returns them as a tree in a single page
 func (repo *CategoryRepository) FetchCategories(params GetCategoryParams, accessToken string) (model.CategoryResponse, error) {
+	firstPage, err := repo.fetchCategoryPage(params, 1, accessToken)
+	if err != nil {
+		return model.CategoryResponse{}, err
+	}
+
+	totalPages := 1
+	if firstPage.Meta != nil && firstPage.Meta.TotalPages > 1 {
+		totalPages = firstPage.Meta.TotalPages
+	}
+
+	pages := make([][]*model.CategoryItems, totalPages+1)
+	pages[1] = firstPage.Items
+
+	err = fetchRemainingPages(totalPages, constants.MaxConcurrentCategoryPages, func(page int) error {
+		categories, err := repo.fetchCategoryPage(params, page, accessToken)
+		if err != nil {
+			return err
+		}
+		pages[page] = categories.Items
+		return nil
+	})
+	if err != nil {
+		return model.CategoryResponse{}, err
+	}
+
+	var categories []*model.CategoryItems
+	for _, items := range pages {
+		categories = append(categories, items...)
+	}
+
+	tree := BuildCategoryTree(categories)
+	if len(tree.Orphans) > 0 {
+		fmt.Printf("catalog %s has %d orphaned categories: %s\n", params.CatalogID, len(tree.Orphans), categoryIDs(tree.Orphans))
+	}
+
+	totalCount := len(categories)
+	rangeStart := 1
+	if totalCount == 0 {
+		rangeStart = 0
+	}
+	return model.CategoryResponse{
+		Meta: &model.CategoryMeta{
+			Page:       1,
+			PageSize:   totalCount,
+			TotalCount: totalCount,
+			TotalPages: 1,
+			ItemRange:  []*int{&rangeStart, &totalCount},
+		},
+		Items: tree.Roots,
+	}, nil
+}
+
+func (repo *CategoryRepository) fetchCategoryPage(params GetCategoryParams, page int, accessToken string) (model.CategoryResponse, error) {
 	// prepare request specifications
 	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/catalogs/"+params.CatalogID+"/categories")
 	requestSpecifications := &httprequest.RequestSpecifications{
@@ -35,7 +91,8 @@ func (repo *CategoryRepository) FetchCategories(params GetCategoryParams, access
 		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
 		Params: map[string]interface{}{
 			"depth":    params.Depth,
-			"pageSize": constants.MinimumCategoriesShown,
+			"page":     page,
+			"pageSize": constants.CategoriesPageSize,
 		},
 	}
 	// make request
@@ -48,52 +105,13 @@ func (repo *CategoryRepository) FetchCategories(params GetCategoryParams, access
 	if err != nil {
 		return model.CategoryResponse{}, err
 	}
-	depthTwoCategory := make(map[string]*model.CategoryItems)
-	childCategory := make(map[string]*model.CategoryItems)
-	parentCategory := make(map[string]*model.CategoryItems)
-	for _, category := range assignmentResp.Items {
-		if category.ParentID == "" {
-			parentCategory[category.ID] = category
-			continue
-		} else if category.ChildCount == 0 && category.ParentID != "" {
-			childCategory[category.ID] = category
-			continue
-		}
-		depthTwoCategory[category.ID] = category
-	}
-	depthTwoCategory, parentCategory = reOrderingDepthCategory(depthTwoCategory, childCategory, parentCategory)
-	parentCategory = parentCategoryResponse(depthTwoCategory, parentCategory)
-	var responseRestructure []*model.CategoryItems
-	for _, item := range parentCategory {
-		responseRestructure = append(responseRestructure, item)
-	}
-	assignmentResp.Items = responseRestructure
 	return assignmentResp, nil
 }
 
-func reOrderingDepthCategory(depthTwoCategory, childCategory, parentCategory map[string]*model.CategoryItems) (map[string]*model.CategoryItems, map[string]*model.CategoryItems) {
-	for _, val := range childCategory {
-		if depthTwoCategorydata, ok := depthTwoCategory[val.ParentID]; ok {
-			depthTwoCategorydata.ChildData = append(depthTwoCategorydata.ChildData, val)
-			depthTwoCategory[val.ParentID] = depthTwoCategorydata
-			defer delete(childCategory, val.ID)
-			continue
-		} else if parentCategorydata, ok := parentCategory[val.ParentID]; ok {
-			parentCategorydata.ChildData = append(parentCategorydata.ChildData, val)
-			parentCategory[val.ParentID] = parentCategorydata
-			defer delete(childCategory, val.ID)
-			continue
-		}
-	}
-	return depthTwoCategory, parentCategory
-}
-func parentCategoryResponse(depthTwoCategory, parentCategory map[string]*model.CategoryItems) map[string]*model.CategoryItems {
-	for key, val := range depthTwoCategory {
-		if parentCategorydata, ok := parentCategory[val.ParentID]; ok {
-			parentCategorydata.ChildData = append(parentCategorydata.ChildData, val)
-			parentCategory[val.ParentID] = parentCategorydata
-			defer delete(depthTwoCategory, key)
-		}
+func categoryIDs(categories []*model.CategoryItems) string {
+	ids := make([]string, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
 	}
-	return parentCategory
+	return strings.Join(ids, ", ")
 }
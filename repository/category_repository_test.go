@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
+	"mpmy-product-service/graph/model"
+)
+
+func TestFetchCategoriesAllPages(t *testing.T) {
+
+	//data test
+	var totalPages = 6
+	var mutex sync.Mutex
+	var requestedPages = make(map[int]int)
+	var inFlight, maxInFlight int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+		mutex.Lock()
+		requestedPages[page]++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		defer func() {
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+		}()
+
+		// a root per page with a child on the next page
+		pageID := strconv.Itoa(page)
+		items := []*model.CategoryItems{{ID: "root" + pageID, Name: "Root " + pageID, ListOrder: totalPages - page}}
+		if page > 1 {
+			items = append(items, &model.CategoryItems{ID: "child" + pageID, ParentID: "root" + strconv.Itoa(page-1)})
+		}
+		_ = json.NewEncoder(w).Encode(model.CategoryResponse{
+			Meta:  &model.CategoryMeta{Page: page, TotalPages: totalPages},
+			Items: items,
+		})
+	}))
+	defer server.Close()
+
+	repo := NewCategoryRepository(config.OrderCloudConfig{OrderCloudEngine: server.URL})
+
+	categories, err := repo.FetchCategories(GetCategoryParams{CatalogID: "catalog", Depth: "all"}, "token")
+	if err != nil {
+		t.Error("test failed error: ", err)
+		return
+	}
+
+	for page := 1; page <= totalPages; page++ {
+		if requestedPages[page] != 1 {
+			t.Error("test failed: page", page, "must be requested once, got", requestedPages[page])
+		}
+	}
+	if maxInFlight > constants.MaxConcurrentCategoryPages {
+		t.Error("test failed: at most", constants.MaxConcurrentCategoryPages, "pages may be requested at once, got", maxInFlight)
+	}
+	if categories.Meta.TotalCount != 2*totalPages-1 {
+		t.Error("test failed: total count must include every page, got", categories.Meta.TotalCount)
+	}
+	if len(categories.Items) != totalPages || categories.Items[0].ID != "root6" || len(categories.Items[5].ChildData) != 1 {
+		t.Error("test failed: categories of all pages must be assembled into one ordered tree")
+	}
+}
+
+func TestFetchCategoriesPageError(t *testing.T) {
+
+	//data test
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "3" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(model.CategoryResponse{Meta: &model.CategoryMeta{TotalPages: 4}})
+	}))
+	defer server.Close()
+
+	repo := NewCategoryRepository(config.OrderCloudConfig{OrderCloudEngine: server.URL})
+
+	_, err := repo.FetchCategories(GetCategoryParams{CatalogID: "catalog", Depth: "all"}, "token")
+	if err == nil {
+		t.Error("test failed: a failed page must fail the fetch")
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"sort"
+
+	"mpmy-product-service/graph/model"
+)
+
+// CategoryTree is a catalog's categories nested under their parents
+type CategoryTree struct {
+	Roots []*model.CategoryItems
+
+	// Orphans are categories left out of the tree, their parent is missing, they are part of a parent cycle
+	// or they repeat the id of an earlier category
+	Orphans []*model.CategoryItems
+}
+
+// BuildCategoryTree nests categories under their parents to any depth. Siblings are ordered by ListOrder, then
+// Name, then ID. ChildData of every category is replaced
+func BuildCategoryTree(categories []*model.CategoryItems) CategoryTree {
+	var tree CategoryTree
+
+	// index categories, keeping the first of repeated ids
+	var unique []*model.CategoryItems
+	categoriesByID := make(map[string]*model.CategoryItems, len(categories))
+	for _, category := range categories {
+		if category == nil {
+			continue
+		}
+		if _, ok := categoriesByID[category.ID]; ok {
+			tree.Orphans = append(tree.Orphans, category)
+			continue
+		}
+		category.ChildData = []*model.CategoryItems{}
+		categoriesByID[category.ID] = category
+		unique = append(unique, category)
+	}
+
+	children := make(map[string][]*model.CategoryItems)
+	for _, category := range unique {
+		if category.ParentID == "" {
+			tree.Roots = append(tree.Roots, category)
+			continue
+		}
+		children[category.ParentID] = append(children[category.ParentID], category)
+	}
+	sortCategories(tree.Roots)
+
+	// attach children breadth first from the roots, categories under a missing parent or in a cycle are never reached
+	placed := make(map[string]bool, len(unique))
+	queue := append([]*model.CategoryItems{}, tree.Roots...)
+	for len(queue) > 0 {
+		category := queue[0]
+		queue = queue[1:]
+		placed[category.ID] = true
+
+		if childCategories, ok := children[category.ID]; ok {
+			sortCategories(childCategories)
+			category.ChildData = childCategories
+			queue = append(queue, childCategories...)
+		}
+	}
+
+	for _, category := range unique {
+		if !placed[category.ID] {
+			tree.Orphans = append(tree.Orphans, category)
+		}
+	}
+
+	return tree
+}
+
+func sortCategories(categories []*model.CategoryItems) {
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].ListOrder != categories[j].ListOrder {
+			return categories[i].ListOrder < categories[j].ListOrder
+		}
+		if categories[i].Name != categories[j].Name {
+			return categories[i].Name < categories[j].Name
+		}
+		return categories[i].ID < categories[j].ID
+	})
+}
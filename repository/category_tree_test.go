@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"mpmy-product-service/graph/model"
+)
+
+func category(id, parentID, name string, listOrder int) *model.CategoryItems {
+	return &model.CategoryItems{ID: id, ParentID: parentID, Name: name, ListOrder: listOrder}
+}
+
+// renderCategories renders categories as "a(b,c(d))" for comparison
+func renderCategories(categories []*model.CategoryItems) string {
+	parts := make([]string, len(categories))
+	for i, c := range categories {
+		parts[i] = c.ID
+		if len(c.ChildData) > 0 {
+			parts[i] += "(" + renderCategories(c.ChildData) + ")"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+
+	//data test
+	var tests = []struct {
+		name       string
+		categories []*model.CategoryItems
+		tree       string
+		orphans    string
+	}{
+		{
+			name:       "empty",
+			categories: nil,
+			tree:       "",
+			orphans:    "",
+		},
+		{
+			name: "flat roots ordered by list order then name",
+			categories: []*model.CategoryItems{
+				category("c", "", "Beta", 1),
+				category("b", "", "Alpha", 1),
+				category("a", "", "Zulu", 0),
+			},
+			tree:    "a,b,c",
+			orphans: "",
+		},
+		{
+			name: "deep tree in reverse order",
+			categories: []*model.CategoryItems{
+				category("l6", "l5", "L6", 0),
+				category("l5", "l4", "L5", 0),
+				category("l4", "l3", "L4", 0),
+				category("l3", "l2", "L3", 0),
+				category("l2", "l1", "L2", 0),
+				category("l1", "", "L1", 0),
+			},
+			tree:    "l1(l2(l3(l4(l5(l6)))))",
+			orphans: "",
+		},
+		{
+			name: "siblings ordered at every level",
+			categories: []*model.CategoryItems{
+				category("root", "", "Root", 0),
+				category("x", "root", "X", 2),
+				category("y", "root", "Y", 1),
+				category("y2", "y", "B", 0),
+				category("y1", "y", "A", 0),
+				category("same2", "x", "Same", 0),
+				category("same1", "x", "Same", 0),
+			},
+			tree:    "root(y(y1,y2),x(same1,same2))",
+			orphans: "",
+		},
+		{
+			name: "missing parent",
+			categories: []*model.CategoryItems{
+				category("a", "", "A", 0),
+				category("b", "missing", "B", 0),
+				category("c", "b", "C", 0),
+			},
+			tree:    "a",
+			orphans: "b,c",
+		},
+		{
+			name: "parent cycle",
+			categories: []*model.CategoryItems{
+				category("a", "", "A", 0),
+				category("b", "c", "B", 0),
+				category("c", "b", "C", 0),
+				category("d", "d", "D", 0),
+			},
+			tree:    "a",
+			orphans: "b,c,d",
+		},
+		{
+			name: "repeated id and nil category",
+			categories: []*model.CategoryItems{
+				category("a", "", "A", 0),
+				nil,
+				category("b", "a", "B", 0),
+				category("b", "", "B again", 0),
+			},
+			tree:    "a(b)",
+			orphans: "b",
+		},
+	}
+
+	for _, test := range tests {
+		tree := BuildCategoryTree(test.categories)
+
+		if got := renderCategories(tree.Roots); got != test.tree {
+			t.Error("test failed, "+test.name+": expected tree", test.tree, "got", got)
+		}
+		if got := categoryIDs(tree.Orphans); strings.ReplaceAll(got, " ", "") != test.orphans {
+			t.Error("test failed, "+test.name+": expected orphans", test.orphans, "got", got)
+		}
+	}
+}
+
+func TestBuildCategoryTreeResetsChildren(t *testing.T) {
+
+	//data test
+	var root = category("root", "", "Root", 0)
+	var leaf = category("leaf", "root", "Leaf", 0)
+	leaf.ChildData = []*model.CategoryItems{category("stale", "leaf", "Stale", 0)}
+
+	BuildCategoryTree([]*model.CategoryItems{root, leaf})
+	tree := BuildCategoryTree([]*model.CategoryItems{root, leaf})
+
+	if got := renderCategories(tree.Roots); got != "root(leaf)" {
+		t.Error("test failed: children must be rebuilt, got", got)
+	}
+	if leaf.ChildData == nil {
+		t.Error("test failed: leaf categories must have empty children")
+	}
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
 
 func GetString(s *string) string {
 	if s == nil {
@@ -39,3 +43,34 @@ func GetIntFromString(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
 }
+
+// EscapeLikePattern escapes the wildcard characters of a LIKE pattern
+func EscapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// fetchRemainingPages calls fetch for pages 2 to totalPages, at most maxConcurrent at once, and returns the first
+// error
+func fetchRemainingPages(totalPages, maxConcurrent int, fetch func(page int) error) error {
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var pageErr error
+
+	semaphore := make(chan struct{}, maxConcurrent)
+	for page := 2; page <= totalPages; page++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := fetch(page)
+			if err != nil {
+				errOnce.Do(func() { pageErr = err })
+			}
+		}(page)
+	}
+	wg.Wait()
+
+	return pageErr
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+)
+
+const (
+	// orderLineInsertBatchSize is the most order lines written by a single insert
+	orderLineInsertBatchSize = 500
+)
+
+// OrderLineItem is an ordered line item as recorded in trending products
+type OrderLineItem struct {
+	OrderID    string
+	LineID     string
+	UserID     string
+	ProductID  string
+	Quantity   int
+	CategoryID *string
+	SupplierID *string
+	CreatedAt  time.Time
+}
+
+type OrderEventRepository struct {
+	db       *sqlx.DB
+	dbConfig config.DBConfig
+}
+
+func NewOrderEventRepository(db *sqlx.DB, dbConfig config.DBConfig) *OrderEventRepository {
+	return &OrderEventRepository{
+		db:       db,
+		dbConfig: dbConfig,
+	}
+}
+
+// SaveOrderLines writes order lines to trending products in a single transaction, lines already recorded
+// for the same order and line id are skipped. Returns the number of lines written
+func (repo *OrderEventRepository) SaveOrderLines(items []OrderLineItem) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	const columnCount = 8
+	var recorded int64
+	for start := 0; start < len(items); start += orderLineInsertBatchSize {
+		end := start + orderLineInsertBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*columnCount)
+		for i, item := range items[start:end] {
+			n := i * columnCount
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+			args = append(args, item.OrderID, item.LineID, item.UserID, item.ProductID, item.Quantity, item.CategoryID, item.SupplierID, item.CreatedAt)
+		}
+
+		query := "INSERT INTO " + repo.dbConfig.Schema + "." + TrendingProductTableName + " (" +
+			"order_id, line_id, user_id, product_id, quantity, category_id, supplier_id, created_at) " +
+			"VALUES " + strings.Join(values, ", ") + " ON CONFLICT (order_id, line_id) DO NOTHING"
+
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		recorded += affected
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(recorded), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"mpmy-product-service/graph/model"
 	"net/http"
+	"strings"
 
 	"mpmy-product-service/constants"
 	"mpmy-product-service/httprequest"
@@ -18,8 +19,23 @@ type PriceScheduleParams struct {
 	PageSize     string                 `json:"pageSize"`
 }
 
+// ProductAssignmentItem is an order cloud product assignment, assigning a product to a buyer or to one of its
+// user groups. PriceScheduleID, when set, prices the product for the party instead of its default price schedule
+type ProductAssignmentItem struct {
+	ProductID       string `json:"ProductID"`
+	BuyerID         string `json:"BuyerID"`
+	UserGroupID     string `json:"UserGroupID"`
+	PriceScheduleID string `json:"PriceScheduleID"`
+}
+
+type ProductAssignmentResponse struct {
+	Meta  categoryProductMeta     `json:"Meta"`
+	Items []ProductAssignmentItem `json:"Items"`
+}
+
 type IPriceScheduleRepository interface {
 	GetPriceSchedules(params PriceScheduleParams, accessToken string) (model.PriceScheduleResponse, error)
+	GetProductAssignments(productIDs []string, buyerID string, accessToken string) ([]ProductAssignmentItem, error)
 }
 
 type PriceScheduleRepository struct {
@@ -75,3 +91,90 @@ func (repo *PriceScheduleRepository) GetPriceSchedules(params PriceScheduleParam
 
 	return assignmentResp, nil
 }
+
+// GetProductAssignments fetches the assignments of the products to the buyer and its user groups, filtering on
+// batches of product ids so only the products asked for are paged through
+func (repo *PriceScheduleRepository) GetProductAssignments(productIDs []string, buyerID string, accessToken string) ([]ProductAssignmentItem, error) {
+	var assignments []ProductAssignmentItem
+	for start := 0; start < len(productIDs); start += constants.ProductAssignmentIDBatchSize {
+		end := start + constants.ProductAssignmentIDBatchSize
+		if end > len(productIDs) {
+			end = len(productIDs)
+		}
+
+		batch, err := repo.fetchProductAssignments(strings.Join(productIDs[start:end], "|"), buyerID, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, batch...)
+	}
+
+	return assignments, nil
+}
+
+// fetchProductAssignments fetches every page of the buyer's product assignments of the products matching the
+// productID filter, pages after the first concurrently
+func (repo *PriceScheduleRepository) fetchProductAssignments(productID, buyerID string, accessToken string) ([]ProductAssignmentItem, error) {
+	firstPage, err := repo.fetchProductAssignmentPage(productID, buyerID, 1, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := 1
+	if firstPage.Meta.TotalPages > 1 {
+		totalPages = firstPage.Meta.TotalPages
+	}
+
+	pages := make([][]ProductAssignmentItem, totalPages+1)
+	pages[1] = firstPage.Items
+
+	err = fetchRemainingPages(totalPages, constants.MaxConcurrentProductAssignmentPages, func(page int) error {
+		assignments, err := repo.fetchProductAssignmentPage(productID, buyerID, page, accessToken)
+		if err != nil {
+			return err
+		}
+		pages[page] = assignments.Items
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []ProductAssignmentItem
+	for _, items := range pages {
+		assignments = append(assignments, items...)
+	}
+
+	return assignments, nil
+}
+
+func (repo *PriceScheduleRepository) fetchProductAssignmentPage(productID, buyerID string, page int, accessToken string) (ProductAssignmentResponse, error) {
+	// prepare request specifications
+	url := fmt.Sprintf("%s/%s", constants.OrderCloudEngine, "v1/products/assignments")
+	requestSpecifications := &httprequest.RequestSpecifications{
+		HTTPMethod: http.MethodGet,
+		URL:        url,
+		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
+		Params: map[string]interface{}{
+			"buyerID":   buyerID,
+			"page":      page,
+			"pageSize":  constants.ProductAssignmentsPageSize,
+			"productID": productID,
+		},
+	}
+
+	// make request
+	statusCode, response, _ := repo.httpRequestHandler.MakeRequest(requestSpecifications)
+	if statusCode != http.StatusOK {
+		return ProductAssignmentResponse{}, fmt.Errorf("failed to fetch product assignments")
+	}
+
+	var assignmentResp ProductAssignmentResponse
+
+	err := json.Unmarshal(response, &assignmentResp)
+	if err != nil {
+		return ProductAssignmentResponse{}, err
+	}
+
+	return assignmentResp, nil
+}
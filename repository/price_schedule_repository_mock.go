@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"mpmy-product-service/graph/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PriceScheduleRepositoryMock struct {
+	mock.Mock
+}
+
+func (repo *PriceScheduleRepositoryMock) GetPriceSchedules(params PriceScheduleParams, accessToken string) (model.PriceScheduleResponse, error) {
+	args := repo.Called(params, accessToken)
+
+	return args.Get(0).(model.PriceScheduleResponse), args.Error(1)
+}
+
+func (repo *PriceScheduleRepositoryMock) GetProductAssignments(productIDs []string, buyerID string, accessToken string) ([]ProductAssignmentItem, error) {
+	args := repo.Called(productIDs, buyerID, accessToken)
+
+	return args.Get(0).([]ProductAssignmentItem), args.Error(1)
+}
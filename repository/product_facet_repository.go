@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/httprequest"
+)
+
+const (
+	// MaximumProductFacetsPageSize is the largest page size accepted by order cloud
+	MaximumProductFacetsPageSize = 100
+)
+
+type ProductFacetResponse struct {
+	Meta  model.OrderCloudMeta     `json:"Meta"`
+	Items []ProductFacetDefinition `json:"Items"`
+}
+
+type ProductFacetDefinition struct {
+	ID        string `json:"ID"`
+	Name      string `json:"Name"`
+	XpPath    string `json:"XpPath"`
+	ListOrder int    `json:"ListOrder"`
+	MinCount  int    `json:"MinCount"`
+}
+
+type IProductFacetRepository interface {
+	GetProductFacets(accessToken string) ([]ProductFacetDefinition, error)
+}
+
+type ProductFacetRepository struct {
+	orderCloud         config.OrderCloudConfig
+	httpRequestHandler *httprequest.RequestHandler
+}
+
+func NewProductFacetRepository(orderConfig config.OrderCloudConfig) *ProductFacetRepository {
+	return &ProductFacetRepository{
+		orderCloud:         orderConfig,
+		httpRequestHandler: httprequest.NewRequestHandler("ProductFacetRepository"),
+	}
+}
+
+func (repo *ProductFacetRepository) GetProductFacets(accessToken string) ([]ProductFacetDefinition, error) {
+	// prepare request specifications
+	url := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/productfacets")
+	requestSpecifications := &httprequest.RequestSpecifications{
+		HTTPMethod: http.MethodGet,
+		URL:        url,
+		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
+		Params: map[string]interface{}{
+			"pageSize": MaximumProductFacetsPageSize,
+			"sortBy":   "ListOrder",
+		},
+	}
+
+	// make request
+	statusCode, response, _ := repo.httpRequestHandler.MakeRequest(requestSpecifications)
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch product facets")
+	}
+
+	var facetResp ProductFacetResponse
+
+	err := json.Unmarshal(response, &facetResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return facetResp.Items, nil
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type ProductFacetRepositoryMock struct {
+	mock.Mock
+}
+
+func (repo *ProductFacetRepositoryMock) GetProductFacets(accessToken string) ([]ProductFacetDefinition, error) {
+	args := repo.Called(accessToken)
+
+	return args.Get(0).([]ProductFacetDefinition), args.Error(1)
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+)
+
+const (
+	// ProductListsTableName is the name of product lists table
+	ProductListsTableName = "product_lists"
+
+	// ProductListItemsTableName is the name of product list items table
+	ProductListItemsTableName = "product_list_items"
+
+	// productListItemColumns are the columns mapped to model.ProductListItem
+	productListItemColumns = "id, list_id, product_id, quantity, notes, position, created_at, updated_at"
+)
+
+// ProductListRecord is a product list along with its owner
+type ProductListRecord struct {
+	model.ProductList
+	UserID  string  `db:"user_id"`
+	BuyerID *string `db:"buyer_id"`
+}
+
+type ProductListRepository struct {
+	db       *sqlx.DB
+	dbConfig config.DBConfig
+}
+
+func NewProductListRepository(db *sqlx.DB, dbConfig config.DBConfig) *ProductListRepository {
+	return &ProductListRepository{
+		db:       db,
+		dbConfig: dbConfig,
+	}
+}
+
+func (repo *ProductListRepository) CreateProductList(userID, buyerID *string, name string, description *string) (int, error) {
+	currentTime := time.Now().UTC()
+
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + ProductListsTableName +
+		" (user_id, buyer_id, name, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $5) RETURNING id"
+
+	var id int
+	err := repo.db.Get(&id, query, userID, buyerID, name, description, currentTime)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetProductLists returns the user's lists, most recently updated first
+func (repo *ProductListRepository) GetProductLists(userID *string) ([]*ProductListRecord, error) {
+	productLists := []*ProductListRecord{}
+
+	query := repo.selectProductLists() + " WHERE l.user_id = $1 ORDER BY l.updated_at DESC, l.id DESC"
+
+	err := repo.db.Select(&productLists, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return productLists, nil
+}
+
+// GetProductList returns the list with the given id, or nil when it does not exist
+func (repo *ProductListRepository) GetProductList(id int) (*ProductListRecord, error) {
+	return repo.getProductList(" WHERE l.id = $1", id)
+}
+
+// GetProductListByShareToken returns the list shared with the given token, or nil when none is
+func (repo *ProductListRepository) GetProductListByShareToken(shareToken string) (*ProductListRecord, error) {
+	return repo.getProductList(" WHERE l.share_token = $1", shareToken)
+}
+
+func (repo *ProductListRepository) getProductList(condition string, arg interface{}) (*ProductListRecord, error) {
+	var productList ProductListRecord
+
+	err := repo.db.Get(&productList, repo.selectProductLists()+condition, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &productList, nil
+}
+
+func (repo *ProductListRepository) selectProductLists() string {
+	return "SELECT l.id, l.user_id, l.buyer_id, l.name, l.description, l.share_token, l.created_at, l.updated_at, " +
+		"(SELECT COUNT(*) FROM " + repo.dbConfig.Schema + "." + ProductListItemsTableName + " i WHERE i.list_id = l.id) AS item_count " +
+		"FROM " + repo.dbConfig.Schema + "." + ProductListsTableName + " l"
+}
+
+func (repo *ProductListRepository) UpdateProductList(id int, name string, description *string) error {
+	query := "UPDATE " + repo.dbConfig.Schema + "." + ProductListsTableName +
+		" SET name = $2, description = $3, updated_at = $4 WHERE id = $1"
+
+	_, err := repo.db.Exec(query, id, name, description, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteProductList deletes the list along with its items
+func (repo *ProductListRepository) DeleteProductList(id int) error {
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + ProductListsTableName + " WHERE id = $1"
+
+	_, err := repo.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetProductListShareToken sets the token the list is shared with, a nil token stops sharing
+func (repo *ProductListRepository) SetProductListShareToken(id int, shareToken *string) error {
+	query := "UPDATE " + repo.dbConfig.Schema + "." + ProductListsTableName +
+		" SET share_token = $2, updated_at = $3 WHERE id = $1"
+
+	_, err := repo.db.Exec(query, id, shareToken, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetProductListItems returns the items of the lists in list order
+func (repo *ProductListRepository) GetProductListItems(listIDs []int) ([]*model.ProductListItem, error) {
+	items := []*model.ProductListItem{}
+	if len(listIDs) == 0 {
+		return items, nil
+	}
+
+	ids := make([]int64, len(listIDs))
+	for i, listID := range listIDs {
+		ids[i] = int64(listID)
+	}
+
+	query := "SELECT " + productListItemColumns + " FROM " + repo.dbConfig.Schema + "." + ProductListItemsTableName +
+		" WHERE list_id = ANY($1) ORDER BY list_id, position, id"
+
+	err := repo.db.Select(&items, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// SaveProductListItem adds a product at the end of the list, a product already in the list gets its quantity and notes updated.
+// The list row is locked so concurrent adds do not read the same last position
+func (repo *ProductListRepository) SaveProductListItem(listID int, productID string, quantity int, notes *string) error {
+	currentTime := time.Now().UTC()
+
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = repo.lockProductList(tx, listID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + ProductListItemsTableName + " AS i " +
+		"(list_id, product_id, quantity, notes, position, created_at, updated_at) " +
+		"VALUES ($1, $2, $3, $4, (SELECT COALESCE(MAX(position) + 1, 0) FROM " + repo.dbConfig.Schema + "." + ProductListItemsTableName + " WHERE list_id = $1), $5, $5) " +
+		"ON CONFLICT (list_id, product_id) DO UPDATE SET " +
+		"quantity = EXCLUDED.quantity, notes = COALESCE(EXCLUDED.notes, i.notes), updated_at = EXCLUDED.updated_at"
+
+	_, err = tx.Exec(query, listID, productID, quantity, notes, currentTime)
+	if err != nil {
+		return err
+	}
+
+	err = repo.touchProductList(tx, listID, currentTime)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateProductListItem updates the quantity and notes of a product in the list, nil values are left unchanged.
+// Returns false when the product is not in the list
+func (repo *ProductListRepository) UpdateProductListItem(listID int, productID string, quantity *int, notes *string) (bool, error) {
+	currentTime := time.Now().UTC()
+
+	query := "UPDATE " + repo.dbConfig.Schema + "." + ProductListItemsTableName +
+		" SET quantity = COALESCE($3, quantity), notes = COALESCE($4, notes), updated_at = $5 WHERE list_id = $1 AND product_id = $2"
+
+	result, err := repo.db.Exec(query, listID, productID, quantity, notes, currentTime)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	return true, repo.touchProductList(repo.db, listID, currentTime)
+}
+
+// DeleteProductListItem removes a product from the list, returns false when the product is not in the list
+func (repo *ProductListRepository) DeleteProductListItem(listID int, productID string) (bool, error) {
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + ProductListItemsTableName +
+		" WHERE list_id = $1 AND product_id = $2"
+
+	result, err := repo.db.Exec(query, listID, productID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	return true, repo.touchProductList(repo.db, listID, time.Now().UTC())
+}
+
+// UpdateProductListItemPositions positions the list's products in the order of productIDs, under the same list lock
+// as SaveProductListItem
+func (repo *ProductListRepository) UpdateProductListItemPositions(listID int, productIDs []string) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = repo.lockProductList(tx, listID)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE " + repo.dbConfig.Schema + "." + ProductListItemsTableName + " AS i SET position = v.position - 1 " +
+		"FROM unnest($2::text[]) WITH ORDINALITY AS v(product_id, position) " +
+		"WHERE i.list_id = $1 AND i.product_id = v.product_id"
+
+	_, err = tx.Exec(query, listID, pq.Array(productIDs))
+	if err != nil {
+		return err
+	}
+
+	err = repo.touchProductList(tx, listID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CopyProductList copies a list with its items to the user and returns the id of the new list
+func (repo *ProductListRepository) CopyProductList(sourceID int, userID, buyerID *string, name string) (int, error) {
+	currentTime := time.Now().UTC()
+
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + ProductListsTableName +
+		" (user_id, buyer_id, name, description, created_at, updated_at) " +
+		"SELECT $2, $3, $4, description, $5, $5 FROM " + repo.dbConfig.Schema + "." + ProductListsTableName + " WHERE id = $1 RETURNING id"
+
+	var id int
+	err = tx.Get(&id, query, sourceID, userID, buyerID, name, currentTime)
+	if err != nil {
+		return 0, err
+	}
+
+	query = "INSERT INTO " + repo.dbConfig.Schema + "." + ProductListItemsTableName +
+		" (list_id, product_id, quantity, notes, position, created_at, updated_at) " +
+		"SELECT $2, product_id, quantity, notes, position, $3, $3 FROM " + repo.dbConfig.Schema + "." + ProductListItemsTableName + " WHERE list_id = $1"
+
+	_, err = tx.Exec(query, sourceID, id, currentTime)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// lockProductList locks the list row until the transaction ends, a list that does not exist has no row to lock
+func (repo *ProductListRepository) lockProductList(tx *sqlx.Tx, id int) error {
+	query := "SELECT id FROM " + repo.dbConfig.Schema + "." + ProductListsTableName + " WHERE id = $1 FOR UPDATE"
+
+	var lockedID int
+	err := tx.Get(&lockedID, query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repo *ProductListRepository) touchProductList(execer sqlx.Execer, id int, updatedAt time.Time) error {
+	query := "UPDATE " + repo.dbConfig.Schema + "." + ProductListsTableName + " SET updated_at = $2 WHERE id = $1"
+
+	_, err := execer.Exec(query, id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+)
+
+func TestSaveProductListItemLocksList(t *testing.T) {
+
+	//data test
+	var listID = 7
+	var productID = "PRD-1"
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("test failed: sqlmock not created:", err)
+	}
+	defer db.Close()
+
+	// the position is read only once the list row is locked, and the lock is held until commit
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery("SELECT id FROM product.product_lists WHERE id = \\$1 FOR UPDATE").
+		WithArgs(listID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(listID))
+	dbMock.ExpectExec("INSERT INTO product.product_list_items").
+		WithArgs(listID, productID, 2, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	dbMock.ExpectExec("UPDATE product.product_lists SET updated_at").
+		WithArgs(listID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	repo := NewProductListRepository(sqlx.NewDb(db, "postgres"), config.DBConfig{Schema: "product"})
+
+	err = repo.SaveProductListItem(listID, productID, 2, nil)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Error("test failed:", err)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"mpmy-product-service/config"
 	"mpmy-product-service/graph/model"
@@ -20,10 +22,25 @@ const (
 	// TrendingProductTableName is the name of trending product table
 	TrendingProductTableName = "trending_products"
 
-	// DefaultCatalogID is the catalog id in order cloud for MY
-	DefaultCatalogID = "zp-my"
+	// trendingScore sums every order line's quantity raised to the exponent $4, halved for every $3 hours
+	// between its created_at and $2
+	trendingScore = "SUM(POWER(GREATEST(quantity, 1), $4) * POWER(0.5, EXTRACT(EPOCH FROM ($2 - created_at)) / 3600 / $3))::float"
 )
 
+// TrendingProductParams configures which orders count towards trending products and how they are scored
+type TrendingProductParams struct {
+	Since            time.Time
+	HalfLifeHours    float64
+	QuantityExponent float64
+	Limit            int
+}
+
+// TrendingProductGroupItem is a trending product within a category or supplier
+type TrendingProductGroupItem struct {
+	GroupID string `db:"group_id"`
+	model.TrendingProduct
+}
+
 type ProductParams struct {
 	CatalogID    string                 `json:"catalogID"`
 	CategoryID   string                 `json:"categoryID"`
@@ -36,16 +53,35 @@ type ProductParams struct {
 	PageSize     string                 `json:"pageSize"`
 }
 
+// FavoriteProductCursor is the position of a favorite within the user's favorites ordered by created_at
+type FavoriteProductCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// FavoriteProductBatchItem is a product favorited by a user at CreatedAt
+type FavoriteProductBatchItem struct {
+	ProductID string
+	CreatedAt time.Time
+}
+
 type IProductRepository interface {
 	GetProducts(params ProductParams, accessToken string) (model.ProductResponse, error)
 	GetProduct(productID string, accessToken string) (model.ProductItem, error)
 	SaveFavoriteProduct(userID *string, productID string) (*model.UserProductFavorite, error)
+	SaveFavoriteProducts(userID *string, items []FavoriteProductBatchItem) ([]model.UserProductFavorite, error)
 	DeleteFavoriteProduct(userID *string, productID string) error
+	DeleteFavoriteProducts(userID *string, productIDs []string) error
 	GetFavoriteProducts(userID, page, pageSize *string) ([]model.UserProductFavorite, error)
+	GetFavoriteProductsAfter(userID *string, after *FavoriteProductCursor, limit int, oldestFirst bool) ([]model.UserProductFavorite, error)
+	CountFavoriteProducts(userID *string) (int, error)
 	GetProductsOrderCloudV2(params ProductParams, accessToken string) (model.ProductResponseV2, error)
 	GetProductsV2(params ProductParams, accessToken string) (model.ProductResponseV2, error)
 	GetProductV2(productID string, accessToken string) (model.LatestProductItems, error)
-	GetTrendingProducts(limit int) ([]model.TrendingProduct, error)
+	GetTrendingProducts(params TrendingProductParams) ([]model.TrendingProduct, error)
+	GetTrendingProductsByCategory(params TrendingProductParams) ([]TrendingProductGroupItem, error)
+	GetTrendingProductsBySupplier(params TrendingProductParams) ([]TrendingProductGroupItem, error)
+	GetFrequentlyPurchasedProducts(userID *string, since time.Time, halfLifeDays float64, limit int) ([]*model.FrequentlyPurchasedProduct, error)
 	FetchProductFilters(search string, accessToken string) ([]*model.ProductFilter, error)
 }
 
@@ -66,10 +102,6 @@ func NewProductRepository(db *sqlx.DB, dbConfig config.DBConfig, orderConfig con
 }
 
 func (repo *ProductRepository) GetProducts(params ProductParams, accessToken string) (model.ProductResponse, error) {
-	if params.CatalogID == "" {
-		params.CatalogID = DefaultCatalogID
-	}
-
 	// prepare request specifications
 	requestURL := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/products")
 	requestSpecifications := &httprequest.RequestSpecifications{
@@ -150,36 +182,60 @@ func (repo *ProductRepository) GetProduct(productID string, accessToken string)
 	return product, nil
 }
 
+// SaveFavoriteProduct favorites a product for the user, favoriting an already favorite product
+// keeps and returns the existing favorite
 func (repo *ProductRepository) SaveFavoriteProduct(userID *string, productID string) (*model.UserProductFavorite, error) {
-	currentTime := time.Now().UTC()
-	userProductFavourite := &model.UserProductFavorite{
-		UserID:    userID,
-		ProductID: &productID,
-		CreatedAt: &currentTime,
+	userProductFavorites, err := repo.SaveFavoriteProducts(userID, []FavoriteProductBatchItem{
+		{ProductID: productID, CreatedAt: time.Now().UTC()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(userProductFavorites) == 0 {
+		return nil, fmt.Errorf("failed to save favorite product")
+	}
+
+	return &userProductFavorites[0], nil
+}
+
+// SaveFavoriteProducts favorites products for the user with a single multi-row insert and returns the
+// favorites of those products, including ones that already existed. Items must be unique per product
+func (repo *ProductRepository) SaveFavoriteProducts(userID *string, items []FavoriteProductBatchItem) ([]model.UserProductFavorite, error) {
+	userProductFavorites := []model.UserProductFavorite{}
+	if len(items) == 0 {
+		return userProductFavorites, nil
 	}
 
-	query := "INSERT INTO " + repo.dbConfig.Schema + "." + FavoriteProductTableName + "(" +
-		"user_id, product_id, created_at)" +
-		"VALUES(:user_id, :product_id, :created_at) " +
-		"RETURNING id"
+	values := make([]string, len(items))
+	args := make([]interface{}, 0, len(items)*2+1)
+	args = append(args, userID)
+	productIDs := make([]string, len(items))
+	for i, item := range items {
+		n := i*2 + 1
+		values[i] = fmt.Sprintf("($1, $%d, $%d)", n+1, n+2)
+		args = append(args, item.ProductID, item.CreatedAt.UTC())
+		productIDs[i] = item.ProductID
+	}
 
-	rows, err := repo.db.NamedQuery(query, userProductFavourite)
-	defer rows.Close()
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + FavoriteProductTableName + " (" +
+		"user_id, product_id, created_at) " +
+		"VALUES " + strings.Join(values, ", ") + " " +
+		"ON CONFLICT (user_id, product_id) DO NOTHING"
+
+	_, err := repo.db.Exec(query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	var lastInsertID int
-	if rows.Next() {
-		err = rows.Scan(&lastInsertID)
-		if err != nil {
-			return nil, err
-		}
-	}
+	query = "SELECT id, user_id, product_id, created_at FROM " + repo.dbConfig.Schema + "." + FavoriteProductTableName +
+		" WHERE user_id = $1 AND product_id = ANY($2) ORDER BY created_at DESC, id DESC"
 
-	userProductFavourite.ID = &lastInsertID
+	err = repo.db.Select(&userProductFavorites, query, userID, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
 
-	return userProductFavourite, nil
+	return userProductFavorites, nil
 }
 
 func (repo *ProductRepository) DeleteFavoriteProduct(userID *string, productID string) error {
@@ -194,6 +250,23 @@ func (repo *ProductRepository) DeleteFavoriteProduct(userID *string, productID s
 	return nil
 }
 
+// DeleteFavoriteProducts removes products from the user's favorites
+func (repo *ProductRepository) DeleteFavoriteProducts(userID *string, productIDs []string) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + FavoriteProductTableName +
+		" WHERE user_id = $1 AND product_id = ANY($2)"
+
+	_, err := repo.db.Exec(query, userID, pq.Array(productIDs))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (repo *ProductRepository) GetFavoriteProducts(userID, page, pageSize *string) ([]model.UserProductFavorite, error) {
 	var userProductFavorites []model.UserProductFavorite
 	var err error
@@ -219,11 +292,51 @@ func (repo *ProductRepository) GetFavoriteProducts(userID, page, pageSize *strin
 	return userProductFavorites, nil
 }
 
-func (repo *ProductRepository) GetProductsOrderCloudV2(params ProductParams, accessToken string) (model.ProductResponseV2, error) {
-	if params.CatalogID == "" {
-		params.CatalogID = DefaultCatalogID
+// GetFavoriteProductsAfter returns up to limit favorites of the user following the after cursor,
+// newest first unless oldestFirst is set. Favorites are ordered by created_at then id so pages never overlap
+func (repo *ProductRepository) GetFavoriteProductsAfter(userID *string, after *FavoriteProductCursor, limit int, oldestFirst bool) ([]model.UserProductFavorite, error) {
+	userProductFavorites := []model.UserProductFavorite{}
+
+	order, comparison := "DESC", "<"
+	if oldestFirst {
+		order, comparison = "ASC", ">"
+	}
+
+	query := "SELECT id, user_id, product_id, created_at FROM " + repo.dbConfig.Schema + "." + FavoriteProductTableName +
+		" WHERE user_id = $1"
+	args := []interface{}{userID}
+
+	if after != nil {
+		query += " AND (created_at, id) " + comparison + " ($2, $3)"
+		args = append(args, after.CreatedAt.UTC(), after.ID)
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT $%d", order, order, len(args)+1)
+	args = append(args, limit)
+
+	err := repo.db.Select(&userProductFavorites, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return userProductFavorites, nil
+}
+
+func (repo *ProductRepository) CountFavoriteProducts(userID *string) (int, error) {
+	var count int
+
+	query := "SELECT COUNT(*) FROM " + repo.dbConfig.Schema + "." + FavoriteProductTableName +
+		" WHERE user_id = $1"
+
+	err := repo.db.Get(&count, query, userID)
+	if err != nil {
+		return 0, err
 	}
 
+	return count, nil
+}
+
+func (repo *ProductRepository) GetProductsOrderCloudV2(params ProductParams, accessToken string) (model.ProductResponseV2, error) {
 	requestURL := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/products")
 	requestSpecifications := &httprequest.RequestSpecifications{
 		HTTPMethod: http.MethodGet,
@@ -281,10 +394,6 @@ func (repo *ProductRepository) GetProductsOrderCloudV2(params ProductParams, acc
 }
 
 func (repo *ProductRepository) GetProductsV2(params ProductParams, accessToken string) (model.ProductResponseV2, error) {
-	if params.CatalogID == "" {
-		params.CatalogID = DefaultCatalogID
-	}
-
 	requestURL := fmt.Sprintf("%s/%s", repo.orderCloud.SellerCenterMiddleware, "products")
 	requestSpecifications := &httprequest.RequestSpecifications{
 		HTTPMethod: http.MethodGet,
@@ -363,17 +472,44 @@ func (repo *ProductRepository) GetProductV2(productID string, accessToken string
 	return product, nil
 }
 
-func (repo *ProductRepository) GetTrendingProducts(limit int) ([]model.TrendingProduct, error) {
-	var trendingProducts []model.TrendingProduct
-	var err error
+// GetTrendingProducts returns the highest scoring products ordered since params.Since
+func (repo *ProductRepository) GetTrendingProducts(params TrendingProductParams) ([]model.TrendingProduct, error) {
+	trendingProducts := []model.TrendingProduct{}
+
+	query := "SELECT product_id, COUNT(product_id) AS order_count, SUM(quantity) AS quantity, " + trendingScore + " AS score " +
+		"FROM " + repo.dbConfig.Schema + "." + TrendingProductTableName +
+		" WHERE created_at >= $1 GROUP BY product_id ORDER BY score DESC, product_id LIMIT $5"
+
+	err := repo.db.Select(&trendingProducts, query, params.Since, time.Now().UTC(), params.HalfLifeHours, params.QuantityExponent, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return trendingProducts, nil
+}
 
-	createdAtStart := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
-	createdAtEnd := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+// GetTrendingProductsByCategory returns the highest scoring products of every category, up to params.Limit each
+func (repo *ProductRepository) GetTrendingProductsByCategory(params TrendingProductParams) ([]TrendingProductGroupItem, error) {
+	return repo.getGroupedTrendingProducts("category_id", params)
+}
+
+// GetTrendingProductsBySupplier returns the highest scoring products of every supplier, up to params.Limit each
+func (repo *ProductRepository) GetTrendingProductsBySupplier(params TrendingProductParams) ([]TrendingProductGroupItem, error) {
+	return repo.getGroupedTrendingProducts("supplier_id", params)
+}
 
-	query := "SELECT product_id, COUNT(product_id) AS order_count, sum(quantity) as quantity FROM " + repo.dbConfig.Schema + "." + TrendingProductTableName +
-		" WHERE created_at between $1 AND $2 GROUP BY product_id ORDER BY order_count DESC LIMIT $3"
+// getGroupedTrendingProducts ranks products within every value of groupColumn, which must be a trusted column name
+func (repo *ProductRepository) getGroupedTrendingProducts(groupColumn string, params TrendingProductParams) ([]TrendingProductGroupItem, error) {
+	trendingProducts := []TrendingProductGroupItem{}
 
-	err = repo.db.Select(&trendingProducts, query, createdAtStart, createdAtEnd, limit)
+	query := "SELECT group_id, product_id, order_count, quantity, score FROM (" +
+		"SELECT " + groupColumn + " AS group_id, product_id, COUNT(product_id) AS order_count, SUM(quantity) AS quantity, " + trendingScore + " AS score, " +
+		"ROW_NUMBER() OVER (PARTITION BY " + groupColumn + " ORDER BY " + trendingScore + " DESC, product_id) AS rank " +
+		"FROM " + repo.dbConfig.Schema + "." + TrendingProductTableName +
+		" WHERE created_at >= $1 AND " + groupColumn + " IS NOT NULL GROUP BY " + groupColumn + ", product_id" +
+		") ranked WHERE rank <= $5 ORDER BY group_id, rank"
+
+	err := repo.db.Select(&trendingProducts, query, params.Since, time.Now().UTC(), params.HalfLifeHours, params.QuantityExponent, params.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -381,7 +517,31 @@ func (repo *ProductRepository) GetTrendingProducts(limit int) ([]model.TrendingP
 	return trendingProducts, nil
 }
 
+// GetFrequentlyPurchasedProducts ranks the products the user ordered since the given time. Every order line
+// adds to a product's score, weighted down by half for every halfLifeDays since it was ordered, so products
+// ordered often and recently come first
+func (repo *ProductRepository) GetFrequentlyPurchasedProducts(userID *string, since time.Time, halfLifeDays float64, limit int) ([]*model.FrequentlyPurchasedProduct, error) {
+	products := []*model.FrequentlyPurchasedProduct{}
+
+	query := "SELECT product_id, COUNT(*) AS order_count, " +
+		"(ARRAY_AGG(quantity ORDER BY created_at DESC))[1] AS last_ordered_quantity, " +
+		"MAX(created_at) AS last_ordered_at, " +
+		"SUM(POWER(0.5, EXTRACT(EPOCH FROM ($2 - created_at)) / 86400 / $3))::float AS score " +
+		"FROM " + repo.dbConfig.Schema + "." + TrendingProductTableName +
+		" WHERE user_id = $1 AND created_at >= $4 GROUP BY product_id " +
+		"ORDER BY score DESC, last_ordered_at DESC, product_id LIMIT $5"
+
+	err := repo.db.Select(&products, query, userID, time.Now().UTC(), halfLifeDays, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
 // /products/Product_filter?Search=CountryOfOrigin
+//
+// Deprecated: facets are read from order cloud with product listings, see ProductFacetRepository
 func (repo *ProductRepository) FetchProductFilters(search string, accessToken string) ([]*model.ProductFilter, error) {
 	url := fmt.Sprintf("%s/%s", repo.orderCloud.SellerCenterMiddleware, "products/Product_filter")
 	requestSpecifications := &httprequest.RequestSpecifications{
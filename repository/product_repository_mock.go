@@ -23,88 +23,15 @@ type ProductRepositoryMock struct {
 }
 
 func (repo *ProductRepositoryMock) GetProducts(params ProductParams, accessToken string) (model.ProductResponse, error) {
-	if params.CatalogID == "" {
-		params.CatalogID = DefaultCatalogID
-	}
-
-	// prepare request specifications
-	requestURL := fmt.Sprintf("%s/%s", repo.orderCloud.OrderCloudEngine, "v1/products")
-	requestSpecifications := &httprequest.RequestSpecifications{
-		HTTPMethod: http.MethodGet,
-		URL:        requestURL,
-		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
-		Params: map[string]interface{}{
-			"catalogID":  params.CatalogID,
-			"categoryID": params.CategoryID,
-			"supplierID": params.SupplierID,
-			"search":     params.Search,
-			"searchOn":   params.SearchOn,
-		},
-	}
-
-	if params.Page != "" {
-		requestSpecifications.Params["page"] = params.Page
-	}
-
-	if params.PageSize != "" {
-		requestSpecifications.Params["pageSize"] = params.PageSize
-	}
-
-	if params.SortBy != "" {
-		requestSpecifications.Params["sortBy"] = params.SortBy
-	}
-
-	// add extra filters from params
-	if params.ExtraFilters == nil {
-		params.ExtraFilters = map[string]interface{}{
-			"Active": true,
-		}
-	} else {
-		params.ExtraFilters["Active"] = true
-	}
-	if len(params.ExtraFilters) > 0 {
-		for key, value := range params.ExtraFilters {
-			requestSpecifications.Params[key] = value
-		}
-	}
-
-	// make request
-	statusCode, response, _ := repo.httpRequestHandler.MakeRequest(requestSpecifications)
-	if statusCode != http.StatusOK {
-		return model.ProductResponse{}, fmt.Errorf("failed to fetch products")
-	}
-
-	var productResp model.ProductResponse
-
-	err := json.Unmarshal(response, &productResp)
-	if err != nil {
-		return model.ProductResponse{}, err
-	}
+	args := repo.Called(params, accessToken)
 
-	return productResp, nil
+	return args.Get(0).(model.ProductResponse), args.Error(1)
 }
 
 func (repo *ProductRepositoryMock) GetProduct(productID string, accessToken string) (model.ProductItem, error) {
-	url := fmt.Sprintf("%s/%s/%s", repo.orderCloud.OrderCloudEngine, "v1/products", productID)
-	requestSpecifications := &httprequest.RequestSpecifications{
-		HTTPMethod: http.MethodGet,
-		URL:        url,
-		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
-	}
-
-	// make request
-	statusCode, response, _ := repo.httpRequestHandler.MakeRequest(requestSpecifications)
-	if statusCode != http.StatusOK {
-		return model.ProductItem{}, fmt.Errorf("failed to fetch product")
-	}
-	var product model.ProductItem
-
-	err := json.Unmarshal(response, &product)
-	if err != nil {
-		return model.ProductItem{}, err
-	}
+	args := repo.Called(productID, accessToken)
 
-	return product, nil
+	return args.Get(0).(model.ProductItem), args.Error(1)
 }
 
 func (repo *ProductRepositoryMock) SaveFavoriteProduct(userID *string, productID string) (*model.UserProductFavorite, error) {
@@ -176,6 +103,30 @@ func (repo *ProductRepositoryMock) GetFavoriteProducts(userID, page, pageSize *s
 	return userProductFavorites, nil
 }
 
+func (repo *ProductRepositoryMock) SaveFavoriteProducts(userID *string, items []FavoriteProductBatchItem) ([]model.UserProductFavorite, error) {
+	args := repo.Called(userID, items)
+
+	return args.Get(0).([]model.UserProductFavorite), args.Error(1)
+}
+
+func (repo *ProductRepositoryMock) DeleteFavoriteProducts(userID *string, productIDs []string) error {
+	args := repo.Called(userID, productIDs)
+
+	return args.Error(0)
+}
+
+func (repo *ProductRepositoryMock) GetFavoriteProductsAfter(userID *string, after *FavoriteProductCursor, limit int, oldestFirst bool) ([]model.UserProductFavorite, error) {
+	args := repo.Called(userID, after, limit, oldestFirst)
+
+	return args.Get(0).([]model.UserProductFavorite), args.Error(1)
+}
+
+func (repo *ProductRepositoryMock) CountFavoriteProducts(userID *string) (int, error) {
+	args := repo.Called(userID)
+
+	return args.Int(0), args.Error(1)
+}
+
 func (repo *ProductRepositoryMock) GetProductsOrderCloudV2(params ProductParams, accessToken string) (model.ProductResponseV2, error) {
 	args := repo.Called(params, accessToken)
 
@@ -183,10 +134,6 @@ func (repo *ProductRepositoryMock) GetProductsOrderCloudV2(params ProductParams,
 }
 
 func (repo *ProductRepositoryMock) GetProductsV2(params ProductParams, accessToken string) (model.ProductResponseV2, error) {
-	if params.CatalogID == "" {
-		params.CatalogID = DefaultCatalogID
-	}
-
 	requestURL := fmt.Sprintf("%s/%s", repo.orderCloud.SellerCenterMiddleware, "products")
 	requestSpecifications := &httprequest.RequestSpecifications{
 		HTTPMethod: http.MethodGet,
@@ -244,43 +191,33 @@ func (repo *ProductRepositoryMock) GetProductsV2(params ProductParams, accessTok
 }
 
 func (repo *ProductRepositoryMock) GetProductV2(productID string, accessToken string) (model.LatestProductItems, error) {
-	url := fmt.Sprintf("%s/%s/%s", repo.orderCloud.SellerCenterMiddleware, "products", productID)
-	requestSpecifications := &httprequest.RequestSpecifications{
-		HTTPMethod: http.MethodGet,
-		URL:        url,
-		Headers:    map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
-	}
+	args := repo.Called(productID, accessToken)
 
-	// make request
-	statusCode, response, _ := repo.httpRequestHandler.MakeRequest(requestSpecifications)
-	if statusCode != http.StatusOK {
-		return model.LatestProductItems{}, fmt.Errorf("failed to fetch product")
-	}
-	var product model.LatestProductItems
+	return args.Get(0).(model.LatestProductItems), args.Error(1)
+}
 
-	err := json.Unmarshal(response, &product)
-	if err != nil {
-		return model.LatestProductItems{}, err
-	}
-	return product, nil
+func (repo *ProductRepositoryMock) GetTrendingProducts(params TrendingProductParams) ([]model.TrendingProduct, error) {
+	args := repo.Called(params)
+
+	return args.Get(0).([]model.TrendingProduct), args.Error(1)
 }
 
-func (repo *ProductRepositoryMock) GetTrendingProducts(limit int) ([]model.TrendingProduct, error) {
-	var trendingProducts []model.TrendingProduct
-	var err error
+func (repo *ProductRepositoryMock) GetTrendingProductsByCategory(params TrendingProductParams) ([]TrendingProductGroupItem, error) {
+	args := repo.Called(params)
 
-	createdAtStart := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
-	createdAtEnd := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	return args.Get(0).([]TrendingProductGroupItem), args.Error(1)
+}
 
-	query := "SELECT product_id, COUNT(product_id) AS order_count, sum(quantity) as quantity FROM " + repo.dbConfig.Schema + "." + TrendingProductTableName +
-		" WHERE created_at between $1 AND $2 GROUP BY product_id ORDER BY order_count DESC LIMIT $3"
+func (repo *ProductRepositoryMock) GetTrendingProductsBySupplier(params TrendingProductParams) ([]TrendingProductGroupItem, error) {
+	args := repo.Called(params)
 
-	err = repo.db.Select(&trendingProducts, query, createdAtStart, createdAtEnd, limit)
-	if err != nil {
-		return nil, err
-	}
+	return args.Get(0).([]TrendingProductGroupItem), args.Error(1)
+}
+
+func (repo *ProductRepositoryMock) GetFrequentlyPurchasedProducts(userID *string, since time.Time, halfLifeDays float64, limit int) ([]*model.FrequentlyPurchasedProduct, error) {
+	args := repo.Called(userID, since, halfLifeDays, limit)
 
-	return trendingProducts, nil
+	return args.Get(0).([]*model.FrequentlyPurchasedProduct), args.Error(1)
 }
 
 // /products/Product_filter?Search=CountryOfOrigin
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"mpmy-product-service/config"
+)
+
+const (
+	// PromotionRedemptionsTableName is the name of promotion redemptions table
+	PromotionRedemptionsTableName = "promotion_redemptions"
+
+	// promotionRedemptionInsertBatchSize is the most redemptions written by a single insert
+	promotionRedemptionInsertBatchSize = 500
+)
+
+// PromotionRedemptionItem is a promotion redeemed on a recorded order
+type PromotionRedemptionItem struct {
+	OrderID     string
+	PromotionID string
+	UserID      string
+	CreatedAt   time.Time
+}
+
+type IPromotionRedemptionRepository interface {
+	SavePromotionRedemptions(items []PromotionRedemptionItem) (int, error)
+	CountUserPromotionRedemptions(userID string, promotionIDs []string) (map[string]int, error)
+}
+
+type PromotionRedemptionRepository struct {
+	db       *sqlx.DB
+	dbConfig config.DBConfig
+}
+
+func NewPromotionRedemptionRepository(db *sqlx.DB, dbConfig config.DBConfig) *PromotionRedemptionRepository {
+	return &PromotionRedemptionRepository{
+		db:       db,
+		dbConfig: dbConfig,
+	}
+}
+
+// SavePromotionRedemptions writes redemptions in a single transaction, promotions already recorded for the same
+// order are skipped. Returns the number of redemptions written
+func (repo *PromotionRedemptionRepository) SavePromotionRedemptions(items []PromotionRedemptionItem) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	const columnCount = 4
+	var recorded int64
+	for start := 0; start < len(items); start += promotionRedemptionInsertBatchSize {
+		end := start + promotionRedemptionInsertBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*columnCount)
+		for i, item := range items[start:end] {
+			n := i * columnCount
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+			args = append(args, item.OrderID, item.PromotionID, item.UserID, item.CreatedAt)
+		}
+
+		query := "INSERT INTO " + repo.dbConfig.Schema + "." + PromotionRedemptionsTableName + " (" +
+			"order_id, promotion_id, user_id, created_at) " +
+			"VALUES " + strings.Join(values, ", ") + " ON CONFLICT (order_id, promotion_id) DO NOTHING"
+
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		recorded += affected
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(recorded), nil
+}
+
+// CountUserPromotionRedemptions returns how many recorded orders of the user redeemed each of the promotions,
+// promotions never redeemed by the user are left out
+func (repo *PromotionRedemptionRepository) CountUserPromotionRedemptions(userID string, promotionIDs []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(promotionIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		PromotionID string `db:"promotion_id"`
+		Count       int    `db:"count"`
+	}
+
+	query := "SELECT promotion_id, COUNT(*) AS count FROM " + repo.dbConfig.Schema + "." + PromotionRedemptionsTableName +
+		" WHERE user_id = $1 AND promotion_id = ANY($2) GROUP BY promotion_id"
+
+	err := repo.db.Select(&rows, query, userID, pq.Array(promotionIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.PromotionID] = row.Count
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type PromotionRedemptionRepositoryMock struct {
+	mock.Mock
+}
+
+func (repo *PromotionRedemptionRepositoryMock) SavePromotionRedemptions(items []PromotionRedemptionItem) (int, error) {
+	args := repo.Called(items)
+
+	return args.Int(0), args.Error(1)
+}
+
+func (repo *PromotionRedemptionRepositoryMock) CountUserPromotionRedemptions(userID string, promotionIDs []string) (map[string]int, error) {
+	args := repo.Called(userID, promotionIDs)
+
+	return args.Get(0).(map[string]int), args.Error(1)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+)
+
+func TestCountUserPromotionRedemptions(t *testing.T) {
+
+	//data test
+	var userID = "42"
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("test failed: sqlmock not created:", err)
+	}
+	defer db.Close()
+
+	dbMock.ExpectQuery("SELECT promotion_id, COUNT\\(\\*\\) AS count FROM product.promotion_redemptions WHERE user_id = \\$1 AND promotion_id = ANY\\(\\$2\\) GROUP BY promotion_id").
+		WithArgs(userID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"promotion_id", "count"}).AddRow("promo-1", 2))
+
+	repo := NewPromotionRedemptionRepository(sqlx.NewDb(db, "postgres"), config.DBConfig{Schema: "product"})
+
+	counts, err := repo.CountUserPromotionRedemptions(userID, []string{"promo-1", "promo-2"})
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	if len(counts) != 1 || counts["promo-1"] != 2 {
+		t.Error("test failed, only redeemed promotions should be counted, got", counts)
+	}
+
+	// nothing is queried without promotions
+	counts, err = repo.CountUserPromotionRedemptions(userID, nil)
+	if err != nil || len(counts) != 0 {
+		t.Error("test failed, no promotions should have no counts")
+	}
+
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Error("test failed:", err)
+	}
+}
@@ -1,66 +1,84 @@
 package repository
 
 import (
+	"fmt"
 	"github.com/jmoiron/sqlx"
 	"mpmy-product-service/config"
 	"mpmy-product-service/graph/model"
+	"strings"
 	"time"
 )
 
 const (
 	// RecentSearchesTableName is the name of recent searches table
 	RecentSearchesTableName = "recent_searches"
+
+	// recentSearchColumns are the columns mapped to model.RecentSearch
+	recentSearchColumns = "id, user_id, search_keyword, search_count, created_at, last_searched_at"
 )
 
 type RecentSearchesRepository struct {
-	db                 *sqlx.DB
-	dbConfig           config.DBConfig
+	db       *sqlx.DB
+	dbConfig config.DBConfig
 }
 
 func NewRecentSearchesRepository(db *sqlx.DB, dbConfig config.DBConfig) *RecentSearchesRepository {
 	return &RecentSearchesRepository{
-		db:                 db,
-		dbConfig:           dbConfig,
+		db:       db,
+		dbConfig: dbConfig,
 	}
 }
 
-func (repo *RecentSearchesRepository) SaveRecentSearch(userId, keyword *string) (*model.RecentSearch, error) {
-	currentTime := time.Now().UTC()
-	recentSearch := &model.RecentSearch{
-		SearchKeyword: keyword,
-		UserID: userId,
-		CreatedAt: &currentTime,
-	}
+// NormalizeSearchKeyword lower-cases keyword and collapses its whitespace, searches are de-duplicated on it
+func NormalizeSearchKeyword(keyword string) string {
+	return strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+}
 
-	query := "INSERT INTO " + repo.dbConfig.Schema + "." + RecentSearchesTableName + "(" +
-		"search_keyword, user_id, created_at)" +
-		"VALUES(:search_keyword, :user_id, :created_at) " +
-		"RETURNING id"
+// RecentSearchBatchItem is a search aggregated per user and normalized keyword before it is written
+type RecentSearchBatchItem struct {
+	UserID            string
+	SearchKeyword     string
+	NormalizedKeyword string
+	SearchCount       int
+	SearchedAt        time.Time
+}
 
-	rows, err := repo.db.NamedQuery(query, recentSearch)
-	defer rows.Close()
-	if err != nil {
-		return nil, err
+// SaveRecentSearches records searches with a single multi-row insert, searches already recorded
+// for the user update the existing row. Items must be unique per user and normalized keyword
+func (repo *RecentSearchesRepository) SaveRecentSearches(items []RecentSearchBatchItem) error {
+	if len(items) == 0 {
+		return nil
 	}
 
-	var lastInsertID int
-	if rows.Next() {
-		err = rows.Scan(&lastInsertID)
-		if err != nil {
-			return nil, err
-		}
+	values := make([]string, len(items))
+	args := make([]interface{}, 0, len(items)*5)
+	for i, item := range items {
+		n := i * 5
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+5)
+		args = append(args, item.SearchKeyword, item.NormalizedKeyword, item.UserID, item.SearchCount, item.SearchedAt)
 	}
 
-	recentSearch.ID = &lastInsertID
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + RecentSearchesTableName + " AS rs (" +
+		"search_keyword, normalized_keyword, user_id, search_count, created_at, last_searched_at) " +
+		"VALUES " + strings.Join(values, ", ") + " " +
+		"ON CONFLICT (user_id, normalized_keyword) DO UPDATE SET " +
+		"search_keyword = EXCLUDED.search_keyword, " +
+		"last_searched_at = GREATEST(rs.last_searched_at, EXCLUDED.last_searched_at), " +
+		"search_count = rs.search_count + EXCLUDED.search_count"
+
+	_, err := repo.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
 
-	return recentSearch, nil
+	return nil
 }
 
 func (repo *RecentSearchesRepository) GetRecentSearches(userID, page, pageSize *string) ([]*model.RecentSearch, error) {
 	var recentSearches []*model.RecentSearch
 	var err error
 
-	query := "SELECT * FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName + " WHERE user_id = $1 ORDER BY created_at DESC"
+	query := "SELECT " + recentSearchColumns + " FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName + " WHERE user_id = $1 ORDER BY last_searched_at DESC"
 
 	if page != nil && pageSize != nil {
 		query += " LIMIT $2 OFFSET $3"
@@ -78,4 +96,92 @@ func (repo *RecentSearchesRepository) GetRecentSearches(userID, page, pageSize *
 	}
 
 	return recentSearches, nil
-}
\ No newline at end of file
+}
+
+// DeleteRecentSearch deletes a recent search of the user, returns false when no such search exists
+func (repo *RecentSearchesRepository) DeleteRecentSearch(userID *string, id int) (bool, error) {
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE id = $1 AND user_id = $2"
+
+	result, err := repo.db.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ClearRecentSearches deletes all recent searches of the user
+func (repo *RecentSearchesRepository) ClearRecentSearches(userID *string) error {
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE user_id = $1"
+
+	_, err := repo.db.Exec(query, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TrimRecentSearches keeps only the user's most recently used searches up to limit
+func (repo *RecentSearchesRepository) TrimRecentSearches(userID *string, limit int) error {
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE user_id = $1 AND id NOT IN (SELECT id FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE user_id = $1 ORDER BY last_searched_at DESC LIMIT $2)"
+
+	_, err := repo.db.Exec(query, userID, limit)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRecentSearchesBefore deletes searches of all users last used before the given time
+func (repo *RecentSearchesRepository) DeleteRecentSearchesBefore(before time.Time) (int64, error) {
+	query := "DELETE FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE last_searched_at < $1"
+
+	result, err := repo.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetRecentSearchKeywords returns the user's search keywords starting with prefix, most recent first
+func (repo *RecentSearchesRepository) GetRecentSearchKeywords(userID *string, prefix string, limit int) ([]string, error) {
+	var keywords []string
+
+	query := "SELECT search_keyword FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE user_id = $1 AND normalized_keyword LIKE $2 ORDER BY last_searched_at DESC LIMIT $3"
+
+	err := repo.db.Select(&keywords, query, userID, EscapeLikePattern(NormalizeSearchKeyword(prefix))+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return keywords, nil
+}
+
+// GetPopularSearchKeywords returns search keywords of all users starting with prefix, most searched first
+func (repo *RecentSearchesRepository) GetPopularSearchKeywords(prefix string, limit int) ([]string, error) {
+	var keywords []string
+
+	query := "SELECT normalized_keyword FROM " + repo.dbConfig.Schema + "." + RecentSearchesTableName +
+		" WHERE normalized_keyword LIKE $1 GROUP BY normalized_keyword ORDER BY SUM(search_count) DESC, normalized_keyword LIMIT $2"
+
+	err := repo.db.Select(&keywords, query, EscapeLikePattern(NormalizeSearchKeyword(prefix))+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return keywords, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+)
+
+const (
+	// ProductRecommendationsTableName is the name of product recommendations table
+	ProductRecommendationsTableName = "product_recommendations"
+
+	// RelationBoughtTogether relates products ordered in the same order
+	RelationBoughtTogether = "bought_together"
+
+	// RelationAlsoBought relates products ordered by the same user
+	RelationAlsoBought = "also_bought"
+)
+
+// ProductRecommendationItem is a product recommended for another product
+type ProductRecommendationItem struct {
+	RelatedProductID string  `db:"related_product_id"`
+	Relation         string  `db:"relation"`
+	Support          int     `db:"support"`
+	Score            float64 `db:"score"`
+}
+
+// RecommendationBuildParams configures which orders count towards recommendations
+type RecommendationBuildParams struct {
+	Since         time.Time
+	MinSupport    int
+	MaxPerProduct int
+}
+
+type IRecommendationRepository interface {
+	GetProductRecommendations(productID string, limit int) ([]ProductRecommendationItem, error)
+}
+
+type RecommendationRepository struct {
+	db       *sqlx.DB
+	dbConfig config.DBConfig
+}
+
+func NewRecommendationRepository(db *sqlx.DB, dbConfig config.DBConfig) *RecommendationRepository {
+	return &RecommendationRepository{
+		db:       db,
+		dbConfig: dbConfig,
+	}
+}
+
+// GetProductRecommendations returns products related to the product, bought together first, then by score
+func (repo *RecommendationRepository) GetProductRecommendations(productID string, limit int) ([]ProductRecommendationItem, error) {
+	recommendations := []ProductRecommendationItem{}
+
+	query := "SELECT related_product_id, relation, support, score FROM " + repo.dbConfig.Schema + "." + ProductRecommendationsTableName +
+		" WHERE product_id = $1 ORDER BY CASE relation WHEN '" + RelationBoughtTogether + "' THEN 0 ELSE 1 END, score DESC, related_product_id LIMIT $2"
+
+	err := repo.db.Select(&recommendations, query, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
+
+// RebuildProductRecommendations replaces all recommendations with ones computed from trending products, in a
+// single transaction so readers never see a partial rebuild. Returns the number of recommendations written
+func (repo *RecommendationRepository) RebuildProductRecommendations(params RecommendationBuildParams) (int, error) {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM " + repo.dbConfig.Schema + "." + ProductRecommendationsTableName)
+	if err != nil {
+		return 0, err
+	}
+
+	currentTime := time.Now().UTC()
+	var written int64
+	relations := []struct{ relation, basketColumn string }{
+		{RelationBoughtTogether, "order_id"},
+		{RelationAlsoBought, "user_id"},
+	}
+	for _, r := range relations {
+		result, err := tx.Exec(repo.coOccurrenceQuery(r.relation, r.basketColumn), params.Since, params.MinSupport, params.MaxPerProduct, currentTime)
+		if err != nil {
+			return 0, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		written += affected
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(written), nil
+}
+
+// coOccurrenceQuery counts how often two products share a basket, an order or a user depending on basketColumn
+// which must be a trusted column name. Pairs are scored by cosine similarity so popular products do not
+// dominate every list, and only the best MaxPerProduct related products of every product are kept
+func (repo *RecommendationRepository) coOccurrenceQuery(relation, basketColumn string) string {
+	return "WITH baskets AS (" +
+		"SELECT DISTINCT " + basketColumn + " AS basket_id, product_id FROM " + repo.dbConfig.Schema + "." + TrendingProductTableName +
+		" WHERE created_at >= $1 AND " + basketColumn + " IS NOT NULL" +
+		"), product_counts AS (" +
+		"SELECT product_id, COUNT(*) AS basket_count FROM baskets GROUP BY product_id" +
+		"), pairs AS (" +
+		"SELECT a.product_id, b.product_id AS related_product_id, COUNT(*) AS support FROM baskets a " +
+		"JOIN baskets b ON a.basket_id = b.basket_id AND a.product_id <> b.product_id " +
+		"GROUP BY a.product_id, b.product_id HAVING COUNT(*) >= $2" +
+		"), ranked AS (" +
+		"SELECT p.product_id, p.related_product_id, p.support, " +
+		"p.support / SQRT(ca.basket_count::float * cb.basket_count) AS score, " +
+		"ROW_NUMBER() OVER (PARTITION BY p.product_id ORDER BY p.support / SQRT(ca.basket_count::float * cb.basket_count) DESC, p.related_product_id) AS rank " +
+		"FROM pairs p JOIN product_counts ca ON ca.product_id = p.product_id JOIN product_counts cb ON cb.product_id = p.related_product_id" +
+		") INSERT INTO " + repo.dbConfig.Schema + "." + ProductRecommendationsTableName +
+		" (product_id, related_product_id, relation, support, score, updated_at) " +
+		"SELECT product_id, related_product_id, '" + relation + "', support, score, $4 FROM ranked WHERE rank <= $3"
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type RecommendationRepositoryMock struct {
+	mock.Mock
+}
+
+func (repo *RecommendationRepositoryMock) GetProductRecommendations(productID string, limit int) ([]ProductRecommendationItem, error) {
+	args := repo.Called(productID, limit)
+
+	return args.Get(0).([]ProductRecommendationItem), args.Error(1)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+)
+
+const (
+	// SearchEventsTableName is the name of search events table
+	SearchEventsTableName = "search_events"
+
+	// SearchClicksTableName is the name of search clicks table
+	SearchClicksTableName = "search_clicks"
+)
+
+// SearchEventItem is a logged search with its outcome
+type SearchEventItem struct {
+	ID                string
+	UserID            string
+	SearchKeyword     string
+	NormalizedKeyword string
+	CatalogID         string
+	Filters           map[string]interface{}
+	ResultCount       int
+	LatencyMs         int
+	CreatedAt         time.Time
+}
+
+type SearchAnalyticsRepository struct {
+	db       *sqlx.DB
+	dbConfig config.DBConfig
+}
+
+func NewSearchAnalyticsRepository(db *sqlx.DB, dbConfig config.DBConfig) *SearchAnalyticsRepository {
+	return &SearchAnalyticsRepository{
+		db:       db,
+		dbConfig: dbConfig,
+	}
+}
+
+// SaveSearchEvents records searches with a single multi-row insert
+func (repo *SearchAnalyticsRepository) SaveSearchEvents(items []SearchEventItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	const columnCount = 9
+	values := make([]string, len(items))
+	args := make([]interface{}, 0, len(items)*columnCount)
+	for i, item := range items {
+		filters, err := json.Marshal(item.Filters)
+		if err != nil {
+			return err
+		}
+		if item.Filters == nil {
+			filters = []byte("{}")
+		}
+
+		n := i * columnCount
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d::jsonb, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9)
+		args = append(args, item.ID, item.UserID, item.SearchKeyword, item.NormalizedKeyword, item.CatalogID, string(filters), item.ResultCount, item.LatencyMs, item.CreatedAt)
+	}
+
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + SearchEventsTableName + " (" +
+		"id, user_id, search_keyword, normalized_keyword, catalog_id, filters, result_count, latency_ms, created_at) " +
+		"VALUES " + strings.Join(values, ", ") + " ON CONFLICT (id) DO NOTHING"
+
+	_, err := repo.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repo *SearchAnalyticsRepository) SaveSearchClick(userID *string, searchID, productID string) error {
+	query := "INSERT INTO " + repo.dbConfig.Schema + "." + SearchClicksTableName +
+		" (search_id, product_id, user_id, created_at) VALUES ($1, $2, $3, $4)"
+
+	_, err := repo.db.Exec(query, searchID, productID, userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSearchTotals returns search, zero result search and click-through totals between from and to
+func (repo *SearchAnalyticsRepository) GetSearchTotals(from, to time.Time) (model.SearchAnalytics, error) {
+	var analytics model.SearchAnalytics
+
+	query := "SELECT COUNT(*) AS total_searches, " +
+		"COUNT(*) FILTER (WHERE e.result_count = 0) AS zero_result_searches, " +
+		"COALESCE(COUNT(c.search_id)::float / NULLIF(COUNT(*), 0), 0) AS click_through_rate " +
+		"FROM " + repo.searchEventsWithClicks() +
+		" WHERE e.created_at >= $1 AND e.created_at < $2"
+
+	err := repo.db.Get(&analytics, query, from, to)
+	if err != nil {
+		return model.SearchAnalytics{}, err
+	}
+
+	return analytics, nil
+}
+
+// GetTopQueries returns the most searched keywords between from and to
+func (repo *SearchAnalyticsRepository) GetTopQueries(from, to time.Time, limit int) ([]*model.SearchQueryStat, error) {
+	return repo.getQueryStats("", from, to, limit)
+}
+
+// GetZeroResultQueries returns the most searched keywords that found no products between from and to
+func (repo *SearchAnalyticsRepository) GetZeroResultQueries(from, to time.Time, limit int) ([]*model.SearchQueryStat, error) {
+	return repo.getQueryStats(" AND e.result_count = 0", from, to, limit)
+}
+
+func (repo *SearchAnalyticsRepository) getQueryStats(condition string, from, to time.Time, limit int) ([]*model.SearchQueryStat, error) {
+	stats := []*model.SearchQueryStat{}
+
+	query := "SELECT e.normalized_keyword AS keyword, COUNT(*) AS search_count, " +
+		"AVG(e.result_count)::float AS average_result_count, " +
+		"COUNT(c.search_id) AS click_count, " +
+		"COUNT(c.search_id)::float / COUNT(*) AS click_through_rate " +
+		"FROM " + repo.searchEventsWithClicks() +
+		" WHERE e.created_at >= $1 AND e.created_at < $2" + condition +
+		" GROUP BY e.normalized_keyword ORDER BY search_count DESC, keyword LIMIT $3"
+
+	err := repo.db.Select(&stats, query, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// searchEventsWithClicks joins every search event with whether any of its results was clicked
+func (repo *SearchAnalyticsRepository) searchEventsWithClicks() string {
+	return repo.dbConfig.Schema + "." + SearchEventsTableName + " e LEFT JOIN (SELECT DISTINCT search_id FROM " +
+		repo.dbConfig.Schema + "." + SearchClicksTableName + ") c ON c.search_id = e.id"
+}
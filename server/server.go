@@ -12,12 +12,18 @@ import (
 )
 
 type Server struct {
-	loginService           *service.LoginService
-	productService         *service.ProductService
-	categoryProductService *service.CategoryProductService
-	priceScheduleService   *service.PriceScheduleService
-	categoryService        *service.CategoryService
-	recentSearchService	*service.RecentSearchService
+	loginService            *service.LoginService
+	productService          *service.ProductService
+	categoryProductService  *service.CategoryProductService
+	priceScheduleService    *service.PriceScheduleService
+	categoryService         *service.CategoryService
+	recentSearchService     *service.RecentSearchService
+	searchSuggestionService *service.SearchSuggestionService
+	searchAnalyticsService  *service.SearchAnalyticsService
+	productListService      *service.ProductListService
+	orderEventService       *service.OrderEventService
+	translationService      *service.TranslationService
+	promotionService        *service.PromotionService
 }
 
 func NewServer(loginService *service.LoginService,
@@ -25,14 +31,26 @@ func NewServer(loginService *service.LoginService,
 	categoryProductService *service.CategoryProductService,
 	priceScheduleService *service.PriceScheduleService,
 	categoryService *service.CategoryService,
-	recentSearchService *service.RecentSearchService) *Server {
+	recentSearchService *service.RecentSearchService,
+	searchSuggestionService *service.SearchSuggestionService,
+	searchAnalyticsService *service.SearchAnalyticsService,
+	productListService *service.ProductListService,
+	orderEventService *service.OrderEventService,
+	translationService *service.TranslationService,
+	promotionService *service.PromotionService) *Server {
 	return &Server{
-		loginService:           loginService,
-		productService:         productService,
-		categoryProductService: categoryProductService,
-		priceScheduleService:   priceScheduleService,
-		categoryService:        categoryService,
-		recentSearchService: recentSearchService,
+		loginService:            loginService,
+		productService:          productService,
+		categoryProductService:  categoryProductService,
+		priceScheduleService:    priceScheduleService,
+		categoryService:         categoryService,
+		recentSearchService:     recentSearchService,
+		searchSuggestionService: searchSuggestionService,
+		searchAnalyticsService:  searchAnalyticsService,
+		productListService:      productListService,
+		orderEventService:       orderEventService,
+		translationService:      translationService,
+		promotionService:        promotionService,
 	}
 }
 
@@ -44,15 +62,23 @@ func (server *Server) RoutesHandler(appConfig config.AppConfig) *gin.Engine {
 	r.Use(middleware.ReqBodyMiddleware())
 	r.Use(middleware.AuthMiddleware(appConfig))
 	r.Use(middleware.GinContextToContextMiddleware())
+	r.Use(middleware.MarketMiddleware(appConfig.Markets))
+	r.Use(middleware.LocaleMiddleware())
 
 	// prepare resolvers
 	resolvers := &graph.Resolver{
-		LoginService:           server.loginService,
-		ProductService:         server.productService,
-		CategoryProductService: server.categoryProductService,
-		PriceScheduleService:   server.priceScheduleService,
-		CategoryService:        server.categoryService,
-		RecentSearchService:   server.recentSearchService,
+		LoginService:            server.loginService,
+		ProductService:          server.productService,
+		CategoryProductService:  server.categoryProductService,
+		PriceScheduleService:    server.priceScheduleService,
+		CategoryService:         server.categoryService,
+		RecentSearchService:     server.recentSearchService,
+		SearchSuggestionService: server.searchSuggestionService,
+		SearchAnalyticsService:  server.searchAnalyticsService,
+		ProductListService:      server.productListService,
+		OrderEventService:       server.orderEventService,
+		TranslationService:      server.translationService,
+		PromotionService:        server.promotionService,
 	}
 
 	routes.Routes(r, resolvers, appConfig)
@@ -2,16 +2,26 @@ package service
 
 import (
 	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
 	"mpmy-product-service/repository"
 )
 
+const (
+	// MaxCategoryProductsPageSize is the most category products returned by a single page
+	MaxCategoryProductsPageSize = 100
+)
+
 type CategoryProductService struct {
 	categoryProductRepo *repository.CategoryProductRepository
+	categoryService     *CategoryService
+	productService      *ProductService
 }
 
-func NewCategoryProductService(orderConfig config.OrderCloudConfig) *CategoryProductService {
+func NewCategoryProductService(orderConfig config.OrderCloudConfig, categoryService *CategoryService, productService *ProductService) *CategoryProductService {
 	return &CategoryProductService{
 		categoryProductRepo: repository.NewCategoryProductRepository(orderConfig),
+		categoryService:     categoryService,
+		productService:      productService,
 	}
 }
 
@@ -23,3 +33,79 @@ func (svc *CategoryProductService) GetCategoryProducts(params repository.Categor
 
 	return categoryProducts, nil
 }
+
+// GetCategoryProductConnection returns a page of the products assigned to a category in merchandising ListOrder,
+// see CategoryService.GetCategoryProductAssignments. Products inactive in order cloud are left out of the page
+func (svc *CategoryProductService) GetCategoryProductConnection(categoryID string, includeDescendants *bool, userID *string, first *int, after *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.CategoryProductConnection, error) {
+	limit := DefaultPageSize
+	if first != nil && *first > 0 {
+		limit = minInt(*first, MaxCategoryProductsPageSize)
+	}
+
+	offset := 0
+	if after != nil && *after != "" {
+		cursorOffset, err := decodeOffsetCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		offset = cursorOffset + 1
+	}
+
+	assignments, err := svc.categoryService.GetCategoryProductAssignments(market.CatalogID, categoryID, GetBool(includeDescendants), accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.CategoryProductConnection{
+		Edges:      []*model.CategoryProductEdge{},
+		PageInfo:   &model.PageInfo{},
+		TotalCount: len(assignments),
+	}
+
+	if offset >= len(assignments) {
+		return connection, nil
+	}
+	end := minInt(offset+limit, len(assignments))
+	connection.PageInfo.HasNextPage = end < len(assignments)
+	assignments = assignments[offset:end]
+
+	// get products with price schedules from order cloud in batches
+	productIDs := make([]string, len(assignments))
+	for i, assignment := range assignments {
+		productIDs[i] = assignment.ProductID
+	}
+
+	productsByID, err := svc.productService.getProductsByIDs(productIDs, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.productService.addPriceSchedules(productsByID, market.Currency, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.productService.addFavorites(userID, productsByID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, assignment := range assignments {
+		cursor := encodeOffsetCursor(offset + i)
+		connection.PageInfo.EndCursor = &cursor
+
+		product, ok := productsByID[assignment.ProductID]
+		if !ok {
+			continue
+		}
+
+		connection.Edges = append(connection.Edges, &model.CategoryProductEdge{
+			Cursor:     cursor,
+			CategoryID: assignment.CategoryID,
+			ListOrder:  assignment.ListOrder,
+			Product:    product,
+		})
+	}
+
+	return connection, nil
+}
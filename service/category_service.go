@@ -1,39 +1,399 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"mpmy-product-service/client/cache"
 	"mpmy-product-service/config"
 	"mpmy-product-service/constants"
 	"mpmy-product-service/graph/model"
 	"mpmy-product-service/repository"
 )
 
+const (
+	// CategoryCacheKey is the key prefix of cached category trees, one per catalog
+	CategoryCacheKey = "categories"
+
+	// CategoryAssignmentsRetryTTL is how long a category tree whose product assignments could not be fetched is
+	// cached before the assignments are fetched again
+	CategoryAssignmentsRetryTTL = time.Minute
+)
+
+// ErrCategoryNotFound is returned when a category does not exist in the catalog
+var ErrCategoryNotFound = errors.New("category not found")
+
+// categoryCatalog is the complete category tree of a catalog with its categories indexed by id, the categories
+// every product is assigned to and the product assignments of every category ordered by ListOrder
+type categoryCatalog struct {
+	categories         model.CategoryResponse
+	categoriesByID     map[string]*model.CategoryItems
+	productCategoryIDs map[string][]string
+	categoryProducts   map[string][]repository.CategoryProductItem
+
+	// assignmentsErr is why product assignments could not be fetched
+	assignmentsErr error
+}
+
 type CategoryService struct {
-	CategoryRepo *repository.CategoryRepository
+	CategoryRepo        *repository.CategoryRepository
+	categoryProductRepo repository.ICategoryProductRepository
+	cacheClient         *cache.Cache
+	cacheTTL            time.Duration
+
+	// catalogBuilds lets concurrent requests of an uncached catalog share one build
+	catalogBuilds singleflight.Group
 }
 
-func NewCategoryService(orderConfig config.OrderCloudConfig) *CategoryService {
+func NewCategoryService(orderConfig config.OrderCloudConfig, categoryConfig config.CategoryConfig, cacheClient *cache.Cache) *CategoryService {
 	return &CategoryService{
-		CategoryRepo: repository.NewCategoryRepository(orderConfig),
+		CategoryRepo:        repository.NewCategoryRepository(orderConfig),
+		categoryProductRepo: repository.NewCategoryProductRepository(orderConfig),
+		cacheClient:         cacheClient,
+		cacheTTL:            time.Duration(categoryConfig.CacheTTL) * time.Minute,
 	}
 }
 
-func (svc *CategoryService) GetCategories(catalog, depth *string, accessToken string) (model.CategoryResponse, error) {
-	catalogID := GetString(catalog)
+// GetCategories returns the category tree of the catalog, the market's catalog by default, down to depth, "all"
+// or empty for every level
+func (svc *CategoryService) GetCategories(catalog, depth *string, market config.MarketConfig, accessToken string) (model.CategoryResponse, error) {
 	depthVal := GetString(depth)
-	if catalogID == "" {
-		catalogID = constants.CatalogID
-	}
 	if depthVal == "" {
 		depthVal = constants.Depth
 	}
-	params := repository.GetCategoryParams{
+
+	catalogCategories, err := svc.getCategoryCatalog(marketCatalogID(catalog, market), accessToken)
+	if err != nil {
+		return model.CategoryResponse{}, err
+	}
+
+	if depthVal == constants.Depth {
+		return catalogCategories.categories, nil
+	}
+
+	depthLimit, err := strconv.Atoi(depthVal)
+	if err != nil || depthLimit < 1 {
+		return model.CategoryResponse{}, fmt.Errorf("invalid depth %q", depthVal)
+	}
+
+	// cached categories are shared, so levels are cut from copies
+	items, count := pruneCategories(catalogCategories.categories.Items, depthLimit)
+	rangeStart := minInt(1, count)
+	return model.CategoryResponse{
+		Meta: &model.CategoryMeta{
+			Page:       1,
+			PageSize:   count,
+			TotalCount: count,
+			TotalPages: 1,
+			ItemRange:  []*int{&rangeStart, &count},
+		},
+		Items: items,
+	}, nil
+}
+
+// GetCategory returns a category of the catalog with its descendants, nil when it does not exist
+func (svc *CategoryService) GetCategory(catalog *string, categoryID string, market config.MarketConfig, accessToken string) (*model.CategoryItems, error) {
+	catalogCategories, err := svc.getCategoryCatalog(marketCatalogID(catalog, market), accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalogCategories.categoriesByID[categoryID], nil
+}
+
+// GetCategoryPath returns the ancestors of a category from the root down to the category itself. The path is
+// empty when the category does not exist
+func (svc *CategoryService) GetCategoryPath(catalog *string, categoryID string, market config.MarketConfig, accessToken string) ([]*model.CategoryItems, error) {
+	catalogCategories, err := svc.getCategoryCatalog(marketCatalogID(catalog, market), accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryPath(catalogCategories.categoriesByID, categoryID), nil
+}
+
+// GetProductCategories returns the categories of the market's catalog a product is assigned to, each with its path.
+// When the catalog's product assignments could not be fetched, only the product's own assignments are looked up
+func (svc *CategoryService) GetProductCategories(productID string, market config.MarketConfig, accessToken string) ([]*model.CategoryItems, error) {
+	catalogCategories, err := svc.getCategoryCatalog(market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIDs := catalogCategories.productCategoryIDs[productID]
+	if catalogCategories.assignmentsErr != nil {
+		categoryIDs, err = svc.getProductCategoryIDs(productID, market.CatalogID, accessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	categories := []*model.CategoryItems{}
+	for _, categoryID := range categoryIDs {
+		if category, ok := catalogCategories.categoriesByID[categoryID]; ok {
+			categories = append(categories, category)
+		}
+	}
+
+	return categories, nil
+}
+
+// GetCategoryProductAssignments returns the product assignments of a category ordered by ListOrder, followed by
+// those of its descendants in tree order when includeDescendants is set. A product is returned once, at its
+// first assignment
+func (svc *CategoryService) GetCategoryProductAssignments(catalogID string, categoryID string, includeDescendants bool, accessToken string) ([]repository.CategoryProductItem, error) {
+	catalogCategories, err := svc.getCategoryCatalog(catalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if catalogCategories.assignmentsErr != nil {
+		return nil, catalogCategories.assignmentsErr
+	}
+
+	category, ok := catalogCategories.categoriesByID[categoryID]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+
+	categories := []*model.CategoryItems{category}
+	if includeDescendants {
+		categories = append(categories, descendantCategories(category)...)
+	}
+
+	var assignments []repository.CategoryProductItem
+	seen := make(map[string]bool)
+	for _, c := range categories {
+		for _, assignment := range catalogCategories.categoryProducts[c.ID] {
+			if seen[assignment.ProductID] {
+				continue
+			}
+			seen[assignment.ProductID] = true
+			assignments = append(assignments, assignment)
+		}
+	}
+
+	return assignments, nil
+}
+
+// InvalidateCategories drops the cached category tree of the catalog, the market's catalog by default, so the
+// next request fetches it again
+func (svc *CategoryService) InvalidateCategories(catalog *string, market config.MarketConfig) {
+	svc.cacheClient.Delete(categoryCacheKey(marketCatalogID(catalog, market)))
+}
+
+// getProductCategoryIDs looks up the categories a product is assigned to, ordered by assignment ListOrder
+func (svc *CategoryService) getProductCategoryIDs(productID string, catalogID string, accessToken string) ([]string, error) {
+	assignments, err := svc.categoryProductRepo.GetCategoryProductsByProductIDs(catalogID, []string{productID}, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments = append([]repository.CategoryProductItem{}, assignments...)
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].ListOrder < assignments[j].ListOrder
+	})
+
+	var categoryIDs []string
+	for _, assignment := range assignments {
+		if assignment.ProductID == productID {
+			categoryIDs = append(categoryIDs, assignment.CategoryID)
+		}
+	}
+
+	return uniqueStrings(categoryIDs), nil
+}
+
+func (svc *CategoryService) getCategoryCatalog(catalogID string, accessToken string) (*categoryCatalog, error) {
+	cacheKey := categoryCacheKey(catalogID)
+
+	cached, err := svc.cacheClient.Get(cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	if catalogCategories, ok := cached.(*categoryCatalog); ok {
+		return catalogCategories, nil
+	}
+
+	built, err, _ := svc.catalogBuilds.Do(cacheKey, func() (interface{}, error) {
+		return svc.buildCategoryCatalog(catalogID, accessToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return built.(*categoryCatalog), nil
+}
+
+// buildCategoryCatalog fetches and caches the category tree of the catalog with its product assignments
+func (svc *CategoryService) buildCategoryCatalog(catalogID string, accessToken string) (*categoryCatalog, error) {
+	cacheKey := categoryCacheKey(catalogID)
+
+	categories, err := svc.CategoryRepo.FetchCategories(repository.GetCategoryParams{
 		CatalogID: catalogID,
-		Depth:     depthVal,
+		Depth:     constants.Depth,
+	}, accessToken)
+	if err != nil {
+		return nil, err
 	}
-	categoryProducts, err := svc.CategoryRepo.FetchCategories(params, accessToken)
+
+	// categories are served without product counts rather than not at all, and are cached briefly so counts are
+	// retried soon without every request fetching the tree again
+	cacheTTL := svc.cacheTTL
+	assignments, assignmentsErr := svc.categoryProductRepo.GetCatalogCategoryProducts(catalogID, accessToken)
+	if assignmentsErr != nil {
+		fmt.Println("error occurred while fetching category product assignments:", assignmentsErr)
+		assignments = nil
+		cacheTTL = CategoryAssignmentsRetryTTL
+	}
+
+	catalogCategories := newCategoryCatalog(categories, assignments)
+	catalogCategories.assignmentsErr = assignmentsErr
+	err = svc.cacheClient.Set(cacheKey, catalogCategories, cacheTTL)
 	if err != nil {
-		return model.CategoryResponse{}, err
+		fmt.Println("error occurred while caching categories:", err)
 	}
 
-	return categoryProducts, nil
+	return catalogCategories, nil
+}
+
+// newCategoryCatalog indexes the category tree and sets the path and product counts of every category
+func newCategoryCatalog(categories model.CategoryResponse, assignments []repository.CategoryProductItem) *categoryCatalog {
+	categoriesByID := make(map[string]*model.CategoryItems)
+
+	for _, root := range categories.Items {
+		root.Path = []*model.CategoryItems{}
+	}
+	queue := append([]*model.CategoryItems{}, categories.Items...)
+	for len(queue) > 0 {
+		category := queue[0]
+		queue = queue[1:]
+
+		categoriesByID[category.ID] = category
+		for _, child := range category.ChildData {
+			child.Path = append(append([]*model.CategoryItems{}, category.Path...), category)
+		}
+		queue = append(queue, category.ChildData...)
+	}
+
+	productCategoryIDs := countCategoryProducts(categoriesByID, assignments)
+
+	// assignments are sorted by ListOrder while counting
+	categoryProducts := make(map[string][]repository.CategoryProductItem)
+	for _, assignment := range assignments {
+		categoryProducts[assignment.CategoryID] = append(categoryProducts[assignment.CategoryID], assignment)
+	}
+
+	return &categoryCatalog{
+		categories:         categories,
+		categoriesByID:     categoriesByID,
+		productCategoryIDs: productCategoryIDs,
+		categoryProducts:   categoryProducts,
+	}
+}
+
+// descendantCategories returns the descendants of a category depth first in tree order
+func descendantCategories(category *model.CategoryItems) []*model.CategoryItems {
+	var descendants []*model.CategoryItems
+	for _, child := range category.ChildData {
+		descendants = append(descendants, child)
+		descendants = append(descendants, descendantCategories(child)...)
+	}
+	return descendants
+}
+
+// countCategoryProducts sets the number of distinct products assigned to every category directly and to it or
+// any of its descendants, and returns the categories of every product ordered by assignment ListOrder
+func countCategoryProducts(categoriesByID map[string]*model.CategoryItems, assignments []repository.CategoryProductItem) map[string][]string {
+	sort.SliceStable(assignments, func(i, j int) bool {
+		if assignments[i].ListOrder != assignments[j].ListOrder {
+			return assignments[i].ListOrder < assignments[j].ListOrder
+		}
+		return assignments[i].ProductID < assignments[j].ProductID
+	})
+
+	directProducts := make(map[string]map[string]bool)
+	totalProducts := make(map[string]map[string]bool)
+	productCategoryIDs := make(map[string][]string)
+	for _, assignment := range assignments {
+		category, ok := categoriesByID[assignment.CategoryID]
+		if !ok || directProducts[category.ID][assignment.ProductID] {
+			continue
+		}
+		productCategoryIDs[assignment.ProductID] = append(productCategoryIDs[assignment.ProductID], category.ID)
+
+		addProduct(directProducts, category.ID, assignment.ProductID)
+		addProduct(totalProducts, category.ID, assignment.ProductID)
+		for _, ancestor := range category.Path {
+			addProduct(totalProducts, ancestor.ID, assignment.ProductID)
+		}
+	}
+
+	for categoryID, category := range categoriesByID {
+		category.ProductCount = &model.CategoryProductCount{
+			Direct: len(directProducts[categoryID]),
+			Total:  len(totalProducts[categoryID]),
+		}
+	}
+
+	return productCategoryIDs
+}
+
+func addProduct(products map[string]map[string]bool, categoryID, productID string) {
+	if products[categoryID] == nil {
+		products[categoryID] = make(map[string]bool)
+	}
+	products[categoryID][productID] = true
+}
+
+func categoryCacheKey(catalogID string) string {
+	return CategoryCacheKey + ":" + catalogID
+}
+
+// categoryPath walks up from the category to its root and returns copies of the categories without their children,
+// so the cached tree is not shared. The walk stops at a repeated category so a parent cycle cannot loop forever
+func categoryPath(categoriesByID map[string]*model.CategoryItems, categoryID string) []*model.CategoryItems {
+	path := []*model.CategoryItems{}
+
+	visited := make(map[string]bool)
+	for category, ok := categoriesByID[categoryID]; ok && !visited[category.ID]; category, ok = categoriesByID[category.ParentID] {
+		visited[category.ID] = true
+
+		ancestor := *category
+		ancestor.ChildData = []*model.CategoryItems{}
+		path = append(path, &ancestor)
+	}
+
+	// reverse to start from the root
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	// the copies' own paths are the copies above them
+	for i := range path {
+		path[i].Path = append([]*model.CategoryItems{}, path[:i]...)
+	}
+
+	return path
+}
+
+// pruneCategories copies categories down to depth levels and returns the copies with the number of categories kept
+func pruneCategories(categories []*model.CategoryItems, depth int) ([]*model.CategoryItems, int) {
+	pruned := make([]*model.CategoryItems, len(categories))
+	count := len(categories)
+	for i, category := range categories {
+		categoryCopy := *category
+		categoryCopy.ChildData = []*model.CategoryItems{}
+		if depth > 1 {
+			var childCount int
+			categoryCopy.ChildData, childCount = pruneCategories(category.ChildData, depth-1)
+			count += childCount
+		}
+		pruned[i] = &categoryCopy
+	}
+	return pruned, count
 }
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"mpmy-product-service/client/cache"
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+func categoryServiceTestCatalog() *categoryCatalog {
+	tree := repository.BuildCategoryTree([]*model.CategoryItems{
+		{ID: "root", Name: "Root"},
+		{ID: "medicine", ParentID: "root", Name: "Medicine"},
+		{ID: "pain", ParentID: "medicine", Name: "Pain Relief"},
+		{ID: "other", Name: "Other"},
+	})
+	return newCategoryCatalog(model.CategoryResponse{Items: tree.Roots}, []repository.CategoryProductItem{
+		{CategoryID: "pain", ProductID: "panadol", ListOrder: 2},
+		{CategoryID: "pain", ProductID: "ibuprofen"},
+		{CategoryID: "medicine", ProductID: "panadol", ListOrder: 1},
+		{CategoryID: "pain", ProductID: "panadol"},
+		{CategoryID: "missing", ProductID: "vitamin"},
+		{CategoryID: "other", ProductID: "plaster"},
+	})
+}
+
+func TestCategoryPath(t *testing.T) {
+
+	//data test
+	var catalogCategories = categoryServiceTestCatalog()
+
+	path := categoryPath(catalogCategories.categoriesByID, "pain")
+	if len(path) != 3 || path[0].ID != "root" || path[1].ID != "medicine" || path[2].ID != "pain" {
+		t.Error("test failed: path must run from the root to the category")
+	}
+	if pain := catalogCategories.categoriesByID["pain"]; len(pain.Path) != 2 || pain.Path[1].ID != "medicine" {
+		t.Error("test failed: category path must hold its ancestors from the root")
+	}
+	if root := catalogCategories.categoriesByID["root"]; root.Path == nil || len(root.Path) != 0 {
+		t.Error("test failed: root path must be empty")
+	}
+
+	if path := categoryPath(catalogCategories.categoriesByID, "missing"); len(path) != 0 {
+		t.Error("test failed: path of a missing category must be empty")
+	}
+
+	// ancestors are copies without their children so the cached tree is not shared
+	if path[1] == catalogCategories.categoriesByID["medicine"] || len(path[1].ChildData) != 0 {
+		t.Error("test failed: path must hold copies of the ancestors without their children")
+	}
+	if len(catalogCategories.categoriesByID["medicine"].ChildData) != 1 {
+		t.Error("test failed: cached ancestors must keep their children")
+	}
+	if len(path[2].Path) != 2 || path[2].Path[1] != path[1] {
+		t.Error("test failed: path of the copies must be the copies above them")
+	}
+
+	cyclic := map[string]*model.CategoryItems{
+		"a": {ID: "a", ParentID: "b"},
+		"b": {ID: "b", ParentID: "a"},
+	}
+	if path := categoryPath(cyclic, "a"); len(path) != 2 {
+		t.Error("test failed: walk must stop at a repeated category")
+	}
+}
+
+func TestPruneCategories(t *testing.T) {
+
+	//data test
+	var catalogCategories = categoryServiceTestCatalog()
+
+	items, count := pruneCategories(catalogCategories.categories.Items, 2)
+	if count != 3 || len(items) != 2 {
+		t.Error("test failed: two levels must keep 3 categories, got", count)
+	} else if len(items[1].ChildData) != 1 || len(items[1].ChildData[0].ChildData) != 0 {
+		t.Error("test failed: categories below depth must be cut")
+	}
+	if len(catalogCategories.categoriesByID["medicine"].ChildData) != 1 {
+		t.Error("test failed: cached categories must not be modified")
+	}
+}
+
+func TestCountCategoryProducts(t *testing.T) {
+
+	//data test
+	var catalogCategories = categoryServiceTestCatalog()
+	var tests = []struct {
+		categoryID string
+		direct     int
+		total      int
+	}{
+		{"root", 0, 2},
+		{"medicine", 1, 2},
+		{"pain", 2, 2},
+		{"other", 1, 1},
+	}
+
+	for _, test := range tests {
+		count := catalogCategories.categoriesByID[test.categoryID].ProductCount
+		if count == nil || count.Direct != test.direct || count.Total != test.total {
+			t.Error("test failed: unexpected product count of", test.categoryID, count)
+		}
+	}
+
+	categoryIDs := catalogCategories.productCategoryIDs["panadol"]
+	if len(categoryIDs) != 2 || categoryIDs[0] != "pain" || categoryIDs[1] != "medicine" {
+		t.Error("test failed: product categories must be unique and ordered by list order, got", categoryIDs)
+	}
+	if len(catalogCategories.productCategoryIDs["vitamin"]) != 0 {
+		t.Error("test failed: assignments to unknown categories must be ignored")
+	}
+}
+
+func TestGetCategoryProductAssignments(t *testing.T) {
+
+	//data test
+	rCache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 100, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheClient := cache.New(rCache)
+	_ = cacheClient.Set(categoryCacheKey(testMarket.CatalogID), categoryServiceTestCatalog(), 0)
+
+	svc := CategoryService{cacheClient: cacheClient}
+
+	var tests = []struct {
+		categoryID         string
+		includeDescendants bool
+		productIDs         []string
+	}{
+		{"pain", false, []string{"ibuprofen", "panadol"}},
+		{"medicine", false, []string{"panadol"}},
+		{"medicine", true, []string{"panadol", "ibuprofen"}},
+		{"root", true, []string{"panadol", "ibuprofen"}},
+		{"root", false, nil},
+	}
+
+	for _, test := range tests {
+		assignments, err := svc.GetCategoryProductAssignments(testMarket.CatalogID, test.categoryID, test.includeDescendants, "token")
+		if err != nil {
+			t.Error("test failed error: ", err)
+			continue
+		}
+
+		var productIDs []string
+		for _, assignment := range assignments {
+			productIDs = append(productIDs, assignment.ProductID)
+		}
+		if strings.Join(productIDs, ",") != strings.Join(test.productIDs, ",") {
+			t.Error("test failed: unexpected products of", test.categoryID, productIDs)
+		}
+	}
+
+	_, err = svc.GetCategoryProductAssignments(testMarket.CatalogID, "missing", false, "token")
+	if err != ErrCategoryNotFound {
+		t.Error("test failed: missing category must return ErrCategoryNotFound")
+	}
+}
+
+func TestGetProductCategoriesWithoutAssignments(t *testing.T) {
+
+	//data test
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(model.CategoryResponse{
+			Meta: &model.CategoryMeta{Page: 1, TotalPages: 1},
+			Items: []*model.CategoryItems{
+				{ID: "medicine", Name: "Medicine"},
+				{ID: "pain", ParentID: "medicine", Name: "Pain Relief"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	rCache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 100, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
+	categoryProductRepositoryMock.On("GetCatalogCategoryProducts", testMarket.CatalogID, "token").Return([]repository.CategoryProductItem(nil), errors.New("failed to fetch category product assignments")).Once()
+	categoryProductRepositoryMock.On("GetCategoryProductsByProductIDs", testMarket.CatalogID, []string{"panadol"}, "token").Return([]repository.CategoryProductItem{
+		{CategoryID: "pain", ProductID: "panadol", ListOrder: 2},
+		{CategoryID: "medicine", ProductID: "panadol", ListOrder: 1},
+	}, nil)
+
+	svc := &CategoryService{
+		CategoryRepo:        repository.NewCategoryRepository(config.OrderCloudConfig{OrderCloudEngine: server.URL}),
+		categoryProductRepo: categoryProductRepositoryMock,
+		cacheClient:         cache.New(rCache),
+	}
+
+	// concurrent items of a list share one build of the tree, which is cached though assignments failed
+	var wg sync.WaitGroup
+	results := make([][]*model.CategoryItems, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = svc.GetProductCategories("panadol", testMarket, "token")
+		}(i)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Error("test failed: categories must be fetched once, got", requests)
+	}
+	for _, result := range results {
+		if len(result) != 2 || result[0].ID != "medicine" || result[1].ID != "pain" {
+			t.Error("test failed: product categories must be looked up for the product, got", result)
+			break
+		}
+	}
+
+	categoryProductRepositoryMock.AssertExpectations(t)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"mpmy-product-service/client/cache"
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// ProductFacetsCacheKey is the key for product facet definitions cache
+	ProductFacetsCacheKey = "product_facets"
+
+	// ProductFacetsCacheTTL is the duration product facet definitions are cached for
+	ProductFacetsCacheTTL = 30 * time.Minute
+)
+
+type FacetService struct {
+	cacheClient      *cache.Cache
+	catalogFacets    map[string][]string
+	productFacetRepo repository.IProductFacetRepository
+}
+
+func NewFacetService(orderConfig config.OrderCloudConfig, cacheClient *cache.Cache) *FacetService {
+	return &FacetService{
+		cacheClient:      cacheClient,
+		catalogFacets:    orderConfig.CatalogFacets,
+		productFacetRepo: repository.NewProductFacetRepository(orderConfig),
+	}
+}
+
+// GetFacets shapes the facets returned by order cloud with a product list into the facets
+// configured for the catalog, ordered by ListOrder and flagged with the values selected in filters
+func (svc *FacetService) GetFacets(catalogID string, facets []*model.ListFacet, filters map[string]interface{}, accessToken string) ([]*model.ListFacet, error) {
+	if len(facets) == 0 {
+		return facets, nil
+	}
+
+	definitions, err := svc.getFacetDefinitions(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildListFacets(definitions, svc.catalogFacets[catalogID], facets, filters), nil
+}
+
+func (svc *FacetService) getFacetDefinitions(accessToken string) ([]repository.ProductFacetDefinition, error) {
+	cacheResp, err := svc.cacheClient.Get(ProductFacetsCacheKey)
+	if err == nil && cacheResp != nil {
+		var definitions []repository.ProductFacetDefinition
+		err = mapstructure.Decode(cacheResp, &definitions)
+		if err == nil {
+			return definitions, nil
+		}
+	}
+
+	// get facet definitions from order cloud
+	definitions, err := svc.productFacetRepo.GetProductFacets(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// a failed cache write only costs an extra order cloud call on the next listing
+	_ = svc.cacheClient.Set(ProductFacetsCacheKey, definitions, ProductFacetsCacheTTL)
+
+	return definitions, nil
+}
+
+func buildListFacets(definitions []repository.ProductFacetDefinition, facetIDs []string, facets []*model.ListFacet, filters map[string]interface{}) []*model.ListFacet {
+	// facets returned by order cloud keyed by xp path
+	facetsByXpPath := make(map[string]*model.ListFacet)
+	for _, facet := range facets {
+		if facet != nil {
+			facetsByXpPath[GetString(facet.XpPath)] = facet
+		}
+	}
+
+	allowedFacetIDs := make(map[string]bool)
+	for _, facetID := range facetIDs {
+		allowedFacetIDs[facetID] = true
+	}
+
+	sortedDefinitions := make([]repository.ProductFacetDefinition, len(definitions))
+	copy(sortedDefinitions, definitions)
+	sort.SliceStable(sortedDefinitions, func(i, j int) bool {
+		return sortedDefinitions[i].ListOrder < sortedDefinitions[j].ListOrder
+	})
+
+	var result []*model.ListFacet
+	for _, definition := range sortedDefinitions {
+		// catalogs without configured facets expose every facet definition
+		if len(allowedFacetIDs) > 0 && !allowedFacetIDs[definition.ID] {
+			continue
+		}
+
+		facet, ok := facetsByXpPath[definition.XpPath]
+		if !ok {
+			continue
+		}
+
+		selectedValues := getSelectedFacetValues(filters, definition.XpPath)
+		for _, value := range facet.Values {
+			if value != nil {
+				value.IsSelected = selectedValues[GetString(value.Value)]
+			}
+		}
+
+		name := definition.Name
+		facet.Name = &name
+		result = append(result, facet)
+	}
+
+	return result
+}
+
+// getSelectedFacetValues reads the values of an xp filter such as xp.Brand=A|B
+func getSelectedFacetValues(filters map[string]interface{}, xpPath string) map[string]bool {
+	selectedValues := make(map[string]bool)
+
+	value, ok := filters["xp."+xpPath]
+	if !ok || value == nil {
+		return selectedValues
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return selectedValues
+	}
+
+	for _, selected := range strings.Split(str, "|") {
+		selectedValues[selected] = true
+	}
+
+	return selectedValues
+}
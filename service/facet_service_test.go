@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+func TestBuildListFacets(t *testing.T) {
+
+	//data test
+	var brandPath = "Brand"
+	var countryPath = "CountryOfOrigin"
+	var acme = "Acme"
+	var zenith = "Zenith"
+	var one = 1
+
+	definitions := []repository.ProductFacetDefinition{
+		{ID: "country", Name: "Country", XpPath: countryPath, ListOrder: 2},
+		{ID: "brand", Name: "Brand", XpPath: brandPath, ListOrder: 1},
+	}
+
+	newFacets := func() []*model.ListFacet {
+		return []*model.ListFacet{
+			{XpPath: &countryPath, Values: []*model.ListFacetValue{{Value: &acme, Count: &one}}},
+			{XpPath: &brandPath, Values: []*model.ListFacetValue{{Value: &acme, Count: &one}, {Value: &zenith, Count: &one}}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		facetIDs []string
+		filters  map[string]interface{}
+		expected []string
+		selected []string
+	}{
+		{name: "all facets ordered by list order", expected: []string{"Brand", "Country"}},
+		{name: "only configured facets", facetIDs: []string{"country"}, expected: []string{"Country"}},
+		{name: "selected values", filters: map[string]interface{}{"xp.Brand": "Acme|Other"}, expected: []string{"Brand", "Country"}, selected: []string{acme}},
+	}
+
+	for _, test := range tests {
+		result := buildListFacets(definitions, test.facetIDs, newFacets(), test.filters)
+		if len(result) != len(test.expected) {
+			t.Errorf("%s: expected %d facets, got %d", test.name, len(test.expected), len(result))
+			continue
+		}
+
+		for i, facet := range result {
+			if GetString(facet.Name) != test.expected[i] {
+				t.Errorf("%s: expected facet %s at %d, got %s", test.name, test.expected[i], i, GetString(facet.Name))
+			}
+		}
+
+		var selected []string
+		for _, value := range result[0].Values {
+			if value.IsSelected {
+				selected = append(selected, GetString(value.Value))
+			}
+		}
+		if len(selected) != len(test.selected) {
+			t.Errorf("%s: expected selected values %v, got %v", test.name, test.selected, selected)
+		}
+	}
+}
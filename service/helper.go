@@ -1,5 +1,7 @@
 package service
 
+import "mpmy-product-service/config"
+
 func GetString(val *string) string {
 	if val == nil {
 		return ""
@@ -7,6 +9,20 @@ func GetString(val *string) string {
 	return *val
 }
 
+func GetInt(val *int) int {
+	if val == nil {
+		return 0
+	}
+	return *val
+}
+
+func GetFloat(val *float64) float64 {
+	if val == nil {
+		return 0
+	}
+	return *val
+}
+
 func GetBool(val *bool) bool {
 	if val == nil {
 		return false
@@ -17,3 +33,32 @@ func GetBool(val *bool) bool {
 func GetBoolPointer(val bool) *bool {
 	return &val
 }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// uniqueStrings returns values without repeats, keeping the first occurrence of each
+func uniqueStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// marketCatalogID returns the requested catalog, or the market's catalog when none is requested
+func marketCatalogID(catalogID *string, market config.MarketConfig) string {
+	if GetString(catalogID) != "" {
+		return *catalogID
+	}
+	return market.CatalogID
+}
@@ -6,11 +6,13 @@ import (
 	"mpmy-product-service/config"
 
 	"mpmy-product-service/repository"
+	"sync"
 	"time"
 )
 
 type LoginService struct {
-	AccessToken string
+	mu          sync.RWMutex
+	accessToken string
 	loginRepo   *repository.LoginRepository
 }
 
@@ -34,7 +36,7 @@ func (svc *LoginService) StartAccessTokenFetcher(ctx context.Context, orderCloud
 	if err != nil {
 		fmt.Println("failed to fetch access token, error: ", err)
 	} else {
-		svc.AccessToken = accessToken
+		svc.setAccessToken(accessToken)
 		fmt.Println("access token fetched")
 	}
 
@@ -52,9 +54,22 @@ func (svc *LoginService) StartAccessTokenFetcher(ctx context.Context, orderCloud
 			if err != nil {
 				fmt.Println("failed to fetch access token")
 			} else {
-				svc.AccessToken = accessToken
+				svc.setAccessToken(accessToken)
 				fmt.Println("access token fetched")
 			}
 		}
 	}
 }
+
+// GetAccessToken returns the latest order cloud access token, empty until the first one is fetched
+func (svc *LoginService) GetAccessToken() string {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	return svc.accessToken
+}
+
+func (svc *LoginService) setAccessToken(accessToken string) {
+	svc.mu.Lock()
+	svc.accessToken = accessToken
+	svc.mu.Unlock()
+}
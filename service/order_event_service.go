@@ -0,0 +1,266 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// MaxOrderEventLines is the most order lines recorded by a single call
+	MaxOrderEventLines = 5000
+
+	// orderEventClockSkew is how far in the future an order may have been submitted, allowing for clock differences
+	orderEventClockSkew = 5 * time.Minute
+)
+
+// ErrTooManyOrderLines is returned when order events carry more than MaxOrderEventLines lines
+var ErrTooManyOrderLines = fmt.Errorf("at most %d order lines can be recorded at once", MaxOrderEventLines)
+
+// OrderEventService records submitted orders into trending products, which feed trending,
+// frequently purchased and recommended products
+type OrderEventService struct {
+	orderEventRepo          *repository.OrderEventRepository
+	promotionRedemptionRepo repository.IPromotionRedemptionRepository
+	categoryProductRepo     repository.ICategoryProductRepository
+	markets                 config.MarketsConfig
+}
+
+func NewOrderEventService(db *sqlx.DB, dbConfig config.DBConfig, orderConfig config.OrderCloudConfig, markets config.MarketsConfig) *OrderEventService {
+	return &OrderEventService{
+		orderEventRepo:          repository.NewOrderEventRepository(db, dbConfig),
+		promotionRedemptionRepo: repository.NewPromotionRedemptionRepository(db, dbConfig),
+		categoryProductRepo:     repository.NewCategoryProductRepository(orderConfig),
+		markets:                 markets,
+	}
+}
+
+// RecordOrderEvents validates and records the lines of submitted orders. Invalid orders are rejected as a whole
+// while the rest are still recorded, and lines recorded before are counted as duplicates so events can be resent.
+// Missing categories are looked up in the catalog of the order's market, the request's market by default. The
+// promotions redeemed on recorded orders are counted towards their users' redemptions
+func (svc *OrderEventService) RecordOrderEvents(events []*model.OrderEventInput, market config.MarketConfig, accessToken string) (*model.RecordOrderEventsPayload, error) {
+	lineCount := 0
+	for _, event := range events {
+		if event != nil {
+			lineCount += len(event.Lines)
+		}
+	}
+	if lineCount > MaxOrderEventLines {
+		return nil, ErrTooManyOrderLines
+	}
+
+	eventsByCatalog, rejectedOrders := svc.groupOrderEventsByCatalog(events, market)
+
+	catalogIDs := make([]string, 0, len(eventsByCatalog))
+	for catalogID := range eventsByCatalog {
+		catalogIDs = append(catalogIDs, catalogID)
+	}
+	sort.Strings(catalogIDs)
+
+	now := time.Now().UTC()
+	var items []repository.OrderLineItem
+	for _, catalogID := range catalogIDs {
+		catalogItems, catalogRejectedOrders := toOrderLineItems(eventsByCatalog[catalogID], now)
+		rejectedOrders = append(rejectedOrders, catalogRejectedOrders...)
+
+		svc.addCategories(catalogItems, catalogID, accessToken)
+		items = append(items, catalogItems...)
+	}
+
+	recorded, err := svc.orderEventRepo.SaveOrderLines(items)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.promotionRedemptionRepo.SavePromotionRedemptions(toPromotionRedemptions(events, now))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RecordOrderEventsPayload{
+		RecordedLineCount:  recorded,
+		DuplicateLineCount: len(items) - recorded,
+		RejectedOrders:     rejectedOrders,
+	}, nil
+}
+
+// groupOrderEventsByCatalog groups orders by the catalog of their market and rejects orders of markets this
+// service does not serve
+func (svc *OrderEventService) groupOrderEventsByCatalog(events []*model.OrderEventInput, market config.MarketConfig) (map[string][]*model.OrderEventInput, []*model.RejectedOrderEvent) {
+	eventsByCatalog := make(map[string][]*model.OrderEventInput)
+	rejectedOrders := []*model.RejectedOrderEvent{}
+
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+
+		catalogID := market.CatalogID
+		if marketID := strings.TrimSpace(GetString(event.MarketID)); marketID != "" {
+			orderMarket, ok := svc.markets.Market(marketID)
+			if !ok {
+				rejectedOrders = append(rejectedOrders, &model.RejectedOrderEvent{OrderID: event.OrderID, Reason: fmt.Sprintf("market %s is not supported", marketID)})
+				continue
+			}
+			catalogID = orderMarket.CatalogID
+		}
+
+		eventsByCatalog[catalogID] = append(eventsByCatalog[catalogID], event)
+	}
+
+	return eventsByCatalog, rejectedOrders
+}
+
+// addCategories looks up the category of lines recorded without one in the catalog, in batches of product ids.
+// Lines whose category cannot be found are recorded without it
+func (svc *OrderEventService) addCategories(items []repository.OrderLineItem, catalogID string, accessToken string) {
+	var productIDs []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.CategoryID == nil && !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	if len(productIDs) == 0 {
+		return
+	}
+
+	categoryProducts, err := svc.categoryProductRepo.GetCategoryProductsByProductIDs(catalogID, productIDs, accessToken)
+	if err != nil {
+		fmt.Printf("error occurred while fetching categories of %d products: %v\n", len(productIDs), err)
+		return
+	}
+
+	// a product in several categories is recorded in the first one order cloud returns
+	categoryIDs := make(map[string]*string)
+	for i := range categoryProducts {
+		if _, ok := categoryIDs[categoryProducts[i].ProductID]; !ok && categoryProducts[i].CategoryID != "" {
+			categoryIDs[categoryProducts[i].ProductID] = &categoryProducts[i].CategoryID
+		}
+	}
+
+	for i := range items {
+		if items[i].CategoryID == nil {
+			items[i].CategoryID = categoryIDs[items[i].ProductID]
+		}
+	}
+}
+
+// toOrderLineItems converts valid orders into order lines and reports why every other order was rejected
+func toOrderLineItems(events []*model.OrderEventInput, now time.Time) ([]repository.OrderLineItem, []*model.RejectedOrderEvent) {
+	var items []repository.OrderLineItem
+	rejectedOrders := []*model.RejectedOrderEvent{}
+
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+
+		if reason := validateOrderEvent(event, now); reason != "" {
+			rejectedOrders = append(rejectedOrders, &model.RejectedOrderEvent{OrderID: event.OrderID, Reason: reason})
+			continue
+		}
+
+		for _, line := range event.Lines {
+			items = append(items, repository.OrderLineItem{
+				OrderID:    strings.TrimSpace(event.OrderID),
+				LineID:     strings.TrimSpace(line.LineID),
+				UserID:     strings.TrimSpace(event.UserID),
+				ProductID:  strings.TrimSpace(line.ProductID),
+				Quantity:   line.Quantity,
+				CategoryID: nonEmptyString(line.CategoryID),
+				SupplierID: nonEmptyString(line.SupplierID),
+				CreatedAt:  event.SubmittedAt.UTC(),
+			})
+		}
+	}
+
+	return items, rejectedOrders
+}
+
+// toPromotionRedemptions returns the promotions redeemed on valid orders, once per order
+func toPromotionRedemptions(events []*model.OrderEventInput, now time.Time) []repository.PromotionRedemptionItem {
+	var items []repository.PromotionRedemptionItem
+
+	for _, event := range events {
+		if event == nil || validateOrderEvent(event, now) != "" {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, promotionID := range event.PromotionIDs {
+			promotionID = strings.TrimSpace(promotionID)
+			if promotionID == "" || seen[promotionID] {
+				continue
+			}
+			seen[promotionID] = true
+
+			items = append(items, repository.PromotionRedemptionItem{
+				OrderID:     strings.TrimSpace(event.OrderID),
+				PromotionID: promotionID,
+				UserID:      strings.TrimSpace(event.UserID),
+				CreatedAt:   event.SubmittedAt.UTC(),
+			})
+		}
+	}
+
+	return items
+}
+
+// validateOrderEvent returns why the order cannot be recorded, or an empty string when it can
+func validateOrderEvent(event *model.OrderEventInput, now time.Time) string {
+	if strings.TrimSpace(event.OrderID) == "" {
+		return "order id is required"
+	}
+	if strings.TrimSpace(event.UserID) == "" {
+		return "user id is required"
+	}
+	if event.SubmittedAt.IsZero() || event.SubmittedAt.After(now.Add(orderEventClockSkew)) {
+		return "submitted at must not be in the future"
+	}
+	if len(event.Lines) == 0 {
+		return "order has no lines"
+	}
+
+	lineIDs := make(map[string]bool)
+	for _, line := range event.Lines {
+		if line == nil {
+			return "order has an empty line"
+		}
+
+		lineID := strings.TrimSpace(line.LineID)
+		if lineID == "" {
+			return "line id is required"
+		}
+		if lineIDs[lineID] {
+			return fmt.Sprintf("line %s is repeated", lineID)
+		}
+		lineIDs[lineID] = true
+
+		if strings.TrimSpace(line.ProductID) == "" {
+			return fmt.Sprintf("line %s has no product id", lineID)
+		}
+		if line.Quantity <= 0 {
+			return fmt.Sprintf("line %s quantity must be positive", lineID)
+		}
+	}
+
+	return ""
+}
+
+func nonEmptyString(val *string) *string {
+	if val == nil || strings.TrimSpace(*val) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*val)
+	return &trimmed
+}
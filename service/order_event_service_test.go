@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+func TestValidateOrderEvent(t *testing.T) {
+
+	//data test
+
+	var now = time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	newEvent := func(modify func(event *model.OrderEventInput)) *model.OrderEventInput {
+		event := &model.OrderEventInput{
+			OrderID:     "order-1",
+			UserID:      "42",
+			SubmittedAt: now.Add(-time.Hour),
+			Lines: []*model.OrderLineEventInput{
+				{LineID: "1", ProductID: "p1", Quantity: 2},
+				{LineID: "2", ProductID: "p2", Quantity: 1},
+			},
+		}
+		if modify != nil {
+			modify(event)
+		}
+		return event
+	}
+
+	tests := []struct {
+		name  string
+		event *model.OrderEventInput
+		valid bool
+	}{
+		{name: "valid order", event: newEvent(nil), valid: true},
+		{name: "submitted within clock skew", event: newEvent(func(e *model.OrderEventInput) { e.SubmittedAt = now.Add(time.Minute) }), valid: true},
+		{name: "missing order id", event: newEvent(func(e *model.OrderEventInput) { e.OrderID = " " })},
+		{name: "missing user id", event: newEvent(func(e *model.OrderEventInput) { e.UserID = "" })},
+		{name: "submitted in the future", event: newEvent(func(e *model.OrderEventInput) { e.SubmittedAt = now.Add(time.Hour) })},
+		{name: "no lines", event: newEvent(func(e *model.OrderEventInput) { e.Lines = nil })},
+		{name: "missing line id", event: newEvent(func(e *model.OrderEventInput) { e.Lines[0].LineID = "" })},
+		{name: "repeated line id", event: newEvent(func(e *model.OrderEventInput) { e.Lines[1].LineID = "1" })},
+		{name: "missing product id", event: newEvent(func(e *model.OrderEventInput) { e.Lines[0].ProductID = "" })},
+		{name: "zero quantity", event: newEvent(func(e *model.OrderEventInput) { e.Lines[1].Quantity = 0 })},
+	}
+
+	for _, test := range tests {
+		reason := validateOrderEvent(test.event, now)
+		if test.valid && reason != "" {
+			t.Errorf("test failed %s, order should be valid, got %q", test.name, reason)
+		}
+		if !test.valid && reason == "" {
+			t.Errorf("test failed %s, order should be rejected", test.name)
+		}
+	}
+}
+
+func TestToOrderLineItems(t *testing.T) {
+
+	//data test
+
+	var now = time.Now().UTC()
+	var supplierID = " supplier-1 "
+	var emptyCategoryID = ""
+
+	events := []*model.OrderEventInput{
+		{
+			OrderID:     "order-1",
+			UserID:      "42",
+			SubmittedAt: now,
+			Lines: []*model.OrderLineEventInput{
+				{LineID: "1", ProductID: "p1", Quantity: 3, SupplierID: &supplierID, CategoryID: &emptyCategoryID},
+			},
+		},
+		{OrderID: "order-2", UserID: "42", SubmittedAt: now},
+	}
+
+	items, rejectedOrders := toOrderLineItems(events, now)
+
+	if len(items) != 1 {
+		t.Fatal("test failed, valid order should have one line")
+	}
+
+	if items[0].OrderID != "order-1" || items[0].LineID != "1" || items[0].Quantity != 3 || !items[0].CreatedAt.Equal(now) {
+		t.Error("test failed, line should be copied from the order")
+	}
+
+	if items[0].SupplierID == nil || *items[0].SupplierID != "supplier-1" || items[0].CategoryID != nil {
+		t.Error("test failed, supplier should be trimmed and empty category dropped")
+	}
+
+	if len(rejectedOrders) != 1 || rejectedOrders[0].OrderID != "order-2" {
+		t.Error("test failed, order without lines should be rejected")
+	}
+}
+
+func TestRecordOrderEventsWithTooManyLines(t *testing.T) {
+
+	//data test
+
+	lines := make([]*model.OrderLineEventInput, MaxOrderEventLines+1)
+	events := []*model.OrderEventInput{{OrderID: "order-1", UserID: "42", SubmittedAt: time.Now(), Lines: lines}}
+
+	svc := OrderEventService{}
+
+	_, err := svc.RecordOrderEvents(events, testMarket, uuid.New().String())
+	if !errors.Is(err, ErrTooManyOrderLines) {
+		t.Error("test failed, error should be ErrTooManyOrderLines")
+	}
+}
+
+func TestAddCategories(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var givenCategoryID = "given"
+
+	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
+
+	// every product without a category is looked up once in a single batch
+	categoryProductRepositoryMock.On("GetCategoryProductsByProductIDs", testMarket.CatalogID, []string{"p1", "p2"}, accessToken).Return([]repository.CategoryProductItem{
+		{CategoryID: "c1", ProductID: "p1"},
+		{CategoryID: "c2", ProductID: "p1"},
+	}, nil).Once()
+
+	items := []repository.OrderLineItem{
+		{ProductID: "p1"},
+		{ProductID: "p1"},
+		{ProductID: "p2"},
+		{ProductID: "p3", CategoryID: &givenCategoryID},
+	}
+
+	svc := OrderEventService{
+		categoryProductRepo: categoryProductRepositoryMock,
+	}
+
+	svc.addCategories(items, testMarket.CatalogID, accessToken)
+
+	if GetString(items[0].CategoryID) != "c1" || GetString(items[1].CategoryID) != "c1" {
+		t.Error("test failed, line should be recorded in the product's first category")
+	}
+
+	if items[2].CategoryID != nil {
+		t.Error("test failed, line without category assignment should have no category")
+	}
+
+	if GetString(items[3].CategoryID) != givenCategoryID {
+		t.Error("test failed, given category should be kept")
+	}
+
+	categoryProductRepositoryMock.AssertExpectations(t)
+}
+
+func TestGroupOrderEventsByCatalog(t *testing.T) {
+
+	//data test
+
+	var sgMarketID = "SG"
+	var unknownMarketID = "th"
+	var sgMarket = config.MarketConfig{ID: "sg", CatalogID: "zp-sg", Currency: "SGD"}
+
+	events := []*model.OrderEventInput{
+		{OrderID: "order-1"},
+		{OrderID: "order-2", MarketID: &sgMarketID},
+		{OrderID: "order-3", MarketID: &unknownMarketID},
+		nil,
+	}
+
+	svc := OrderEventService{
+		markets: config.MarketsConfig{DefaultMarket: "my", Markets: map[string]config.MarketConfig{"my": testMarket, "sg": sgMarket}},
+	}
+
+	eventsByCatalog, rejectedOrders := svc.groupOrderEventsByCatalog(events, testMarket)
+
+	if len(eventsByCatalog[testMarket.CatalogID]) != 1 || eventsByCatalog[testMarket.CatalogID][0].OrderID != "order-1" {
+		t.Error("test failed, order without market should use the request's catalog")
+	}
+
+	if len(eventsByCatalog[sgMarket.CatalogID]) != 1 || eventsByCatalog[sgMarket.CatalogID][0].OrderID != "order-2" {
+		t.Error("test failed, order should use the catalog of its market")
+	}
+
+	if len(rejectedOrders) != 1 || rejectedOrders[0].OrderID != "order-3" {
+		t.Error("test failed, order of an unknown market should be rejected")
+	}
+}
+
+func TestToPromotionRedemptions(t *testing.T) {
+
+	//data test
+	var now = time.Now().UTC()
+
+	events := []*model.OrderEventInput{
+		{
+			OrderID:      "order-1",
+			UserID:       "42",
+			SubmittedAt:  now,
+			Lines:        []*model.OrderLineEventInput{{LineID: "1", ProductID: "p1", Quantity: 1}},
+			PromotionIDs: []string{" promo-1 ", "promo-1", "", "promo-2"},
+		},
+		{OrderID: "order-2", UserID: "42", SubmittedAt: now, PromotionIDs: []string{"promo-1"}},
+	}
+
+	items := toPromotionRedemptions(events, now)
+
+	if len(items) != 2 || items[0].PromotionID != "promo-1" || items[1].PromotionID != "promo-2" {
+		t.Fatal("test failed, promotions of the valid order should be redeemed once each, got", items)
+	}
+
+	if items[0].OrderID != "order-1" || items[0].UserID != "42" || !items[0].CreatedAt.Equal(now) {
+		t.Error("test failed, redemption should be copied from the order")
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+)
+
+var (
+	// ErrInvalidQuantity is returned when a price is quoted for less than one unit
+	ErrInvalidQuantity = errors.New("quantity must be at least 1")
+
+	// ErrNoPrice is returned when the product has no price schedule or no price break applies to the quantity
+	ErrNoPrice = errors.New("product has no price for the quantity")
+
+	// ErrQuantityBelowMinimum is returned when the quantity is below the price schedule's MinQuantity
+	ErrQuantityBelowMinimum = errors.New("quantity is below the minimum")
+
+	// ErrQuantityAboveMaximum is returned when the quantity is above the price schedule's MaxQuantity
+	ErrQuantityAboveMaximum = errors.New("quantity is above the maximum")
+
+	// ErrRestrictedQuantity is returned when the price schedule restricts quantities to its price break quantities
+	ErrRestrictedQuantity = errors.New("quantity must be one of the price break quantities")
+)
+
+// windowTimeLayouts are the layouts sale and promotion windows are read in, those without a zone are in the market's
+// timezone
+var windowTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// QuotePrice returns the price of quantity units of the product at the current time in the market, nil when the
+// product has no price schedule. Quantities below the price schedule's MinQuantity are quoted at MinQuantity, so
+// products sold in packs still get a price at the default quantity. See quotePrice
+func (svc *PriceScheduleService) QuotePrice(product *model.ProductItem, quantity int, market config.MarketConfig) (*model.PriceQuote, error) {
+	if product.PriceSchedule == nil {
+		return nil, nil
+	}
+	if minQuantity := GetInt(product.PriceSchedule.MinQuantity); quantity < minQuantity {
+		quantity = minQuantity
+	}
+
+	return quotePrice(product, quantity, market, time.Now())
+}
+
+// GetPriceQuote returns the price of quantity units of the product at the current time in the market
+func (svc *ProductService) GetPriceQuote(productID string, quantity int, market config.MarketConfig, party PriceParty, accessToken string) (*model.PriceQuote, error) {
+	product, err := svc.productRepo.GetProduct(productID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.addPriceSchedules(map[string]*model.ProductItem{productID: &product}, market.Currency, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return quotePrice(&product, quantity, market, time.Now())
+}
+
+// quotePrice prices quantity units at the price break with the largest quantity not above quantity. The unit price is
+// rounded to the currency's minor units first and totals are multiplied from it, so the extended price is always
+// quantity times the unit price shown. The break's sale price applies when now is within the sale window, read in the
+// market's timezone when the window has no zone. Quantities outside MinQuantity and MaxQuantity are rejected, as are
+// quantities other than the break quantities when the price schedule is restricted
+func quotePrice(product *model.ProductItem, quantity int, market config.MarketConfig, now time.Time) (*model.PriceQuote, error) {
+	if quantity < 1 {
+		return nil, ErrInvalidQuantity
+	}
+
+	priceSchedule := product.PriceSchedule
+	if priceSchedule == nil {
+		return nil, ErrNoPrice
+	}
+
+	if priceSchedule.MinQuantity != nil && quantity < *priceSchedule.MinQuantity {
+		return nil, fmt.Errorf("%w of %d", ErrQuantityBelowMinimum, *priceSchedule.MinQuantity)
+	}
+	if priceSchedule.MaxQuantity != nil && *priceSchedule.MaxQuantity > 0 && quantity > *priceSchedule.MaxQuantity {
+		return nil, fmt.Errorf("%w of %d", ErrQuantityAboveMaximum, *priceSchedule.MaxQuantity)
+	}
+
+	priceBreak := selectPriceBreak(priceSchedule.PriceBreaks, quantity)
+	if priceBreak == nil {
+		return nil, ErrNoPrice
+	}
+	if GetBool(priceSchedule.RestrictedQuantity) && GetInt(priceBreak.Quantity) != quantity {
+		return nil, ErrRestrictedQuantity
+	}
+
+	listUnitPrice := decimalFromFloat(GetFloat(priceBreak.Price))
+	unitPrice := listUnitPrice
+	isOnSale := priceBreak.SalePrice != nil && isWithinWindow(GetString(priceSchedule.SaleStart), GetString(priceSchedule.SaleEnd), market.Location(), now)
+	if isOnSale {
+		unitPrice = decimalFromFloat(*priceBreak.SalePrice)
+	}
+
+	currency := priceSchedule.Currency
+	if GetString(currency) == "" && market.Currency != "" {
+		currency = &market.Currency
+	}
+
+	listUnitPriceMoney := moneyFromDecimal(listUnitPrice, GetString(currency))
+	unitPriceMoney := moneyFromDecimal(unitPrice, GetString(currency))
+	extendedPriceMoney := unitPriceMoney
+	extendedPriceMoney.MinorUnits = unitPriceMoney.MinorUnits * int64(quantity)
+	savingsMoney := unitPriceMoney
+	savingsMoney.MinorUnits = 0
+	if listUnitPriceMoney.MinorUnits > unitPriceMoney.MinorUnits {
+		savingsMoney.MinorUnits = (listUnitPriceMoney.MinorUnits - unitPriceMoney.MinorUnits) * int64(quantity)
+	}
+
+	return &model.PriceQuote{
+		ProductID:          GetString(product.ID),
+		PriceScheduleID:    priceSchedule.ID,
+		Currency:           currency,
+		Quantity:           quantity,
+		BreakQuantity:      GetInt(priceBreak.Quantity),
+		ListUnitPrice:      listUnitPriceMoney.Float64(),
+		UnitPrice:          unitPriceMoney.Float64(),
+		ExtendedPrice:      extendedPriceMoney.Float64(),
+		Savings:            savingsMoney.Float64(),
+		IsOnSale:           isOnSale,
+		ListUnitPriceMoney: &listUnitPriceMoney,
+		UnitPriceMoney:     &unitPriceMoney,
+		ExtendedPriceMoney: &extendedPriceMoney,
+		SavingsMoney:       &savingsMoney,
+	}, nil
+}
+
+// selectPriceBreak returns the price break with the largest quantity not above quantity, nil when there is none
+func selectPriceBreak(priceBreaks []*model.PriceBreak, quantity int) *model.PriceBreak {
+	var selected *model.PriceBreak
+	for _, priceBreak := range priceBreaks {
+		if priceBreak == nil || priceBreak.Price == nil || GetInt(priceBreak.Quantity) > quantity {
+			continue
+		}
+		if selected == nil || GetInt(priceBreak.Quantity) > GetInt(selected.Quantity) {
+			selected = priceBreak
+		}
+	}
+	return selected
+}
+
+// isWithinWindow reports whether now is from start up to end. A window needs at least one bound, an end without a
+// time lasts the whole day, and a bound that cannot be read closes the window
+func isWithinWindow(start, end string, location *time.Location, now time.Time) bool {
+	if start == "" && end == "" {
+		return false
+	}
+
+	if start != "" {
+		startTime, _, ok := parseWindowTime(start, location)
+		if !ok || now.Before(startTime) {
+			return false
+		}
+	}
+
+	if end != "" {
+		endTime, dateOnly, ok := parseWindowTime(end, location)
+		if !ok {
+			return false
+		}
+		if dateOnly {
+			endTime = endTime.AddDate(0, 0, 1)
+		}
+		if !now.Before(endTime) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseWindowTime reads a window bound, dateOnly is set when it has no time of day
+func parseWindowTime(value string, location *time.Location) (t time.Time, dateOnly bool, ok bool) {
+	for _, layout := range windowTimeLayouts {
+		parsed, err := time.ParseInLocation(layout, value, location)
+		if err == nil {
+			return parsed, layout == "2006-01-02", true
+		}
+	}
+	return time.Time{}, false, false
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mpmy-product-service/graph/model"
+)
+
+func priceQuoteTestProduct(priceSchedule *model.PriceScheduleItem) *model.ProductItem {
+	id := "p1"
+	return &model.ProductItem{ID: &id, PriceSchedule: priceSchedule}
+}
+
+func priceQuoteTestBreak(quantity int, price, salePrice float64) *model.PriceBreak {
+	priceBreak := &model.PriceBreak{Quantity: &quantity, Price: &price}
+	if salePrice > 0 {
+		priceBreak.SalePrice = &salePrice
+	}
+	return priceBreak
+}
+
+func TestQuotePrice(t *testing.T) {
+
+	//data test
+	var minQuantity, maxQuantity = 2, 100
+	var restricted = true
+	var saleStart, saleEnd = "2023-06-01", "2023-06-30"
+	var zonedSaleEnd = "2023-06-10T00:00:00Z"
+	// 2023-06-30 20:00 in UTC is already July in Kuala Lumpur
+	var duringSale = time.Date(2023, 6, 30, 10, 0, 0, 0, time.UTC)
+	var afterSale = time.Date(2023, 6, 30, 20, 0, 0, 0, time.UTC)
+
+	var breaks = []*model.PriceBreak{
+		priceQuoteTestBreak(1, 10, 8),
+		priceQuoteTestBreak(10, 9, 7.5),
+		priceQuoteTestBreak(50, 8.333, 0),
+	}
+
+	var tests = []struct {
+		name          string
+		priceSchedule *model.PriceScheduleItem
+		quantity      int
+		now           time.Time
+		unitPrice     float64
+		extendedPrice float64
+		savings       float64
+		breakQuantity int
+		isOnSale      bool
+		err           error
+	}{
+		{name: "largest break not above quantity", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks}, quantity: 12, now: duringSale, unitPrice: 9, extendedPrice: 108, breakQuantity: 10},
+		{name: "sale price within window", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, SaleStart: &saleStart, SaleEnd: &saleEnd}, quantity: 12, now: duringSale, unitPrice: 7.5, extendedPrice: 90, savings: 18, breakQuantity: 10, isOnSale: true},
+		{name: "sale ends in market timezone", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, SaleStart: &saleStart, SaleEnd: &saleEnd}, quantity: 12, now: afterSale, unitPrice: 9, extendedPrice: 108, breakQuantity: 10},
+		{name: "sale window with zone", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, SaleEnd: &zonedSaleEnd}, quantity: 1, now: duringSale, unitPrice: 10, extendedPrice: 10, breakQuantity: 1},
+		{name: "break without sale price", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, SaleStart: &saleStart}, quantity: 60, now: duringSale, unitPrice: 8.33, extendedPrice: 499.8, breakQuantity: 50},
+		{name: "below minimum", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, MinQuantity: &minQuantity}, quantity: 1, now: duringSale, err: ErrQuantityBelowMinimum},
+		{name: "above maximum", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, MaxQuantity: &maxQuantity}, quantity: 101, now: duringSale, err: ErrQuantityAboveMaximum},
+		{name: "restricted to break quantities", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, RestrictedQuantity: &restricted}, quantity: 12, now: duringSale, err: ErrRestrictedQuantity},
+		{name: "restricted break quantity", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks, RestrictedQuantity: &restricted}, quantity: 10, now: duringSale, unitPrice: 9, extendedPrice: 90, breakQuantity: 10},
+		{name: "no break for quantity", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks[1:]}, quantity: 5, now: duringSale, err: ErrNoPrice},
+		{name: "no price schedule", quantity: 1, now: duringSale, err: ErrNoPrice},
+		{name: "invalid quantity", priceSchedule: &model.PriceScheduleItem{PriceBreaks: breaks}, quantity: 0, now: duringSale, err: ErrInvalidQuantity},
+	}
+
+	for _, test := range tests {
+		result, err := quotePrice(priceQuoteTestProduct(test.priceSchedule), test.quantity, testMarket, test.now)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test failed: %s, expected error %v, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test failed: %s, error should be nil, got %v", test.name, err)
+			continue
+		}
+
+		if result.UnitPrice != test.unitPrice || result.ExtendedPrice != test.extendedPrice || result.Savings != test.savings {
+			t.Errorf("test failed: %s, expected %v/%v/%v, got %v/%v/%v", test.name, test.unitPrice, test.extendedPrice, test.savings, result.UnitPrice, result.ExtendedPrice, result.Savings)
+		}
+		if result.BreakQuantity != test.breakQuantity || result.IsOnSale != test.isOnSale {
+			t.Errorf("test failed: %s, expected break %d on sale %v, got break %d on sale %v", test.name, test.breakQuantity, test.isOnSale, result.BreakQuantity, result.IsOnSale)
+		}
+		if GetString(result.Currency) != testMarket.Currency {
+			t.Errorf("test failed: %s, currency must default to the market's", test.name)
+		}
+		if result.ExtendedPriceMoney.Float64() != test.extendedPrice || result.ExtendedPriceMoney.Currency != testMarket.Currency {
+			t.Errorf("test failed: %s, expected extended price money %v, got %v", test.name, test.extendedPrice, result.ExtendedPriceMoney.Amount())
+		}
+	}
+}
+
+func TestQuotePriceTotalsInDecimal(t *testing.T) {
+
+	//data test
+	var price = 0.1
+	var priceSchedule = &model.PriceScheduleItem{PriceBreaks: []*model.PriceBreak{{Quantity: new(int), Price: &price}}}
+
+	result, err := quotePrice(priceQuoteTestProduct(priceSchedule), 3, testMarket, time.Now())
+	if err != nil {
+		t.Fatal("test failed: error should be nil, got", err)
+	}
+	if result.ExtendedPriceMoney.MinorUnits != 30 || result.ExtendedPriceMoney.Amount() != "0.30" || result.ExtendedPrice != 0.3 {
+		t.Error("test failed: 3 units of 0.10 must total 0.30, got", result.ExtendedPriceMoney.Amount())
+	}
+}
+
+func TestQuotePriceAtMinQuantity(t *testing.T) {
+
+	//data test
+	var minQuantity = 6
+	var priceSchedule = &model.PriceScheduleItem{PriceBreaks: []*model.PriceBreak{priceQuoteTestBreak(1, 2.5, 0)}, MinQuantity: &minQuantity}
+
+	svc := PriceScheduleService{}
+
+	result, err := svc.QuotePrice(priceQuoteTestProduct(priceSchedule), 1, testMarket)
+	if err != nil {
+		t.Fatal("test failed: error should be nil, got", err)
+	}
+	if result.Quantity != minQuantity || result.ExtendedPrice != 15 {
+		t.Errorf("test failed: quantity below the minimum must be quoted at the minimum, got %d for %v", result.Quantity, result.ExtendedPrice)
+	}
+
+	result, err = svc.QuotePrice(priceQuoteTestProduct(nil), 1, testMarket)
+	if err != nil || result != nil {
+		t.Error("test failed: product without price schedule must have no quote")
+	}
+}
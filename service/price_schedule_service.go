@@ -1,18 +1,44 @@
 package service
 
 import (
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
 	"mpmy-product-service/graph/model"
 	"mpmy-product-service/repository"
-	"strings"
 )
 
+const (
+	// defaultCurrencyScale is the number of minor unit digits of currencies unknown to ISO 4217
+	defaultCurrencyScale = 2
+
+	// PriceScheduleBatchSize is the number of price schedules fetched from order cloud per request, keeping ID
+	// filters within URL limits
+	PriceScheduleBatchSize = 50
+)
+
+// PriceParty is who products are priced for, the buyer of the user's token and the buyer's user groups the user is
+// in, in order of preference. A party without a buyer is priced at default price schedules only
+type PriceParty struct {
+	BuyerID      string
+	UserGroupIDs []string
+}
+
 type IPriceScheduleService interface {
-	GetPriceSchedule(productID string, page, pageSize *string, accessToken string) (model.PriceScheduleResponse, error)
-	GetPriceSchedules(productIDs []string, page, pageSize *string, accessToken string) (model.PriceScheduleResponse, error)
+	GetPriceSchedule(productID, defaultPriceScheduleID, currency string, party PriceParty, accessToken string) (model.PriceScheduleResponse, error)
+	ResolvePriceSchedules(defaultPriceScheduleIDs map[string]string, currency string, party PriceParty, accessToken string) (map[string]*model.PriceScheduleItem, error)
 }
 
 type PriceScheduleService struct {
-	priceScheduleRepo *repository.PriceScheduleRepository
+	priceScheduleRepo repository.IPriceScheduleRepository
 }
 
 func NewPriceScheduleService() *PriceScheduleService {
@@ -21,35 +47,310 @@ func NewPriceScheduleService() *PriceScheduleService {
 	}
 }
 
-func (svc *PriceScheduleService) GetPriceSchedule(productID string, page, pageSize *string, accessToken string) (model.PriceScheduleResponse, error) {
-	params := repository.PriceScheduleParams{
-		Search:   productID,
-		SearchOn: "ID",
-		Page:     GetString(page),
-		PageSize: GetString(pageSize),
+// GetPriceSchedule returns the price schedules that can price the product for the party in the currency, in the
+// order they are tried in, the first being the one the product is priced at. See ResolvePriceSchedules
+func (svc *PriceScheduleService) GetPriceSchedule(productID, defaultPriceScheduleID, currency string, party PriceParty, accessToken string) (model.PriceScheduleResponse, error) {
+	candidates, err := svc.getPriceScheduleCandidates(map[string]string{productID: defaultPriceScheduleID}, party, accessToken)
+	if err != nil {
+		return model.PriceScheduleResponse{}, err
 	}
 
-	priceSchedules, err := svc.priceScheduleRepo.GetPriceSchedules(params, accessToken)
+	priceSchedulesByID, err := svc.getPriceSchedulesByID(candidates[productID], currency, accessToken)
 	if err != nil {
 		return model.PriceScheduleResponse{}, err
 	}
 
-	return priceSchedules, nil
+	items := make([]*model.PriceScheduleItem, 0, len(candidates[productID]))
+	for _, priceScheduleID := range candidates[productID] {
+		if priceSchedule, ok := priceSchedulesByID[priceScheduleID]; ok {
+			items = append(items, priceSchedule)
+		}
+	}
+
+	page, totalPages, count := 1, 1, len(items)
+	return model.PriceScheduleResponse{
+		Meta:  &model.OrderCloudMeta{Page: &page, PageSize: &count, TotalCount: &count, TotalPages: &totalPages},
+		Items: items,
+	}, nil
 }
 
-func (svc *PriceScheduleService) GetPriceSchedules(productIDs []string, page, pageSize *string, accessToken string) (model.PriceScheduleResponse, error) {
-	params := repository.PriceScheduleParams{
-		ExtraFilters: map[string]interface{}{
-			"ID": strings.Join(productIDs, "|"),
-		},
-		Page:     GetString(page),
-		PageSize: GetString(pageSize),
+// ResolvePriceSchedules returns the price schedule each product is priced at for the party in the currency, by
+// product id. Products are priced at the first of, in order:
+//  1. the price schedule the product is assigned at for one of the party's user groups, in the groups' order
+//  2. the price schedule the product is assigned at for the party's buyer
+//  3. the product's DefaultPriceScheduleID, given by product id in defaultPriceScheduleIDs
+//
+// that exists and is in the currency or in none. Products none of them applies to are left out
+func (svc *PriceScheduleService) ResolvePriceSchedules(defaultPriceScheduleIDs map[string]string, currency string, party PriceParty, accessToken string) (map[string]*model.PriceScheduleItem, error) {
+	candidates, err := svc.getPriceScheduleCandidates(defaultPriceScheduleIDs, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var priceScheduleIDs []string
+	for _, productCandidates := range candidates {
+		priceScheduleIDs = append(priceScheduleIDs, productCandidates...)
 	}
 
-	priceSchedules, err := svc.priceScheduleRepo.GetPriceSchedules(params, accessToken)
+	priceSchedulesByID, err := svc.getPriceSchedulesByID(priceScheduleIDs, currency, accessToken)
 	if err != nil {
-		return model.PriceScheduleResponse{}, err
+		return nil, err
+	}
+
+	priceSchedules := make(map[string]*model.PriceScheduleItem, len(candidates))
+	for productID, productCandidates := range candidates {
+		if priceSchedule := selectPriceSchedule(productCandidates, priceSchedulesByID); priceSchedule != nil {
+			priceSchedules[productID] = priceSchedule
+		}
 	}
 
 	return priceSchedules, nil
 }
+
+// getPriceScheduleCandidates returns the ids of the price schedules that can price each product for the party, in
+// the order they are tried in, see priceScheduleCandidates. Assignments are only fetched for a party with a buyer,
+// and only those of the products priced
+func (svc *PriceScheduleService) getPriceScheduleCandidates(defaultPriceScheduleIDs map[string]string, party PriceParty, accessToken string) (map[string][]string, error) {
+	assignmentsByProduct := make(map[string][]repository.ProductAssignmentItem)
+	if party.BuyerID != "" && len(defaultPriceScheduleIDs) > 0 {
+		productIDs := make([]string, 0, len(defaultPriceScheduleIDs))
+		for productID := range defaultPriceScheduleIDs {
+			productIDs = append(productIDs, productID)
+		}
+		sort.Strings(productIDs)
+
+		assignments, err := svc.priceScheduleRepo.GetProductAssignments(productIDs, party.BuyerID, accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, assignment := range assignments {
+			if _, ok := defaultPriceScheduleIDs[assignment.ProductID]; ok && assignment.PriceScheduleID != "" {
+				assignmentsByProduct[assignment.ProductID] = append(assignmentsByProduct[assignment.ProductID], assignment)
+			}
+		}
+	}
+
+	candidates := make(map[string][]string, len(defaultPriceScheduleIDs))
+	for productID, defaultPriceScheduleID := range defaultPriceScheduleIDs {
+		candidates[productID] = priceScheduleCandidates(defaultPriceScheduleID, party, assignmentsByProduct[productID])
+	}
+
+	return candidates, nil
+}
+
+// priceScheduleCandidates orders the price schedules of a product's assignments to the party and its default price
+// schedule in the order of ResolvePriceSchedules. Assignments to other buyers or to user groups the party is not in
+// are left out
+func priceScheduleCandidates(defaultPriceScheduleID string, party PriceParty, assignments []repository.ProductAssignmentItem) []string {
+	var candidates []string
+	for _, userGroupID := range party.UserGroupIDs {
+		for _, assignment := range assignments {
+			if assignment.BuyerID == party.BuyerID && userGroupID != "" && assignment.UserGroupID == userGroupID {
+				candidates = append(candidates, assignment.PriceScheduleID)
+			}
+		}
+	}
+
+	for _, assignment := range assignments {
+		if assignment.BuyerID == party.BuyerID && assignment.UserGroupID == "" {
+			candidates = append(candidates, assignment.PriceScheduleID)
+		}
+	}
+
+	if defaultPriceScheduleID != "" {
+		candidates = append(candidates, defaultPriceScheduleID)
+	}
+
+	return uniqueStrings(candidates)
+}
+
+// selectPriceSchedule returns the first candidate found, nil when none is
+func selectPriceSchedule(candidates []string, priceSchedulesByID map[string]*model.PriceScheduleItem) *model.PriceScheduleItem {
+	for _, priceScheduleID := range candidates {
+		if priceSchedule, ok := priceSchedulesByID[priceScheduleID]; ok {
+			return priceSchedule
+		}
+	}
+	return nil
+}
+
+// getPriceSchedulesByID fetches the price schedules in the currency or in none from order cloud in batches, by id
+func (svc *PriceScheduleService) getPriceSchedulesByID(priceScheduleIDs []string, currency string, accessToken string) (map[string]*model.PriceScheduleItem, error) {
+	priceScheduleIDs = uniqueStrings(priceScheduleIDs)
+	sort.Strings(priceScheduleIDs)
+
+	priceSchedulesByID := make(map[string]*model.PriceScheduleItem, len(priceScheduleIDs))
+	for start := 0; start < len(priceScheduleIDs); start += PriceScheduleBatchSize {
+		batch := priceScheduleIDs[start:minInt(start+PriceScheduleBatchSize, len(priceScheduleIDs))]
+
+		priceSchedules, err := svc.priceScheduleRepo.GetPriceSchedules(repository.PriceScheduleParams{
+			ExtraFilters: map[string]interface{}{
+				"ID": strings.Join(batch, "|"),
+			},
+			Page:     "1",
+			PageSize: repository.GetStringFromInt(len(batch)),
+		}, accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		priceSchedules = filterPriceSchedulesByCurrency(priceSchedules, currency)
+		addPriceBreakMoney(priceSchedules.Items, currency)
+
+		for _, priceSchedule := range priceSchedules.Items {
+			priceSchedulesByID[GetString(priceSchedule.ID)] = priceSchedule
+		}
+	}
+
+	return priceSchedulesByID, nil
+}
+
+// filterPriceSchedulesByCurrency keeps price schedules in the currency and those without a currency, which apply
+// to every market. Every price schedule is kept when no currency is given
+func filterPriceSchedulesByCurrency(priceSchedules model.PriceScheduleResponse, currency string) model.PriceScheduleResponse {
+	if currency == "" {
+		return priceSchedules
+	}
+
+	items := make([]*model.PriceScheduleItem, 0, len(priceSchedules.Items))
+	for _, priceSchedule := range priceSchedules.Items {
+		scheduleCurrency := GetString(priceSchedule.Currency)
+		if scheduleCurrency == "" || strings.EqualFold(scheduleCurrency, currency) {
+			items = append(items, priceSchedule)
+		}
+	}
+	priceSchedules.Items = items
+
+	return priceSchedules
+}
+
+// FormatMoney formats the amount for display in the first of the locales that can be read, e.g. "RM 12.50" or
+// "Rp 15.000". The locale's symbol for the currency is used, or its narrow symbol when the locale only knows the
+// ISO code and the narrow symbol is unambiguous letters, e.g. "RM" rather than "$". The amount is formatted from its
+// minor units, so amounts too large for a float keep every digit
+func (svc *PriceScheduleService) FormatMoney(money model.Money, locales []string) string {
+	tag := language.English
+	for _, locale := range locales {
+		if parsed, err := language.Parse(strings.ReplaceAll(locale, "_", "-")); err == nil {
+			tag = parsed
+			break
+		}
+	}
+	printer := message.NewPrinter(tag)
+
+	symbol := money.Currency
+	if unit, err := currency.ParseISO(money.Currency); err == nil {
+		symbol = printer.Sprint(currency.Symbol(unit))
+		if narrow := printer.Sprint(currency.NarrowSymbol(unit)); symbol == unit.String() && isLetters(narrow) {
+			symbol = narrow
+		}
+	}
+
+	minorUnits := money.MinorUnits
+	sign := ""
+	if minorUnits < 0 {
+		sign = "-"
+		minorUnits = -minorUnits
+	}
+
+	// the major units are formatted with Scale zeros in the locale's digits, which are then replaced by the minor
+	// units so the amount never goes through a float
+	unit := int64(1)
+	for i := 0; i < money.Scale; i++ {
+		unit *= 10
+	}
+	formatted := printer.Sprint(number.Decimal(minorUnits/unit, number.Scale(money.Scale)))
+	if money.Scale > 0 {
+		digits := []rune(formatted)
+		zero := digits[len(digits)-1]
+		fraction := minorUnits % unit
+		for i := len(digits) - 1; i >= len(digits)-money.Scale; i-- {
+			digits[i] = zero + rune(fraction%10)
+			fraction /= 10
+		}
+		formatted = string(digits)
+	}
+
+	if symbol == "" {
+		return sign + formatted
+	}
+	return sign + symbol + " " + formatted
+}
+
+// addPriceBreakMoney sets the Money of the price breaks in the price schedule's currency, or in currencyCode when the
+// price schedule has none
+func addPriceBreakMoney(priceSchedules []*model.PriceScheduleItem, currencyCode string) {
+	for _, priceSchedule := range priceSchedules {
+		scheduleCurrency := GetString(priceSchedule.Currency)
+		if scheduleCurrency == "" {
+			scheduleCurrency = currencyCode
+		}
+
+		for _, priceBreak := range priceSchedule.PriceBreaks {
+			if priceBreak == nil {
+				continue
+			}
+			if priceBreak.Price != nil {
+				price := toMoney(*priceBreak.Price, scheduleCurrency)
+				priceBreak.PriceMoney = &price
+			}
+			if priceBreak.SalePrice != nil {
+				salePrice := toMoney(*priceBreak.SalePrice, scheduleCurrency)
+				priceBreak.SalePriceMoney = &salePrice
+			}
+		}
+	}
+}
+
+// toMoney converts a price to Money in the currency, see moneyFromDecimal
+func toMoney(price float64, currencyCode string) model.Money {
+	return moneyFromDecimal(decimalFromFloat(price), currencyCode)
+}
+
+// decimalFromFloat returns the decimal a price float was written as, e.g. 0.1 is exactly 1/10 rather than the
+// float nearest to it
+func decimalFromFloat(price float64) *big.Rat {
+	decimal, ok := new(big.Rat).SetString(strconv.FormatFloat(price, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return decimal
+}
+
+// moneyFromDecimal rounds the amount half away from zero to the minor units of the currency, e.g. 2 digits for
+// MYR and none for JPY
+func moneyFromDecimal(amount *big.Rat, currencyCode string) model.Money {
+	currencyCode = strings.ToUpper(strings.TrimSpace(currencyCode))
+	scale := currencyScale(currencyCode)
+
+	minorUnits := new(big.Rat).Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	quotient, remainder := new(big.Int).QuoRem(minorUnits.Num(), minorUnits.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(minorUnits.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(minorUnits.Sign())))
+	}
+
+	return model.Money{MinorUnits: quotient.Int64(), Currency: currencyCode, Scale: scale}
+}
+
+// currencyScale returns the number of minor unit digits of the ISO 4217 currency
+func currencyScale(currencyCode string) int {
+	unit, err := currency.ParseISO(currencyCode)
+	if err != nil {
+		return defaultCurrencyScale
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return scale
+}
+
+func isLetters(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,35 +2,24 @@ package service
 
 import (
 	"mpmy-product-service/graph/model"
-	"mpmy-product-service/repository"
 
 	"github.com/stretchr/testify/mock"
 )
 
 type PriceScheduleServiceMock struct {
-	priceScheduleRepo *repository.PriceScheduleRepository
 	mock.Mock
 }
 
-func (svc *PriceScheduleServiceMock) GetPriceSchedule(productID string, page, pageSize *string, accessToken string) (model.PriceScheduleResponse, error) {
-	params := repository.PriceScheduleParams{
-		Search:   productID,
-		SearchOn: "ID",
-		Page:     GetString(page),
-		PageSize: GetString(pageSize),
-	}
+func (svc *PriceScheduleServiceMock) GetPriceSchedule(productID, defaultPriceScheduleID, currency string, party PriceParty, accessToken string) (model.PriceScheduleResponse, error) {
 
-	priceSchedules, err := svc.priceScheduleRepo.GetPriceSchedules(params, accessToken)
-	if err != nil {
-		return model.PriceScheduleResponse{}, err
-	}
+	args := svc.Called(productID, defaultPriceScheduleID, currency, party, accessToken)
 
-	return priceSchedules, nil
+	return args.Get(0).(model.PriceScheduleResponse), args.Error(1)
 }
 
-func (svc *PriceScheduleServiceMock) GetPriceSchedules(productIDs []string, page, pageSize *string, accessToken string) (model.PriceScheduleResponse, error) {
+func (svc *PriceScheduleServiceMock) ResolvePriceSchedules(defaultPriceScheduleIDs map[string]string, currency string, party PriceParty, accessToken string) (map[string]*model.PriceScheduleItem, error) {
 
-	args := svc.Called(productIDs, page, pageSize, accessToken)
+	args := svc.Called(defaultPriceScheduleIDs, currency, party, accessToken)
 
-	return args.Get(0).(model.PriceScheduleResponse), args.Error(1)
+	return args.Get(0).(map[string]*model.PriceScheduleItem), args.Error(1)
 }
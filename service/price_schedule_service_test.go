@@ -0,0 +1,257 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+
+	"github.com/google/uuid"
+)
+
+func TestFilterPriceSchedulesByCurrency(t *testing.T) {
+
+	//data test
+	var ids = []string{"myr", "sgd", "none", "lower"}
+	var currencies = []string{"MYR", "SGD", "", "myr"}
+	var priceSchedules = model.PriceScheduleResponse{
+		Items: []*model.PriceScheduleItem{
+			{ID: &ids[0], Currency: &currencies[0]},
+			{ID: &ids[1], Currency: &currencies[1]},
+			{ID: &ids[2], Currency: &currencies[2]},
+			{ID: &ids[3], Currency: &currencies[3]},
+			{ID: &ids[2]},
+		},
+	}
+
+	result := filterPriceSchedulesByCurrency(priceSchedules, "MYR")
+	if len(result.Items) != 4 {
+		t.Error("test failed: schedules in the currency or without one must be kept, got", len(result.Items))
+	}
+	for _, priceSchedule := range result.Items {
+		if GetString(priceSchedule.ID) == "sgd" {
+			t.Error("test failed: schedules in another currency must be left out")
+		}
+	}
+
+	result = filterPriceSchedulesByCurrency(priceSchedules, "")
+	if len(result.Items) != len(priceSchedules.Items) {
+		t.Error("test failed: every schedule must be kept without a currency")
+	}
+}
+
+func TestMarketCatalogID(t *testing.T) {
+
+	//data test
+	var requestedCatalogID = "zp-sg"
+	var emptyCatalogID = ""
+
+	if marketCatalogID(&requestedCatalogID, testMarket) != requestedCatalogID {
+		t.Error("test failed: the requested catalog must be used")
+	}
+	if marketCatalogID(&emptyCatalogID, testMarket) != testMarket.CatalogID || marketCatalogID(nil, testMarket) != testMarket.CatalogID {
+		t.Error("test failed: the market's catalog must be used when none is requested")
+	}
+}
+
+func TestToMoney(t *testing.T) {
+
+	//data test
+	var tests = []struct {
+		name       string
+		price      float64
+		currency   string
+		minorUnits int64
+		scale      int
+		amount     string
+	}{
+		{name: "cents of a float sum", price: 0.1 + 0.2, currency: "MYR", minorUnits: 30, scale: 2, amount: "0.30"},
+		{name: "half rounds away from zero", price: 1.005, currency: "MYR", minorUnits: 101, scale: 2, amount: "1.01"},
+		{name: "negative half rounds away from zero", price: -2.675, currency: "SGD", minorUnits: -268, scale: 2, amount: "-2.68"},
+		{name: "currency without minor units", price: 1234.5, currency: "JPY", minorUnits: 1235, scale: 0, amount: "1235"},
+		{name: "currency with three digits", price: 1.2345, currency: "KWD", minorUnits: 1235, scale: 3, amount: "1.235"},
+		{name: "lower case currency", price: 12.5, currency: "myr", minorUnits: 1250, scale: 2, amount: "12.50"},
+		{name: "unknown currency", price: 12.5, currency: "", minorUnits: 1250, scale: 2, amount: "12.50"},
+	}
+
+	for _, test := range tests {
+		result := toMoney(test.price, test.currency)
+		if result.MinorUnits != test.minorUnits || result.Scale != test.scale || result.Amount() != test.amount {
+			t.Errorf("test failed: %s, expected %d of scale %d, got %d of scale %d", test.name, test.minorUnits, test.scale, result.MinorUnits, result.Scale)
+		}
+	}
+}
+
+func TestAddPriceBreakMoney(t *testing.T) {
+
+	//data test
+	var sgd = "SGD"
+	var price, salePrice = 12.5, 9.99
+	var priceSchedules = []*model.PriceScheduleItem{
+		{PriceBreaks: []*model.PriceBreak{{Price: &price, SalePrice: &salePrice}}},
+		{Currency: &sgd, PriceBreaks: []*model.PriceBreak{{Price: &price}, nil}},
+	}
+
+	addPriceBreakMoney(priceSchedules, "MYR")
+
+	if money := priceSchedules[0].PriceBreaks[0].PriceMoney; money == nil || money.MinorUnits != 1250 || money.Currency != "MYR" {
+		t.Error("test failed: price must be in the requested currency when the schedule has none, got", money)
+	}
+	if money := priceSchedules[0].PriceBreaks[0].SalePriceMoney; money == nil || money.MinorUnits != 999 {
+		t.Error("test failed: sale price must be converted, got", money)
+	}
+	if money := priceSchedules[1].PriceBreaks[0].PriceMoney; money == nil || money.Currency != "SGD" {
+		t.Error("test failed: price must be in the schedule's currency, got", money)
+	}
+	if priceSchedules[1].PriceBreaks[0].SalePriceMoney != nil {
+		t.Error("test failed: break without sale price must have no sale price money")
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+
+	//data test
+	var tests = []struct {
+		name     string
+		money    model.Money
+		locales  []string
+		expected string
+	}{
+		{name: "ringgit in malay", money: model.Money{MinorUnits: 1250, Currency: "MYR", Scale: 2}, locales: []string{"ms-MY"}, expected: "RM 12.50"},
+		{name: "ringgit in english", money: model.Money{MinorUnits: 123456750, Currency: "MYR", Scale: 2}, locales: []string{"en"}, expected: "RM 1,234,567.50"},
+		{name: "rupiah in indonesian", money: model.Money{MinorUnits: 1500000, Currency: "IDR", Scale: 2}, locales: []string{"id-ID"}, expected: "Rp 15.000,00"},
+		{name: "yen without minor units", money: model.Money{MinorUnits: 1235, Currency: "JPY", Scale: 0}, locales: []string{"ja"}, expected: "￥ 1,235"},
+		{name: "unreadable locale is skipped", money: model.Money{MinorUnits: -999, Currency: "MYR", Scale: 2}, locales: []string{"!!", "en"}, expected: "-RM 9.99"},
+		{name: "without currency", money: model.Money{MinorUnits: 1250, Scale: 2}, locales: nil, expected: "12.50"},
+		{name: "beyond float precision", money: model.Money{MinorUnits: 9007199254740993, Currency: "MYR", Scale: 2}, locales: []string{"en"}, expected: "RM 90,071,992,547,409.93"},
+		{name: "minor units only", money: model.Money{MinorUnits: 5, Currency: "MYR", Scale: 2}, locales: []string{"ms"}, expected: "RM 0.05"},
+		{name: "three digit scale", money: model.Money{MinorUnits: 1234567, Currency: "KWD", Scale: 3}, locales: []string{"fr"}, expected: "KWD 1\u00a0234,567"},
+	}
+
+	svc := &PriceScheduleService{}
+	for _, test := range tests {
+		result := svc.FormatMoney(test.money, test.locales)
+		if result != test.expected {
+			t.Errorf("test failed: %s, expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestPriceScheduleCandidates(t *testing.T) {
+
+	//data test
+	var party = PriceParty{BuyerID: "zp-buyer", UserGroupIDs: []string{"zp-vip", "zp-wholesale"}}
+	var assignments = []repository.ProductAssignmentItem{
+		{BuyerID: "zp-buyer", PriceScheduleID: "ps-buyer"},
+		{BuyerID: "zp-buyer", UserGroupID: "zp-wholesale", PriceScheduleID: "ps-wholesale"},
+		{BuyerID: "zp-buyer", UserGroupID: "zp-vip", PriceScheduleID: "ps-vip"},
+		{BuyerID: "zp-buyer", UserGroupID: "zp-staff", PriceScheduleID: "ps-staff"},
+		{BuyerID: "other-buyer", PriceScheduleID: "ps-other"},
+	}
+
+	var tests = []struct {
+		name                   string
+		defaultPriceScheduleID string
+		party                  PriceParty
+		assignments            []repository.ProductAssignmentItem
+		expected               []string
+	}{
+		{name: "user groups in preference order, then buyer, then default", defaultPriceScheduleID: "ps-default", party: party, assignments: assignments, expected: []string{"ps-vip", "ps-wholesale", "ps-buyer", "ps-default"}},
+		{name: "buyer without user groups", defaultPriceScheduleID: "ps-default", party: PriceParty{BuyerID: "zp-buyer"}, assignments: assignments, expected: []string{"ps-buyer", "ps-default"}},
+		{name: "default when nothing is assigned", defaultPriceScheduleID: "ps-default", party: party, expected: []string{"ps-default"}},
+		{name: "default assigned to the buyer is tried once", defaultPriceScheduleID: "ps-buyer", party: PriceParty{BuyerID: "zp-buyer"}, assignments: assignments, expected: []string{"ps-buyer"}},
+		{name: "no default", party: PriceParty{BuyerID: "other-buyer"}, assignments: assignments, expected: []string{"ps-other"}},
+		{name: "nothing to try", party: PriceParty{}, expected: []string{}},
+	}
+
+	for _, test := range tests {
+		result := priceScheduleCandidates(test.defaultPriceScheduleID, test.party, test.assignments)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("test failed: %s, expected %v, got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestResolvePriceSchedules(t *testing.T) {
+
+	//data test
+	var accessToken = uuid.New().String()
+	var party = PriceParty{BuyerID: "zp-buyer", UserGroupIDs: []string{"zp-wholesale"}}
+	var ids = []string{"ps-buyer", "ps-default", "ps-default2", "ps-wholesale", "ps-sgd"}
+	var myr, sgd = "MYR", "SGD"
+
+	var priceScheduleRepositoryMock = &repository.PriceScheduleRepositoryMock{}
+
+	priceScheduleRepositoryMock.On("GetProductAssignments", []string{"p1", "p2", "p3"}, party.BuyerID, accessToken).Return([]repository.ProductAssignmentItem{
+		{ProductID: "p1", BuyerID: "zp-buyer", PriceScheduleID: "ps-buyer"},
+		{ProductID: "p1", BuyerID: "zp-buyer", UserGroupID: "zp-wholesale", PriceScheduleID: "ps-wholesale"},
+		{ProductID: "p2", BuyerID: "zp-buyer", UserGroupID: "zp-wholesale", PriceScheduleID: "ps-sgd"},
+		{ProductID: "p2", BuyerID: "zp-buyer", UserGroupID: "zp-wholesale"},
+		{ProductID: "other", BuyerID: "zp-buyer", PriceScheduleID: "ps-other"},
+	}, nil)
+
+	priceScheduleRepositoryMock.On("GetPriceSchedules", repository.PriceScheduleParams{
+		ExtraFilters: map[string]interface{}{"ID": "ps-buyer|ps-default|ps-default2|ps-sgd|ps-wholesale"},
+		Page:         "1",
+		PageSize:     "5",
+	}, accessToken).Return(model.PriceScheduleResponse{
+		Items: []*model.PriceScheduleItem{
+			{ID: &ids[0], Currency: &myr},
+			{ID: &ids[1]},
+			{ID: &ids[2], Currency: &myr},
+			{ID: &ids[3], Currency: &myr},
+			{ID: &ids[4], Currency: &sgd},
+		},
+	}, nil)
+
+	svc := &PriceScheduleService{priceScheduleRepo: priceScheduleRepositoryMock}
+
+	result, err := svc.ResolvePriceSchedules(map[string]string{"p1": "ps-default", "p2": "ps-default2", "p3": ""}, "MYR", party, accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	if GetString(result["p1"].ID) != "ps-wholesale" {
+		t.Error("test failed: user group assignment must come before buyer assignment and default")
+	}
+	if GetString(result["p2"].ID) != "ps-default2" {
+		t.Error("test failed: assignment in another currency must fall back to the default")
+	}
+	if _, ok := result["p3"]; ok || len(result) != 2 {
+		t.Error("test failed: product without a price schedule must be left out")
+	}
+
+	priceScheduleRepositoryMock.AssertExpectations(t)
+}
+
+func TestGetPriceScheduleWithoutBuyer(t *testing.T) {
+
+	//data test
+	var accessToken = uuid.New().String()
+	var priceScheduleID = "ps-default"
+
+	var priceScheduleRepositoryMock = &repository.PriceScheduleRepositoryMock{}
+
+	priceScheduleRepositoryMock.On("GetPriceSchedules", repository.PriceScheduleParams{
+		ExtraFilters: map[string]interface{}{"ID": priceScheduleID},
+		Page:         "1",
+		PageSize:     "1",
+	}, accessToken).Return(model.PriceScheduleResponse{
+		Items: []*model.PriceScheduleItem{{ID: &priceScheduleID}},
+	}, nil)
+
+	svc := &PriceScheduleService{priceScheduleRepo: priceScheduleRepositoryMock}
+
+	result, err := svc.GetPriceSchedule("p1", priceScheduleID, "MYR", PriceParty{}, accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+	if len(result.Items) != 1 || GetString(result.Items[0].ID) != priceScheduleID || *result.Meta.TotalCount != 1 {
+		t.Error("test failed: a party without a buyer must be priced at the default price schedule only")
+	}
+
+	// assignments are not fetched without a buyer
+	priceScheduleRepositoryMock.AssertExpectations(t)
+	priceScheduleRepositoryMock.AssertNotCalled(t, "GetProductAssignments", []string{"p1"}, "", accessToken)
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// MaxProductListNameLength is the longest name a product list can have
+	MaxProductListNameLength = 100
+
+	// MaxProductListItemNotesLength is the longest notes a product list item can have
+	MaxProductListItemNotesLength = 500
+
+	// shareTokenBytes is the number of random bytes in a product list share token
+	shareTokenBytes = 24
+)
+
+var (
+	// ErrProductListNotFound is returned when the list does not exist or is not visible to the user
+	ErrProductListNotFound = errors.New("product list not found")
+
+	// ErrProductListItemNotFound is returned when the product is not in the list
+	ErrProductListItemNotFound = errors.New("product list item not found")
+
+	// ErrInvalidProductListName is returned when a list name is empty or too long
+	ErrInvalidProductListName = fmt.Errorf("product list name must be between 1 and %d characters", MaxProductListNameLength)
+
+	// ErrInvalidProductListItem is returned when an item quantity is not positive or its notes are too long
+	ErrInvalidProductListItem = fmt.Errorf("product list item quantity must be positive and notes at most %d characters", MaxProductListItemNotesLength)
+
+	// ErrBuyerRequired is returned when sharing a list without belonging to a buyer organization
+	ErrBuyerRequired = errors.New("product lists can only be shared within a buyer organization")
+)
+
+// ProductListService manages named product lists of a user. A shared list can be viewed and copied
+// by users of the same buyer organization as its owner
+type ProductListService struct {
+	productListRepo *repository.ProductListRepository
+	productService  *ProductService
+}
+
+func NewProductListService(db *sqlx.DB, dbConfig config.DBConfig, productService *ProductService) *ProductListService {
+	return &ProductListService{
+		productListRepo: repository.NewProductListRepository(db, dbConfig),
+		productService:  productService,
+	}
+}
+
+func (svc *ProductListService) GetProductLists(userID *string, market config.MarketConfig, party PriceParty, accessToken string) ([]*model.ProductList, error) {
+	records, err := svc.productListRepo.GetProductLists(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.toProductLists(records, userID, market, party, accessToken)
+}
+
+// GetProductList returns one of the user's lists, or nil when the user has no such list
+func (svc *ProductListService) GetProductList(userID *string, id int, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	record, err := svc.getOwnProductList(userID, id)
+	if errors.Is(err, ErrProductListNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.toProductList(record, userID, market, party, accessToken)
+}
+
+// GetSharedProductList returns the list shared with shareToken when the user may view it, or nil otherwise
+func (svc *ProductListService) GetSharedProductList(userID, buyerID *string, shareToken string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	record, err := svc.getSharedProductList(userID, buyerID, shareToken)
+	if errors.Is(err, ErrProductListNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.toProductList(record, userID, market, party, accessToken)
+}
+
+func (svc *ProductListService) CreateProductList(userID, buyerID *string, name string, description *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	name, err := validateProductListName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := svc.productListRepo.CreateProductList(userID, buyerID, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.getUpdatedProductList(userID, id, market, party, accessToken)
+}
+
+// UpdateProductList renames the list or changes its description, nil values are left unchanged
+func (svc *ProductListService) UpdateProductList(userID *string, id int, name, description *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	record, err := svc.getOwnProductList(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	newName := record.Name
+	if name != nil {
+		newName, err = validateProductListName(*name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newDescription := record.Description
+	if description != nil {
+		newDescription = description
+	}
+
+	err = svc.productListRepo.UpdateProductList(id, newName, newDescription)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.getUpdatedProductList(userID, id, market, party, accessToken)
+}
+
+func (svc *ProductListService) DeleteProductList(userID *string, id int) (bool, error) {
+	_, err := svc.getOwnProductList(userID, id)
+	if err != nil {
+		return false, err
+	}
+
+	err = svc.productListRepo.DeleteProductList(id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AddProductListItem adds a product at the end of the list, adding a product already in the list updates it instead.
+// Only products that exist and are active in order cloud can be added
+func (svc *ProductListService) AddProductListItem(userID *string, listID int, productID string, quantity *int, notes *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	quantityVal := 1
+	if quantity != nil {
+		quantityVal = *quantity
+	}
+	if err := validateProductListItem(&quantityVal, notes); err != nil {
+		return nil, err
+	}
+
+	_, err := svc.getOwnProductList(userID, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	// check product in order cloud
+	productsByID, err := svc.productService.getProductsByIDs([]string{productID}, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := productsByID[productID]; !ok {
+		return nil, ErrProductNotFound
+	}
+
+	err = svc.productListRepo.SaveProductListItem(listID, productID, quantityVal, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.getUpdatedProductList(userID, listID, market, party, accessToken)
+}
+
+// UpdateProductListItem changes the quantity or notes of a product in the list, nil values are left unchanged
+func (svc *ProductListService) UpdateProductListItem(userID *string, listID int, productID string, quantity *int, notes *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	if err := validateProductListItem(quantity, notes); err != nil {
+		return nil, err
+	}
+
+	_, err := svc.getOwnProductList(userID, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := svc.productListRepo.UpdateProductListItem(listID, productID, quantity, notes)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrProductListItemNotFound
+	}
+
+	return svc.getUpdatedProductList(userID, listID, market, party, accessToken)
+}
+
+func (svc *ProductListService) RemoveProductListItem(userID *string, listID int, productID string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	_, err := svc.getOwnProductList(userID, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := svc.productListRepo.DeleteProductListItem(listID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrProductListItemNotFound
+	}
+
+	return svc.getUpdatedProductList(userID, listID, market, party, accessToken)
+}
+
+// ReorderProductListItems moves the given products to the top of the list in the given order,
+// the remaining products keep their order after them
+func (svc *ProductListService) ReorderProductListItems(userID *string, listID int, productIDs []string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	_, err := svc.getOwnProductList(userID, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := svc.productListRepo.GetProductListItems([]int{listID})
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.productListRepo.UpdateProductListItemPositions(listID, orderProductListItems(items, productIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.getUpdatedProductList(userID, listID, market, party, accessToken)
+}
+
+// ShareProductList makes the list viewable with a share token by users of the owner's buyer organization.
+// Sharing an already shared list keeps its token
+func (svc *ProductListService) ShareProductList(userID *string, id int, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	record, err := svc.getOwnProductList(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.BuyerID == nil || *record.BuyerID == "" {
+		return nil, ErrBuyerRequired
+	}
+
+	if record.ShareToken == nil {
+		shareToken, err := newShareToken()
+		if err != nil {
+			return nil, err
+		}
+
+		err = svc.productListRepo.SetProductListShareToken(id, &shareToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.getUpdatedProductList(userID, id, market, party, accessToken)
+}
+
+// UnshareProductList revokes the list's share token
+func (svc *ProductListService) UnshareProductList(userID *string, id int, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	_, err := svc.getOwnProductList(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.productListRepo.SetProductListShareToken(id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.getUpdatedProductList(userID, id, market, party, accessToken)
+}
+
+// CopyProductList copies a list shared with shareToken, along with its items, to the user's own lists
+func (svc *ProductListService) CopyProductList(userID, buyerID *string, shareToken string, name *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	record, err := svc.getSharedProductList(userID, buyerID, shareToken)
+	if err != nil {
+		return nil, err
+	}
+
+	copyName := record.Name
+	if name != nil {
+		copyName = *name
+	}
+	copyName, err = validateProductListName(copyName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := svc.productListRepo.CopyProductList(record.ID, userID, buyerID, copyName)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.getUpdatedProductList(userID, id, market, party, accessToken)
+}
+
+func (svc *ProductListService) getOwnProductList(userID *string, id int) (*repository.ProductListRecord, error) {
+	record, err := svc.productListRepo.GetProductList(id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.UserID != GetString(userID) {
+		return nil, ErrProductListNotFound
+	}
+
+	return record, nil
+}
+
+// getSharedProductList returns the list shared with shareToken, visible to its owner and users of the owner's buyer organization
+func (svc *ProductListService) getSharedProductList(userID, buyerID *string, shareToken string) (*repository.ProductListRecord, error) {
+	if shareToken == "" {
+		return nil, ErrProductListNotFound
+	}
+
+	record, err := svc.productListRepo.GetProductListByShareToken(shareToken)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !canViewProductList(record, userID, buyerID) {
+		return nil, ErrProductListNotFound
+	}
+
+	return record, nil
+}
+
+func (svc *ProductListService) getUpdatedProductList(userID *string, id int, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	record, err := svc.getOwnProductList(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.toProductList(record, userID, market, party, accessToken)
+}
+
+func (svc *ProductListService) toProductList(record *repository.ProductListRecord, userID *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.ProductList, error) {
+	productLists, err := svc.toProductLists([]*repository.ProductListRecord{record}, userID, market, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return productLists[0], nil
+}
+
+// toProductLists adds items to lists and hydrates their products with price schedules from order cloud.
+// Items of products that are inactive or no longer exist have no product
+func (svc *ProductListService) toProductLists(records []*repository.ProductListRecord, userID *string, market config.MarketConfig, party PriceParty, accessToken string) ([]*model.ProductList, error) {
+	productLists := make([]*model.ProductList, len(records))
+	listIndex := make(map[int]int)
+	listIDs := make([]int, len(records))
+	for i, record := range records {
+		productList := record.ProductList
+		productList.IsOwner = record.UserID == GetString(userID)
+		productList.Items = []*model.ProductListItem{}
+		// the share token is only revealed to the owner
+		if !productList.IsOwner {
+			productList.ShareToken = nil
+		}
+
+		productLists[i] = &productList
+		listIndex[record.ID] = i
+		listIDs[i] = record.ID
+	}
+
+	// get list items from database
+	items, err := svc.productListRepo.GetProductListItems(listIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var productIDs []string
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	// get products from order cloud
+	productsByID, err := svc.productService.getProductsByIDs(uniqueStrings(productIDs), market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.productService.addPriceSchedules(productsByID, market.Currency, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		item.Product = productsByID[item.ProductID]
+		productList := productLists[listIndex[item.ListID]]
+		productList.Items = append(productList.Items, item)
+	}
+
+	return productLists, nil
+}
+
+func canViewProductList(record *repository.ProductListRecord, userID, buyerID *string) bool {
+	if record.UserID == GetString(userID) {
+		return true
+	}
+
+	return record.BuyerID != nil && *record.BuyerID != "" && *record.BuyerID == GetString(buyerID)
+}
+
+// orderProductListItems returns the list's product ids with productIDs first in the given order, followed by
+// the remaining products in their current order. Ids not in the list are ignored
+func orderProductListItems(items []*model.ProductListItem, productIDs []string) []string {
+	inList := make(map[string]bool)
+	for _, item := range items {
+		inList[item.ProductID] = true
+	}
+
+	ordered := make([]string, 0, len(items))
+	placed := make(map[string]bool)
+	for _, productID := range productIDs {
+		if inList[productID] && !placed[productID] {
+			placed[productID] = true
+			ordered = append(ordered, productID)
+		}
+	}
+
+	for _, item := range items {
+		if !placed[item.ProductID] {
+			placed[item.ProductID] = true
+			ordered = append(ordered, item.ProductID)
+		}
+	}
+
+	return ordered
+}
+
+func validateProductListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > MaxProductListNameLength {
+		return "", ErrInvalidProductListName
+	}
+
+	return name, nil
+}
+
+func validateProductListItem(quantity *int, notes *string) error {
+	if quantity != nil && *quantity <= 0 {
+		return ErrInvalidProductListItem
+	}
+	if notes != nil && len([]rune(*notes)) > MaxProductListItemNotesLength {
+		return ErrInvalidProductListItem
+	}
+
+	return nil
+}
+
+func newShareToken() (string, error) {
+	token := make([]byte, shareTokenBytes)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
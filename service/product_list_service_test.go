@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+func TestOrderProductListItems(t *testing.T) {
+
+	//data test
+
+	items := []*model.ProductListItem{
+		{ProductID: "a", Position: 0},
+		{ProductID: "b", Position: 1},
+		{ProductID: "c", Position: 2},
+		{ProductID: "d", Position: 3},
+	}
+
+	tests := []struct {
+		name       string
+		productIDs []string
+		expected   []string
+	}{
+		{name: "full order", productIDs: []string{"d", "c", "b", "a"}, expected: []string{"d", "c", "b", "a"}},
+		{name: "partial order keeps the rest", productIDs: []string{"c", "a"}, expected: []string{"c", "a", "b", "d"}},
+		{name: "unknown and repeated ids are ignored", productIDs: []string{"x", "b", "b"}, expected: []string{"b", "a", "c", "d"}},
+		{name: "no order", productIDs: nil, expected: []string{"a", "b", "c", "d"}},
+	}
+
+	for _, test := range tests {
+		result := orderProductListItems(items, test.productIDs)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("test failed %s, expected %v, got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestCanViewProductList(t *testing.T) {
+
+	//data test
+
+	var ownerID = "1"
+	var otherUserID = "2"
+	var buyerID = "pharmacy"
+	var otherBuyerID = "clinic"
+
+	record := &repository.ProductListRecord{UserID: ownerID, BuyerID: &buyerID}
+
+	if !canViewProductList(record, &ownerID, nil) {
+		t.Error("test failed, owner should view the list")
+	}
+
+	if !canViewProductList(record, &otherUserID, &buyerID) {
+		t.Error("test failed, user of the same buyer should view the list")
+	}
+
+	if canViewProductList(record, &otherUserID, &otherBuyerID) {
+		t.Error("test failed, user of another buyer should not view the list")
+	}
+
+	if canViewProductList(record, &otherUserID, nil) {
+		t.Error("test failed, user without buyer should not view the list")
+	}
+
+	if canViewProductList(&repository.ProductListRecord{UserID: ownerID}, &otherUserID, nil) {
+		t.Error("test failed, list without buyer should only be viewed by its owner")
+	}
+}
+
+func TestValidateProductList(t *testing.T) {
+
+	//data test
+
+	var zero = 0
+	var longNotes = strings.Repeat("n", MaxProductListItemNotesLength+1)
+
+	name, err := validateProductListName("  Monthly restock ")
+	if err != nil || name != "Monthly restock" {
+		t.Error("test failed, name should be trimmed")
+	}
+
+	for _, invalidName := range []string{"", "   ", strings.Repeat("x", MaxProductListNameLength+1)} {
+		_, err = validateProductListName(invalidName)
+		if !errors.Is(err, ErrInvalidProductListName) {
+			t.Errorf("test failed, name %q should be invalid", invalidName)
+		}
+	}
+
+	if !errors.Is(validateProductListItem(&zero, nil), ErrInvalidProductListItem) {
+		t.Error("test failed, zero quantity should be invalid")
+	}
+
+	if !errors.Is(validateProductListItem(nil, &longNotes), ErrInvalidProductListItem) {
+		t.Error("test failed, long notes should be invalid")
+	}
+
+	if validateProductListItem(nil, nil) != nil {
+		t.Error("test failed, unchanged item should be valid")
+	}
+}
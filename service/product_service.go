@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,29 +22,91 @@ import (
 const (
 	// TrendingProductCacheKey is the key for trending products cache
 	TrendingProductCacheKey = "trending_products"
+
+	// TrendingProductCategoryGroup is the cache key segment of trending products per category
+	TrendingProductCategoryGroup = "category"
+
+	// TrendingProductSupplierGroup is the cache key segment of trending products per supplier
+	TrendingProductSupplierGroup = "supplier"
+
+	// DefaultPageSize is the page size order cloud uses when none is requested
+	DefaultPageSize = 20
+
+	// MaxFavoriteProductsPageSize is the most favorites returned by a single page
+	MaxFavoriteProductsPageSize = 100
+
+	// MaxTrendingProductsPageSize is the most trending products returned by a single page
+	MaxTrendingProductsPageSize = 100
+
+	// MaxBulkFavoriteProducts is the most products changed by a single setFavorites or syncFavorites call
+	MaxBulkFavoriteProducts = 100
+
+	// DefaultFrequentlyPurchasedLimit is the number of frequently purchased products returned when no limit is requested
+	DefaultFrequentlyPurchasedLimit = 20
+
+	// MaxFrequentlyPurchasedLimit is the most frequently purchased products returned at once
+	MaxFrequentlyPurchasedLimit = 100
+
+	// FrequentlyPurchasedLookbackDays is how far back orders count towards frequently purchased products
+	FrequentlyPurchasedLookbackDays = 365
+
+	// FrequentlyPurchasedHalfLifeDays is the age at which an order counts half as much towards a product's rank
+	FrequentlyPurchasedHalfLifeDays = 30
+
+	// ProductBatchSize is the number of products fetched from order cloud per request, keeping ID filters within URL limits
+	ProductBatchSize = 50
+
+	// MaxRecommendProducts is the most products recommended for a product, bought together and also bought
+	// products first, topped up with products of the same category
+	MaxRecommendProducts = 50
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor was not issued by this service
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrProductNotFound is returned when a product does not exist or is inactive in order cloud
+	ErrProductNotFound = errors.New("product not found")
+
+	// ErrTrendingFilterConflict is returned when trending products are requested for a category and a supplier at once
+	ErrTrendingFilterConflict = errors.New("trending products can be filtered by category or supplier, not both")
+
+	// ErrTooManyFavoriteProducts is returned when a bulk favorite change exceeds MaxBulkFavoriteProducts
+	ErrTooManyFavoriteProducts = fmt.Errorf("at most %d products can be changed at once", MaxBulkFavoriteProducts)
 )
 
 type ProductService struct {
 	cacheClient          *cache.Cache
+	facetService         *FacetService
 	priceScheduleService IPriceScheduleService
 	productRepo          repository.IProductRepository
 	categoryProductRepo  repository.ICategoryProductRepository
-	recentSearchesRepo   *repository.RecentSearchesRepository
+	recommendationRepo   repository.IRecommendationRepository
+	searchLogService     *SearchLogService
+	searchIndexService   *SearchIndexService
+	trendingConfig       config.TrendingConfig
+	similarityConfig     config.SimilarityConfig
 }
 
-func NewProductService(db *sqlx.DB, dbConfig config.DBConfig, orderConfig config.OrderCloudConfig, cacheClient *cache.Cache) *ProductService {
+func NewProductService(db *sqlx.DB, dbConfig config.DBConfig, orderConfig config.OrderCloudConfig, trendingConfig config.TrendingConfig, similarityConfig config.SimilarityConfig, cacheClient *cache.Cache, searchIndexService *SearchIndexService, searchLogService *SearchLogService) *ProductService {
 	return &ProductService{
+		facetService:         NewFacetService(orderConfig, cacheClient),
 		priceScheduleService: NewPriceScheduleService(),
 		productRepo:          repository.NewProductRepository(db, dbConfig, orderConfig),
 		categoryProductRepo:  repository.NewCategoryProductRepository(orderConfig),
+		recommendationRepo:   repository.NewRecommendationRepository(db, dbConfig),
 		cacheClient:          cacheClient,
-		recentSearchesRepo:   repository.NewRecentSearchesRepository(db, dbConfig),
+		searchLogService:     searchLogService,
+		searchIndexService:   searchIndexService,
+		trendingConfig:       trendingConfig,
+		similarityConfig:     similarityConfig,
 	}
 }
 
-func (svc *ProductService) GetProducts(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search *string, isFavorite *bool, extraFilters map[string]interface{}, accessToken string) (model.ProductResponse, error) {
+func (svc *ProductService) GetProducts(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search *string, isFavorite *bool, extraFilters map[string]interface{}, market config.MarketConfig, party PriceParty, accessToken string) (model.ProductResponse, error) {
+	searchStartedAt := time.Now()
 	params := repository.ProductParams{
-		CatalogID:    GetString(catalogID),
+		CatalogID:    marketCatalogID(catalogID, market),
 		CategoryID:   GetString(categoryID),
 		SupplierID:   GetString(supplierID),
 		Page:         GetString(page),
@@ -75,20 +140,35 @@ func (svc *ProductService) GetProducts(catalogID, categoryID, supplierID, userID
 	if search != nil {
 		params.Search = *search
 		params.SearchOn = "ID,Name,Description"
-
-		// insert search details to database
-		_, err = svc.recentSearchesRepo.SaveRecentSearch(userID, search)
-		if err != nil {
-			return model.ProductResponse{}, err
-		}
 	}
 
-	// get products from order cloud
-	products, err := svc.productRepo.GetProducts(params, accessToken)
+	// get products from order cloud, ranked by the search index when enabled
+	var products model.ProductResponse
+	if search != nil && svc.searchIndexService.Enabled(params.CatalogID) {
+		products, err = svc.getIndexedSearchProducts(params, accessToken)
+	} else {
+		products, err = svc.productRepo.GetProducts(params, accessToken)
+	}
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
 
+	// keep facets configured for the catalog
+	if products.Meta != nil {
+		products.Meta.Facets = svc.getCatalogFacets(params, products.Meta.Facets, accessToken)
+	}
+
+	// queue search details for the database, later pages of the same search are not recorded again
+	if search != nil && isFirstPage(page) {
+		if products.Meta == nil {
+			products.Meta = &model.ProductMeta{}
+		}
+		searchID := svc.searchLogService.LogSearch(newSearchEvent(userID, search, isFavorite, params, products.Meta, len(products.Items), time.Since(searchStartedAt)))
+		if searchID != "" {
+			products.Meta.SearchID = &searchID
+		}
+	}
+
 	// add isFavorite field to products
 	for i, product := range products.Items {
 		for _, userProductFavorite := range userProductFavorites {
@@ -98,91 +178,186 @@ func (svc *ProductService) GetProducts(catalogID, categoryID, supplierID, userID
 		}
 	}
 
-	// prepare product ids
-	productIDs := make([]string, len(products.Items))
-	for i, product := range products.Items {
-		productIDs[i] = GetString(product.ID)
+	// get price schedules of the products for the party
+	defaultPriceScheduleIDs := make(map[string]string, len(products.Items))
+	for _, product := range products.Items {
+		defaultPriceScheduleIDs[GetString(product.ID)] = GetString(product.DefaultPriceScheduleID)
 	}
 
-	// get price schedules
-	priceSchedules, err := svc.priceScheduleService.GetPriceSchedules(productIDs, nil, nil, accessToken)
+	priceSchedules, err := svc.priceScheduleService.ResolvePriceSchedules(defaultPriceScheduleIDs, market.Currency, party, accessToken)
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
 
 	// add price schedules to products
 	for i, product := range products.Items {
-		for _, priceSchedule := range priceSchedules.Items {
-			if GetString(product.ID) == GetString(priceSchedule.ID) {
-				products.Items[i].PriceSchedule = priceSchedule
-			}
-		}
+		products.Items[i].PriceSchedule = priceSchedules[GetString(product.ID)]
 	}
 
 	return products, nil
 }
 
-func (svc *ProductService) GetSimilarProducts(productID string, userID, page, pageSize *string, accessToken string) (model.ProductResponse, error) {
-	categoryProduct, err := svc.categoryProductRepo.GetCategoryProductByProductID(productID, accessToken)
+// GetSimilarProducts ranks products of the product's category by how many attributes they share with the product,
+// see rankSimilarProducts
+func (svc *ProductService) GetSimilarProducts(productID string, userID, page, pageSize *string, market config.MarketConfig, party PriceParty, accessToken string) (model.ProductResponse, error) {
+	categoryProduct, err := svc.categoryProductRepo.GetCategoryProductByProductID(market.CatalogID, productID, accessToken)
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
 
-	// get user favorite products from database
-	userProductFavorites, err := svc.productRepo.GetFavoriteProducts(userID, page, pageSize)
+	sourceProduct, err := svc.productRepo.GetProduct(productID, accessToken)
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
 
-	sameCategoryProducts, err := svc.productRepo.GetProducts(repository.ProductParams{
-		CategoryID: categoryProduct.CategoryID,
-		Page:       GetString(page),
-		PageSize:   GetString(pageSize),
-	}, accessToken)
-	if err != nil {
-		return model.ProductResponse{}, err
-	}
+	// candidates are fetched at once so they can be ranked across pages, those sharing a weighted attribute are
+	// fetched by that attribute so large categories are not cut off at the first products by id
+	var candidates []*model.ProductItem
+	productsByID := make(map[string]*model.ProductItem)
+	for _, extraFilters := range similarCandidateFilters(&sourceProduct, svc.similarityConfig) {
+		sameCategoryProducts, err := svc.productRepo.GetProducts(repository.ProductParams{
+			CatalogID:    market.CatalogID,
+			CategoryID:   categoryProduct.CategoryID,
+			SortBy:       "ID",
+			Page:         "1",
+			PageSize:     repository.GetStringFromInt(svc.similarityConfig.CandidateLimit),
+			ExtraFilters: extraFilters,
+		}, accessToken)
+		if err != nil {
+			return model.ProductResponse{}, err
+		}
 
-	// add isFavorite field to products
-	for i, product := range sameCategoryProducts.Items {
-		for _, userProductFavorite := range userProductFavorites {
-			if *product.ID == *userProductFavorite.ProductID {
-				sameCategoryProducts.Items[i].IsFavorite = true
+		for _, product := range sameCategoryProducts.Items {
+			if _, ok := productsByID[GetString(product.ID)]; ok || GetString(product.ID) == productID {
+				continue
 			}
+			productsByID[GetString(product.ID)] = product
+			candidates = append(candidates, product)
 		}
 	}
 
-	// prepare product ids
-	productIDs := make([]string, len(sameCategoryProducts.Items))
-	for i, product := range sameCategoryProducts.Items {
-		productIDs[i] = GetString(product.ID)
+	// add isFavorite field to products
+	err = svc.addFavorites(userID, productsByID)
+	if err != nil {
+		return model.ProductResponse{}, err
 	}
 
-	// get price schedules
-	priceSchedules, err := svc.priceScheduleService.GetPriceSchedules(productIDs, nil, nil, accessToken)
+	// price schedules of the product are needed for price bands
+	productsByID[productID] = &sourceProduct
+	err = svc.addPriceSchedules(productsByID, market.Currency, party, accessToken)
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
 
-	// add price schedules to products
-	for i, product := range sameCategoryProducts.Items {
-		for _, priceSchedule := range priceSchedules.Items {
-			if GetString(product.ID) == GetString(priceSchedule.ID) {
-				sameCategoryProducts.Items[i].PriceSchedule = priceSchedule
-			}
+	similarProducts := rankSimilarProducts(&sourceProduct, candidates, svc.similarityConfig)
+	items, meta := paginateProducts(similarProducts, GetString(page), GetString(pageSize))
+
+	return model.ProductResponse{
+		Meta:  meta,
+		Items: items,
+	}, nil
+}
+
+// GetRecommendProducts returns products bought together with or also bought by buyers of the product, topped
+// up with products of the same category. Without order history, or when it cannot be read, only same category
+// products are returned
+func (svc *ProductService) GetRecommendProducts(productID string, page, pageSize *string, market config.MarketConfig, party PriceParty, accessToken string) (model.ProductResponseV2, error) {
+	recommendations, err := svc.recommendationRepo.GetProductRecommendations(productID, MaxRecommendProducts)
+	if err != nil {
+		fmt.Printf("error occurred while fetching recommendations of product %s: %v\n", productID, err)
+	}
+
+	if err != nil || len(recommendations) == 0 {
+		return svc.getSameCategoryProducts(productID, page, pageSize, market, party, accessToken)
+	}
+
+	products, err := svc.getRecommendedProducts(productID, recommendations, market, party, accessToken)
+	if err != nil {
+		return model.ProductResponseV2{}, err
+	}
+
+	// top up with products of the same category, best effort as the product may not be categorized
+	if len(products) < MaxRecommendProducts {
+		topUpPage, topUpPageSize := "1", repository.GetStringFromInt(MaxRecommendProducts)
+		sameCategoryProducts, err := svc.getSameCategoryProducts(productID, &topUpPage, &topUpPageSize, market, party, accessToken)
+		if err != nil {
+			fmt.Println("error occurred while fetching same category products:", err)
+		} else {
+			products = mergeRecommendedProducts(products, sameCategoryProducts.Items, MaxRecommendProducts)
 		}
 	}
 
-	return sameCategoryProducts, nil
+	items, meta := paginateProducts(products, GetString(page), GetString(pageSize))
+
+	return model.ProductResponseV2{
+		Meta:  meta,
+		Items: items,
+	}, nil
+}
+
+// getRecommendedProducts fetches active recommended products with their price schedules, keeping the order of
+// recommendations. A product related by order and by user is recommended once as bought together
+func (svc *ProductService) getRecommendedProducts(productID string, recommendations []repository.ProductRecommendationItem, market config.MarketConfig, party PriceParty, accessToken string) ([]*model.LatestProductItems, error) {
+	var productIDs []string
+	reasons := make(map[string]model.RecommendationReason)
+	for _, recommendation := range recommendations {
+		if recommendation.RelatedProductID == productID {
+			continue
+		}
+		if _, ok := reasons[recommendation.RelatedProductID]; ok {
+			continue
+		}
+		reasons[recommendation.RelatedProductID] = toRecommendationReason(recommendation.Relation)
+		productIDs = append(productIDs, recommendation.RelatedProductID)
+	}
+
+	productsByID := make(map[string]*model.LatestProductItems)
+	for start := 0; start < len(productIDs); start += ProductBatchSize {
+		batch := productIDs[start:minInt(start+ProductBatchSize, len(productIDs))]
+
+		products, err := svc.productRepo.GetProductsOrderCloudV2(repository.ProductParams{
+			CatalogID: market.CatalogID,
+			ExtraFilters: map[string]interface{}{
+				"ID": strings.Join(batch, "|"),
+			},
+			Page:     "1",
+			PageSize: repository.GetStringFromInt(len(batch)),
+		}, accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, product := range products.Items {
+			productsByID[GetString(product.ID)] = product
+		}
+	}
+
+	products := make([]*model.LatestProductItems, 0, len(productsByID))
+	for _, id := range productIDs {
+		if product, ok := productsByID[id]; ok {
+			reason := reasons[id]
+			product.Reason = &reason
+			products = append(products, product)
+		}
+	}
+
+	err := svc.addLatestProductPriceSchedules(products, market, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
 }
 
-func (svc *ProductService) GetRecommendProducts(productID string, page, pageSize *string, accessToken string) (model.ProductResponseV2, error) {
-	categoryProduct, err := svc.categoryProductRepo.GetCategoryProductByProductID(productID, accessToken)
+// getSameCategoryProducts returns a page of products of the product's category excluding the product itself
+func (svc *ProductService) getSameCategoryProducts(productID string, page, pageSize *string, market config.MarketConfig, party PriceParty, accessToken string) (model.ProductResponseV2, error) {
+	categoryProduct, err := svc.categoryProductRepo.GetCategoryProductByProductID(market.CatalogID, productID, accessToken)
 	if err != nil {
 		return model.ProductResponseV2{}, err
 	}
 
 	sameCategoryProducts, err := svc.productRepo.GetProductsOrderCloudV2(repository.ProductParams{
+		CatalogID:  market.CatalogID,
 		CategoryID: categoryProduct.CategoryID,
 		Page:       GetString(page),
 		PageSize:   GetString(pageSize),
@@ -191,46 +366,57 @@ func (svc *ProductService) GetRecommendProducts(productID string, page, pageSize
 		return model.ProductResponseV2{}, err
 	}
 
-	// prepare product ids
-	productIDs := make([]string, len(sameCategoryProducts.Items))
-	for i, product := range sameCategoryProducts.Items {
-		productIDs[i] = GetString(product.ID)
+	// exclude the product itself
+	items := make([]*model.LatestProductItems, 0, len(sameCategoryProducts.Items))
+	for _, product := range sameCategoryProducts.Items {
+		if GetString(product.ID) == productID {
+			continue
+		}
+		reason := model.RecommendationReasonSameCategory
+		product.Reason = &reason
+		items = append(items, product)
 	}
+	sameCategoryProducts.Items = items
 
-	// get price schedules
-	priceSchedules, err := svc.priceScheduleService.GetPriceSchedules(productIDs, nil, nil, accessToken)
+	err = svc.addLatestProductPriceSchedules(sameCategoryProducts.Items, market, party, accessToken)
 	if err != nil {
 		return model.ProductResponseV2{}, err
 	}
 
-	// add price schedules to products
-	for i, product := range sameCategoryProducts.Items {
-		for _, priceSchedule := range priceSchedules.Items {
-			if GetString(product.ID) == GetString(priceSchedule.ID) {
-				sameCategoryProducts.Items[i].PriceSchedule = &model.NewProductPriceSchedule{
-					ID:                    priceSchedule.ID,
-					OwnerID:               priceSchedule.OwnerID,
-					Name:                  priceSchedule.Name,
-					ApplyTax:              priceSchedule.ApplyTax,
-					ApplyShipping:         priceSchedule.ApplyShipping,
-					MinQuantity:           priceSchedule.MinQuantity,
-					MaxQuantity:           priceSchedule.MaxQuantity,
-					UseCumulativeQuantity: priceSchedule.UseCumulativeQuantity,
-					RestrictedQuantity:    priceSchedule.RestrictedQuantity,
-					Currency:              priceSchedule.Currency,
-					SaleStart:             priceSchedule.SaleStart,
-					SaleEnd:               priceSchedule.SaleEnd,
-					IsOnSale:              priceSchedule.IsOnSale,
-					PriceBreaks:           priceSchedule.PriceBreaks,
-				}
-			}
+	return sameCategoryProducts, nil
+}
+
+// addLatestProductPriceSchedules adds the price schedule each product is priced at for the party, see
+// PriceScheduleService.ResolvePriceSchedules
+func (svc *ProductService) addLatestProductPriceSchedules(products []*model.LatestProductItems, market config.MarketConfig, party PriceParty, accessToken string) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	defaultPriceScheduleIDs := make(map[string]string, len(products))
+	for _, product := range products {
+		var defaultPriceScheduleID string
+		if product.Product != nil {
+			defaultPriceScheduleID = GetString(product.Product.DefaultPriceScheduleID)
 		}
+		defaultPriceScheduleIDs[GetString(product.ID)] = defaultPriceScheduleID
 	}
 
-	return sameCategoryProducts, nil
+	priceSchedules, err := svc.priceScheduleService.ResolvePriceSchedules(defaultPriceScheduleIDs, market.Currency, party, accessToken)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		if priceSchedule, ok := priceSchedules[GetString(product.ID)]; ok {
+			product.PriceSchedule = toNewProductPriceSchedule(priceSchedule)
+		}
+	}
+
+	return nil
 }
 
-func (svc *ProductService) GetProduct(productID string, userID *string, accessToken string) (model.ProductItem, error) {
+func (svc *ProductService) GetProduct(productID string, userID *string, market config.MarketConfig, party PriceParty, accessToken string) (model.ProductItem, error) {
 	// get user favorite products from database
 	userProductFavorites, err := svc.productRepo.GetFavoriteProducts(userID, nil, nil)
 	if err != nil {
@@ -249,73 +435,481 @@ func (svc *ProductService) GetProduct(productID string, userID *string, accessTo
 		}
 	}
 
-	// get price schedules
-	priceSchedules, err := svc.priceScheduleService.GetPriceSchedules([]string{productID}, nil, nil, accessToken)
+	// add the price schedule of the product for the party
+	err = svc.addPriceSchedules(map[string]*model.ProductItem{productID: &product}, market.Currency, party, accessToken)
 	if err != nil {
 		return model.ProductItem{}, err
 	}
 
-	// add price schedules to products
-	for _, priceSchedule := range priceSchedules.Items {
-		if GetString(product.ID) == GetString(priceSchedule.ID) {
-			product.PriceSchedule = priceSchedule
+	return product, nil
+}
+
+// GetProductPriceSchedules returns the price schedules that can price the product for the party in the market's
+// currency, the one it is priced at first, see PriceScheduleService.ResolvePriceSchedules
+func (svc *ProductService) GetProductPriceSchedules(productID string, market config.MarketConfig, party PriceParty, accessToken string) (model.PriceScheduleResponse, error) {
+	product, err := svc.productRepo.GetProduct(productID, accessToken)
+	if err != nil {
+		return model.PriceScheduleResponse{}, err
+	}
+
+	return svc.priceScheduleService.GetPriceSchedule(productID, GetString(product.DefaultPriceScheduleID), market.Currency, party, accessToken)
+}
+
+// FavoriteProduct adds or removes a product from the user's favorites, both are idempotent.
+// Only products that exist and are active in order cloud can be favorited
+func (svc *ProductService) FavoriteProduct(userID *string, productID string, isFavorite bool, market config.MarketConfig, accessToken string) (*model.FavoriteProductPayload, error) {
+	if !isFavorite {
+		err := svc.productRepo.DeleteFavoriteProduct(userID, productID)
+		if err != nil {
+			return nil, err
 		}
+
+		return &model.FavoriteProductPayload{ProductID: productID, IsFavorite: false}, nil
 	}
 
-	return product, nil
+	// check product in order cloud
+	productsByID, err := svc.getProductsByIDs([]string{productID}, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := productsByID[productID]; !ok {
+		return nil, ErrProductNotFound
+	}
+
+	userProductFavourite, err := svc.productRepo.SaveFavoriteProduct(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.FavoriteProductPayload{ProductID: productID, IsFavorite: true, Favorite: userProductFavourite}, nil
 }
 
-func (svc *ProductService) FavoriteProduct(userID *string, productID string, isFavorite bool) (*model.UserProductFavorite, error) {
-	if isFavorite {
-		userProductFavourite, err := svc.productRepo.SaveFavoriteProduct(userID, productID)
+// SetFavorites adds or removes several products from the user's favorites at once. Products that
+// cannot be favorited because they are inactive or do not exist are rejected, the rest are still applied
+func (svc *ProductService) SetFavorites(userID *string, productIDs []string, isFavorite bool, market config.MarketConfig, accessToken string) (*model.SetFavoritesPayload, error) {
+	productIDs = uniqueStrings(productIDs)
+	if len(productIDs) > MaxBulkFavoriteProducts {
+		return nil, ErrTooManyFavoriteProducts
+	}
+
+	payload := &model.SetFavoritesPayload{
+		Results:            []*model.FavoriteProductPayload{},
+		RejectedProductIDs: []string{},
+	}
+
+	if !isFavorite {
+		err := svc.productRepo.DeleteFavoriteProducts(userID, productIDs)
 		if err != nil {
 			return nil, err
 		}
 
-		return userProductFavourite, nil
-	} else {
-		err := svc.productRepo.DeleteFavoriteProduct(userID, productID)
+		for _, productID := range productIDs {
+			payload.Results = append(payload.Results, &model.FavoriteProductPayload{ProductID: productID, IsFavorite: false})
+		}
+		return payload, nil
+	}
+
+	// check products in order cloud
+	productsByID, err := svc.getProductsByIDs(productIDs, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var items []repository.FavoriteProductBatchItem
+	for _, productID := range productIDs {
+		if _, ok := productsByID[productID]; !ok {
+			payload.RejectedProductIDs = append(payload.RejectedProductIDs, productID)
+			continue
+		}
+		items = append(items, repository.FavoriteProductBatchItem{ProductID: productID, CreatedAt: now})
+	}
+
+	userProductFavorites, err := svc.productRepo.SaveFavoriteProducts(userID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	favoritesByProductID := make(map[string]*model.UserProductFavorite)
+	for i := range userProductFavorites {
+		favoritesByProductID[GetString(userProductFavorites[i].ProductID)] = &userProductFavorites[i]
+	}
+
+	for _, item := range items {
+		payload.Results = append(payload.Results, &model.FavoriteProductPayload{
+			ProductID:  item.ProductID,
+			IsFavorite: true,
+			Favorite:   favoritesByProductID[item.ProductID],
+		})
+	}
+
+	return payload, nil
+}
+
+// SyncFavorites applies favorite changes queued by an offline client and returns the user's favorites
+// afterwards. Only the latest change of every product counts, and a removal made before the product
+// was favorited again on another device is ignored
+func (svc *ProductService) SyncFavorites(userID *string, changes []*model.FavoriteChangeInput, market config.MarketConfig, accessToken string) (*model.SyncFavoritesPayload, error) {
+	changes = latestFavoriteChanges(changes)
+	if len(changes) > MaxBulkFavoriteProducts {
+		return nil, ErrTooManyFavoriteProducts
+	}
+
+	// get user favorite products from database
+	userProductFavorites, err := svc.productRepo.GetFavoriteProducts(userID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	items, removedProductIDs := planFavoriteSync(userProductFavorites, changes, now)
+
+	// check added products in order cloud
+	addedProductIDs := make([]string, len(items))
+	for i, item := range items {
+		addedProductIDs[i] = item.ProductID
+	}
+
+	productsByID, err := svc.getProductsByIDs(addedProductIDs, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectedProductIDs := []string{}
+	var validItems []repository.FavoriteProductBatchItem
+	for _, item := range items {
+		if _, ok := productsByID[item.ProductID]; !ok {
+			rejectedProductIDs = append(rejectedProductIDs, item.ProductID)
+			continue
+		}
+		validItems = append(validItems, item)
+	}
+
+	err = svc.productRepo.DeleteFavoriteProducts(userID, removedProductIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.productRepo.SaveFavoriteProducts(userID, validItems)
+	if err != nil {
+		return nil, err
+	}
+
+	// return the resulting favorites so the client can replace its local copy
+	userProductFavorites, err = svc.productRepo.GetFavoriteProducts(userID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	favoriteProductIDs := []string{}
+	for _, userProductFavorite := range userProductFavorites {
+		favoriteProductIDs = append(favoriteProductIDs, GetString(userProductFavorite.ProductID))
+	}
+
+	return &model.SyncFavoritesPayload{
+		FavoriteProductIDs: favoriteProductIDs,
+		RejectedProductIDs: rejectedProductIDs,
+		SyncedAt:           now,
+	}, nil
+}
+
+// GetFavoriteProducts returns a page of the user's favorites hydrated from order cloud. Favorites whose products
+// are inactive or no longer exist are reported in UnavailableProductIDs instead of being returned as edges
+func (svc *ProductService) GetFavoriteProducts(userID *string, first *int, after *string, sortBy *model.FavoriteProductSortBy, market config.MarketConfig, party PriceParty, accessToken string) (*model.FavoriteProductConnection, error) {
+	limit := DefaultPageSize
+	if first != nil && *first > 0 {
+		limit = *first
+	}
+	if limit > MaxFavoriteProductsPageSize {
+		limit = MaxFavoriteProductsPageSize
+	}
+
+	var afterCursor *repository.FavoriteProductCursor
+	if after != nil && *after != "" {
+		cursor, err := decodeFavoriteProductCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		afterCursor = &cursor
+	}
+
+	oldestFirst := sortBy != nil && *sortBy == model.FavoriteProductSortByOldest
+
+	// get one favorite more than requested from database to know whether another page follows
+	userProductFavorites, err := svc.productRepo.GetFavoriteProductsAfter(userID, afterCursor, limit+1, oldestFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	hasNextPage := len(userProductFavorites) > limit
+	if hasNextPage {
+		userProductFavorites = userProductFavorites[:limit]
+	}
+
+	totalCount, err := svc.productRepo.CountFavoriteProducts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// get favorite products from order cloud
+	productIDs := make([]string, 0, len(userProductFavorites))
+	for _, userProductFavorite := range userProductFavorites {
+		productIDs = append(productIDs, GetString(userProductFavorite.ProductID))
+	}
+
+	productsByID, err := svc.getProductsByIDs(productIDs, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.addPriceSchedules(productsByID, market.Currency, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.FavoriteProductConnection{
+		Edges:                 []*model.FavoriteProductEdge{},
+		PageInfo:              &model.PageInfo{HasNextPage: hasNextPage},
+		TotalCount:            totalCount,
+		UnavailableProductIDs: []string{},
+	}
+	for _, userProductFavorite := range userProductFavorites {
+		cursor := encodeFavoriteProductCursor(userProductFavorite)
+		connection.PageInfo.EndCursor = &cursor
+
+		product, ok := productsByID[GetString(userProductFavorite.ProductID)]
+		if !ok {
+			connection.UnavailableProductIDs = append(connection.UnavailableProductIDs, GetString(userProductFavorite.ProductID))
+			continue
+		}
+		product.IsFavorite = true
+
+		connection.Edges = append(connection.Edges, &model.FavoriteProductEdge{
+			Cursor:      cursor,
+			FavoritedAt: userProductFavorite.CreatedAt,
+			Product:     product,
+		})
+	}
+
+	return connection, nil
+}
+
+// getProductsByIDs fetches active products of the catalog from order cloud in batches, keyed by id.
+// Products that are inactive or do not exist are missing from the result
+func (svc *ProductService) getProductsByIDs(productIDs []string, catalogID string, accessToken string) (map[string]*model.ProductItem, error) {
+	productsByID := make(map[string]*model.ProductItem)
+
+	for start := 0; start < len(productIDs); start += ProductBatchSize {
+		batch := productIDs[start:minInt(start+ProductBatchSize, len(productIDs))]
+
+		products, err := svc.productRepo.GetProducts(repository.ProductParams{
+			CatalogID: catalogID,
+			ExtraFilters: map[string]interface{}{
+				"ID": strings.Join(batch, "|"),
+			},
+			Page:     "1",
+			PageSize: repository.GetStringFromInt(len(batch)),
+		}, accessToken)
 		if err != nil {
 			return nil, err
 		}
 
-		return nil, nil
+		for _, product := range products.Items {
+			productsByID[GetString(product.ID)] = product
+		}
 	}
+
+	return productsByID, nil
 }
 
-func (svc *ProductService) GetTrendingProducts(accessToken string) (model.ProductResponse, error) {
-	cacheResp, err := svc.cacheClient.Get(TrendingProductCacheKey)
-	if err != nil || cacheResp == nil {
-		return model.ProductResponse{}, err
+// addPriceSchedules adds the price schedule each product is priced at for the party in the currency, see
+// PriceScheduleService.ResolvePriceSchedules
+func (svc *ProductService) addPriceSchedules(productsByID map[string]*model.ProductItem, currency string, party PriceParty, accessToken string) error {
+	if len(productsByID) == 0 {
+		return nil
+	}
+
+	defaultPriceScheduleIDs := make(map[string]string, len(productsByID))
+	for productID, product := range productsByID {
+		defaultPriceScheduleIDs[productID] = GetString(product.DefaultPriceScheduleID)
+	}
+
+	priceSchedules, err := svc.priceScheduleService.ResolvePriceSchedules(defaultPriceScheduleIDs, currency, party, accessToken)
+	if err != nil {
+		return err
+	}
+
+	for productID, product := range productsByID {
+		product.PriceSchedule = priceSchedules[productID]
+	}
+
+	return nil
+}
+
+// GetFrequentlyPurchasedProducts returns the products the user orders most often and most recently, with their
+// last ordered quantity and current price and stock. Products no longer available are left out
+func (svc *ProductService) GetFrequentlyPurchasedProducts(userID *string, first *int, market config.MarketConfig, party PriceParty, accessToken string) ([]*model.FrequentlyPurchasedProduct, error) {
+	limit := DefaultFrequentlyPurchasedLimit
+	if first != nil && *first > 0 {
+		limit = *first
+	}
+	if limit > MaxFrequentlyPurchasedLimit {
+		limit = MaxFrequentlyPurchasedLimit
+	}
+
+	// get user's order history from database
+	since := time.Now().UTC().AddDate(0, 0, -FrequentlyPurchasedLookbackDays)
+	purchasedProducts, err := svc.productRepo.GetFrequentlyPurchasedProducts(userID, since, FrequentlyPurchasedHalfLifeDays, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	// unmarshal trending products to trending product struct
+	productIDs := make([]string, len(purchasedProducts))
+	for i, purchasedProduct := range purchasedProducts {
+		productIDs[i] = purchasedProduct.ProductID
+	}
+
+	// get current products with price schedules from order cloud
+	productsByID, err := svc.getProductsByIDs(productIDs, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.addPriceSchedules(productsByID, market.Currency, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*model.FrequentlyPurchasedProduct{}
+	for _, purchasedProduct := range purchasedProducts {
+		product, ok := productsByID[purchasedProduct.ProductID]
+		if !ok {
+			continue
+		}
+		purchasedProduct.Product = product
+		result = append(result, purchasedProduct)
+	}
+
+	return result, nil
+}
+
+// GetTrendingProducts returns a page of trending products in rank order, of all products or of a category or
+// supplier when given. Products no longer available are left out of the page without shifting later pages
+func (svc *ProductService) GetTrendingProducts(categoryID, supplierID, userID *string, first *int, after *string, market config.MarketConfig, party PriceParty, accessToken string) (*model.TrendingProductConnection, error) {
+	if GetString(categoryID) != "" && GetString(supplierID) != "" {
+		return nil, ErrTrendingFilterConflict
+	}
+
+	limit := DefaultPageSize
+	if first != nil && *first > 0 {
+		limit = minInt(*first, MaxTrendingProductsPageSize)
+	}
+
+	offset := 0
+	if after != nil && *after != "" {
+		cursorOffset, err := decodeOffsetCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		offset = cursorOffset + 1
+	}
+
+	cacheKey := TrendingProductCacheKey
+	if GetString(categoryID) != "" {
+		cacheKey = trendingProductGroupCacheKey(TrendingProductCategoryGroup, *categoryID)
+	} else if GetString(supplierID) != "" {
+		cacheKey = trendingProductGroupCacheKey(TrendingProductSupplierGroup, *supplierID)
+	}
+
+	// get ranked trending products from cache
 	var trendingProducts []model.TrendingProduct
-	err = mapstructure.Decode(cacheResp, &trendingProducts)
+	cacheResp, err := svc.cacheClient.Get(cacheKey)
 	if err != nil {
-		return model.ProductResponse{}, err
+		return nil, err
+	}
+	if cacheResp != nil {
+		err = mapstructure.Decode(cacheResp, &trendingProducts)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// get trending product ids
-	trendingProductIDs := make([]string, len(trendingProducts))
-	for i := range trendingProducts {
-		trendingProductIDs[i] = *trendingProducts[i].ProductID
+	connection := &model.TrendingProductConnection{
+		Edges:      []*model.TrendingProductEdge{},
+		PageInfo:   &model.PageInfo{},
+		TotalCount: len(trendingProducts),
 	}
 
-	// prepare params for order cloud
-	params := repository.ProductParams{
-		ExtraFilters: map[string]interface{}{
-			"ID": strings.Join(trendingProductIDs, "|"),
-		},
+	if offset >= len(trendingProducts) {
+		return connection, nil
+	}
+	end := minInt(offset+limit, len(trendingProducts))
+	connection.PageInfo.HasNextPage = end < len(trendingProducts)
+	trendingProducts = trendingProducts[offset:end]
+
+	// get products with price schedules from order cloud
+	productIDs := make([]string, len(trendingProducts))
+	for i, trendingProduct := range trendingProducts {
+		productIDs[i] = GetString(trendingProduct.ProductID)
 	}
 
-	// get products from order cloud
-	products, err := svc.productRepo.GetProducts(params, accessToken)
+	productsByID, err := svc.getProductsByIDs(productIDs, market.CatalogID, accessToken)
 	if err != nil {
-		return model.ProductResponse{}, err
+		return nil, err
 	}
 
-	return products, nil
+	err = svc.addPriceSchedules(productsByID, market.Currency, party, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.addFavorites(userID, productsByID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, trendingProduct := range trendingProducts {
+		rank := offset + i
+		cursor := encodeOffsetCursor(rank)
+		connection.PageInfo.EndCursor = &cursor
+
+		product, ok := productsByID[GetString(trendingProduct.ProductID)]
+		if !ok {
+			continue
+		}
+
+		connection.Edges = append(connection.Edges, &model.TrendingProductEdge{
+			Cursor:     cursor,
+			Rank:       rank + 1,
+			OrderCount: GetInt(trendingProduct.OrderCount),
+			Quantity:   GetInt(trendingProduct.Quantity),
+			Score:      GetFloat(trendingProduct.Score),
+			Product:    product,
+		})
+	}
+
+	return connection, nil
+}
+
+// addFavorites marks the products the user has favorited
+func (svc *ProductService) addFavorites(userID *string, productsByID map[string]*model.ProductItem) error {
+	if userID == nil || len(productsByID) == 0 {
+		return nil
+	}
+
+	// get user favorite products from database
+	userProductFavorites, err := svc.productRepo.GetFavoriteProducts(userID, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, userProductFavorite := range userProductFavorites {
+		if product, ok := productsByID[GetString(userProductFavorite.ProductID)]; ok {
+			product.IsFavorite = true
+		}
+	}
+
+	return nil
 }
 
 func (svc *ProductService) StartTrendingProductsProcessor(ctx context.Context) {
@@ -333,7 +927,7 @@ func (svc *ProductService) StartTrendingProductsProcessor(ctx context.Context) {
 		fmt.Println("error occurred while updating trending products cache initially:", err)
 	}
 
-	pullDuration := time.Duration(60) * time.Minute
+	pullDuration := time.Duration(svc.trendingConfig.RefreshDuration) * time.Minute
 	tick := time.NewTicker(pullDuration)
 
 	for {
@@ -354,8 +948,15 @@ func (svc *ProductService) StartTrendingProductsProcessor(ctx context.Context) {
 }
 
 func (svc *ProductService) updateTrendingProductCache() error {
+	params := repository.TrendingProductParams{
+		Since:            time.Now().UTC().Add(-time.Duration(svc.trendingConfig.WindowHours) * time.Hour),
+		HalfLifeHours:    svc.trendingConfig.HalfLifeHours,
+		QuantityExponent: svc.trendingConfig.QuantityExponent,
+		Limit:            svc.trendingConfig.Limit,
+	}
+
 	// get trending products from database
-	trendingProducts, err := svc.productRepo.GetTrendingProducts(10)
+	trendingProducts, err := svc.productRepo.GetTrendingProducts(params)
 	if err != nil {
 		fmt.Println("error occurred while fetching trending products from database:", err)
 		return err
@@ -370,9 +971,238 @@ func (svc *ProductService) updateTrendingProductCache() error {
 		return err
 	}
 
+	// per category and supplier lists expire unless refreshed, so groups that stop trending disappear
+	params.Limit = svc.trendingConfig.GroupLimit
+	groupTTL := 2 * time.Duration(svc.trendingConfig.RefreshDuration) * time.Minute
+
+	categoryProducts, err := svc.productRepo.GetTrendingProductsByCategory(params)
+	if err != nil {
+		fmt.Println("error occurred while fetching trending products per category from database:", err)
+		return err
+	}
+
+	supplierProducts, err := svc.productRepo.GetTrendingProductsBySupplier(params)
+	if err != nil {
+		fmt.Println("error occurred while fetching trending products per supplier from database:", err)
+		return err
+	}
+
+	groups := map[string][]repository.TrendingProductGroupItem{
+		TrendingProductCategoryGroup: categoryProducts,
+		TrendingProductSupplierGroup: supplierProducts,
+	}
+	for groupType, items := range groups {
+		for groupID, groupProducts := range groupTrendingProducts(items) {
+			err = svc.cacheClient.Set(trendingProductGroupCacheKey(groupType, groupID), groupProducts, groupTTL)
+			if err != nil {
+				fmt.Println("error occurred while updating trending products cache:", err)
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+// groupTrendingProducts splits ranked trending products by their group, keeping rank order
+func groupTrendingProducts(items []repository.TrendingProductGroupItem) map[string][]model.TrendingProduct {
+	result := make(map[string][]model.TrendingProduct)
+	for _, item := range items {
+		result[item.GroupID] = append(result[item.GroupID], item.TrendingProduct)
+	}
+	return result
+}
+
+func trendingProductGroupCacheKey(groupType, groupID string) string {
+	return TrendingProductCacheKey + ":" + groupType + ":" + groupID
+}
+
+// getIndexedSearchProducts pages the product ids ranked by the search index of the catalog, restricted to an ID
+// filter first, and hydrates only the page's products from order cloud in rank order. Counts are of ranked ids, so
+// a page can be short of products order cloud no longer returns, and facets describe the page's products
+func (svc *ProductService) getIndexedSearchProducts(params repository.ProductParams, accessToken string) (model.ProductResponse, error) {
+	rankedProductIDs := svc.searchIndexService.Search(params.CatalogID, params.Search)
+
+	// restrict results to an existing id filter, e.g. favorites
+	if idFilter, ok := params.ExtraFilters["ID"].(string); ok && idFilter != "" {
+		allowedProductIDs := make(map[string]bool)
+		for _, productID := range strings.Split(idFilter, "|") {
+			allowedProductIDs[productID] = true
+		}
+
+		var filteredProductIDs []string
+		for _, productID := range rankedProductIDs {
+			if allowedProductIDs[productID] {
+				filteredProductIDs = append(filteredProductIDs, productID)
+			}
+		}
+		rankedProductIDs = filteredProductIDs
+	}
+
+	// page the ranked ids, so only the products of the requested page are fetched
+	pageProductIDs, meta := paginateProducts(rankedProductIDs, params.Page, params.PageSize)
+
+	productsByID := make(map[string]*model.ProductItem)
+	var facets []*model.ListFacet
+	for start := 0; start < len(pageProductIDs); start += ProductBatchSize {
+		batch := pageProductIDs[start:minInt(start+ProductBatchSize, len(pageProductIDs))]
+
+		extraFilters := make(map[string]interface{})
+		for key, value := range params.ExtraFilters {
+			extraFilters[key] = value
+		}
+		extraFilters["ID"] = strings.Join(batch, "|")
+
+		products, err := svc.productRepo.GetProducts(repository.ProductParams{
+			CatalogID:    params.CatalogID,
+			CategoryID:   params.CategoryID,
+			SupplierID:   params.SupplierID,
+			ExtraFilters: extraFilters,
+			Page:         "1",
+			PageSize:     repository.GetStringFromInt(len(batch)),
+		}, accessToken)
+		if err != nil {
+			return model.ProductResponse{}, err
+		}
+
+		for _, product := range products.Items {
+			productsByID[GetString(product.ID)] = product
+		}
+		if products.Meta != nil {
+			facets = mergeListFacets(facets, products.Meta.Facets)
+		}
+	}
+
+	items := make([]*model.ProductItem, 0, len(pageProductIDs))
+	for _, productID := range pageProductIDs {
+		if product, ok := productsByID[productID]; ok {
+			items = append(items, product)
+		}
+	}
+	meta.Facets = facets
+
+	return model.ProductResponse{Meta: meta, Items: items}, nil
+}
+
+// mergeListFacets adds the value counts of facets to merged, facets and values matched by name
+func mergeListFacets(merged, facets []*model.ListFacet) []*model.ListFacet {
+	for _, facet := range facets {
+		if facet == nil {
+			continue
+		}
+
+		var target *model.ListFacet
+		for _, mergedFacet := range merged {
+			if GetString(mergedFacet.Name) == GetString(facet.Name) {
+				target = mergedFacet
+				break
+			}
+		}
+		if target == nil {
+			target = &model.ListFacet{Name: facet.Name, XpPath: facet.XpPath}
+			merged = append(merged, target)
+		}
+
+		for _, value := range facet.Values {
+			if value == nil {
+				continue
+			}
+
+			var targetValue *model.ListFacetValue
+			for _, mergedValue := range target.Values {
+				if GetString(mergedValue.Value) == GetString(value.Value) {
+					targetValue = mergedValue
+					break
+				}
+			}
+			if targetValue == nil {
+				count := 0
+				targetValue = &model.ListFacetValue{Value: value.Value, Count: &count, IsSelected: value.IsSelected}
+				target.Values = append(target.Values, targetValue)
+			}
+			*targetValue.Count += GetInt(value.Count)
+		}
+	}
+
+	return merged
+}
+
+// paginateProducts returns the requested page of items along with order cloud style meta
+func paginateProducts[T any](items []T, page, pageSize string) ([]T, *model.ProductMeta) {
+	pageVal := repository.GetIntFromString(page)
+	if pageVal <= 0 {
+		pageVal = 1
+	}
+	pageSizeVal := repository.GetIntFromString(pageSize)
+	if pageSizeVal <= 0 {
+		pageSizeVal = DefaultPageSize
+	}
+
+	totalCount := len(items)
+	totalPages := (totalCount + pageSizeVal - 1) / pageSizeVal
+
+	start := (pageVal - 1) * pageSizeVal
+	if start > totalCount {
+		start = totalCount
+	}
+	end := start + pageSizeVal
+	if end > totalCount {
+		end = totalCount
+	}
+
+	rangeStart, rangeEnd := start+1, end
+	if start == end {
+		rangeStart = 0
+	}
+
+	meta := &model.ProductMeta{
+		Page:       &pageVal,
+		PageSize:   &pageSizeVal,
+		TotalCount: &totalCount,
+		TotalPages: &totalPages,
+		ItemRange:  []*int{&rangeStart, &rangeEnd},
+	}
+
+	return items[start:end], meta
+}
+
+func newSearchEvent(userID, search *string, isFavorite *bool, params repository.ProductParams, meta *model.ProductMeta, itemCount int, latency time.Duration) SearchEvent {
+	resultCount := itemCount
+	if meta != nil && meta.TotalCount != nil {
+		resultCount = *meta.TotalCount
+	}
+
+	// filters chosen by the user, internal filters added by the service are left out
+	filters := make(map[string]interface{})
+	for key, value := range params.ExtraFilters {
+		if key != "ID" && key != "Active" {
+			filters[key] = value
+		}
+	}
+	if params.CategoryID != "" {
+		filters["categoryID"] = params.CategoryID
+	}
+	if params.SupplierID != "" {
+		filters["supplierID"] = params.SupplierID
+	}
+	if GetBool(isFavorite) {
+		filters["isFavorite"] = true
+	}
+
+	return SearchEvent{
+		UserID:      GetString(userID),
+		Keyword:     GetString(search),
+		CatalogID:   params.CatalogID,
+		Filters:     filters,
+		ResultCount: resultCount,
+		Latency:     latency,
+	}
+}
+
+func isFirstPage(page *string) bool {
+	return page == nil || repository.GetIntFromStringPointer(page) <= 1
+}
+
 func getFavoriteProductIDs(userProductFavorites []model.UserProductFavorite) string {
 	var productIDs []string
 	for _, userProductFavorite := range userProductFavorites {
@@ -386,9 +1216,129 @@ func getFavoriteProductIDs(userProductFavorites []model.UserProductFavorite) str
 	return result
 }
 
-func (svc *ProductService) GetProductsV2(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search *string, isFavorite *bool, extraFilters map[string]interface{}, accessToken string) (model.ProductResponseV2, error) {
+// latestFavoriteChanges keeps the latest change of every product, in the order products first appear
+func latestFavoriteChanges(changes []*model.FavoriteChangeInput) []*model.FavoriteChangeInput {
+	var result []*model.FavoriteChangeInput
+	changeIndex := make(map[string]int)
+
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+
+		i, ok := changeIndex[change.ProductID]
+		if !ok {
+			changeIndex[change.ProductID] = len(result)
+			result = append(result, change)
+			continue
+		}
+		if !change.ChangedAt.Before(result[i].ChangedAt) {
+			result[i] = change
+		}
+	}
+
+	return result
+}
+
+// planFavoriteSync returns the favorites to add and the products to remove to apply changes on top of
+// the current favorites. Added favorites keep the time they were made offline, but never a future one
+func planFavoriteSync(userProductFavorites []model.UserProductFavorite, changes []*model.FavoriteChangeInput, now time.Time) ([]repository.FavoriteProductBatchItem, []string) {
+	favoritedAt := make(map[string]*time.Time)
+	for _, userProductFavorite := range userProductFavorites {
+		favoritedAt[GetString(userProductFavorite.ProductID)] = userProductFavorite.CreatedAt
+	}
+
+	var items []repository.FavoriteProductBatchItem
+	var removedProductIDs []string
+	for _, change := range changes {
+		createdAt, isFavorite := favoritedAt[change.ProductID]
+
+		if change.IsFavorite {
+			if isFavorite {
+				continue
+			}
+
+			changedAt := change.ChangedAt.UTC()
+			if changedAt.After(now) {
+				changedAt = now
+			}
+			items = append(items, repository.FavoriteProductBatchItem{ProductID: change.ProductID, CreatedAt: changedAt})
+			continue
+		}
+
+		// a product favorited again after the offline removal stays favorite
+		if isFavorite && (createdAt == nil || !createdAt.After(change.ChangedAt)) {
+			removedProductIDs = append(removedProductIDs, change.ProductID)
+		}
+	}
+
+	return items, removedProductIDs
+}
+
+// encodeOffsetCursor returns an opaque cursor pointing at a position in a ranked list
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(cursor string) (int, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(value), "offset:"))
+	if err != nil || offset < 0 || !strings.HasPrefix(string(value), "offset:") {
+		return 0, ErrInvalidCursor
+	}
+
+	return offset, nil
+}
+
+// encodeFavoriteProductCursor returns an opaque cursor pointing at the favorite
+func encodeFavoriteProductCursor(userProductFavorite model.UserProductFavorite) string {
+	var createdAt time.Time
+	if userProductFavorite.CreatedAt != nil {
+		createdAt = *userProductFavorite.CreatedAt
+	}
+
+	var id int
+	if userProductFavorite.ID != nil {
+		id = *userProductFavorite.ID
+	}
+
+	value := fmt.Sprintf("%d:%d", createdAt.UnixNano(), id)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeFavoriteProductCursor(cursor string) (repository.FavoriteProductCursor, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repository.FavoriteProductCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(value), ":")
+	if len(parts) != 2 {
+		return repository.FavoriteProductCursor{}, ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return repository.FavoriteProductCursor{}, ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return repository.FavoriteProductCursor{}, ErrInvalidCursor
+	}
+
+	return repository.FavoriteProductCursor{CreatedAt: time.Unix(0, createdAt).UTC(), ID: id}, nil
+}
+
+func (svc *ProductService) GetProductsV2(catalogID, categoryID, supplierID, userID, page, pageSize, sortBy, search *string, isFavorite *bool, extraFilters map[string]interface{}, market config.MarketConfig, accessToken string) (model.ProductResponseV2, error) {
+	searchStartedAt := time.Now()
 	params := repository.ProductParams{
-		CatalogID:    GetString(catalogID),
+		CatalogID:    marketCatalogID(catalogID, market),
 		CategoryID:   GetString(categoryID),
 		SupplierID:   GetString(supplierID),
 		Page:         GetString(page),
@@ -422,12 +1372,6 @@ func (svc *ProductService) GetProductsV2(catalogID, categoryID, supplierID, user
 	if search != nil {
 		params.Search = *search
 		params.SearchOn = "ID,Name,Description"
-
-		// insert search details to database
-		_, err = svc.recentSearchesRepo.SaveRecentSearch(userID, search)
-		if err != nil {
-			return model.ProductResponseV2{}, err
-		}
 	}
 
 	// get products from order cloud
@@ -436,6 +1380,22 @@ func (svc *ProductService) GetProductsV2(catalogID, categoryID, supplierID, user
 		return model.ProductResponseV2{}, err
 	}
 
+	// keep facets configured for the catalog
+	if products.Meta != nil {
+		products.Meta.Facets = svc.getCatalogFacets(params, products.Meta.Facets, accessToken)
+	}
+
+	// queue search details for the database, later pages of the same search are not recorded again
+	if search != nil && isFirstPage(page) {
+		if products.Meta == nil {
+			products.Meta = &model.ProductMeta{}
+		}
+		searchID := svc.searchLogService.LogSearch(newSearchEvent(userID, search, isFavorite, params, products.Meta, len(products.Items), time.Since(searchStartedAt)))
+		if searchID != "" {
+			products.Meta.SearchID = &searchID
+		}
+	}
+
 	// add isFavorite field to products
 	for i, product := range products.Items {
 		for _, userProductFavorite := range userProductFavorites {
@@ -448,12 +1408,16 @@ func (svc *ProductService) GetProductsV2(catalogID, categoryID, supplierID, user
 	return products, nil
 }
 
-func (svc *ProductService) GetProductV2(productID string, userID *string, accessToken string) (model.LatestProductItems, error) {
-	// get user favorite products from database
-	userProductFavorites, err := svc.productRepo.GetFavoriteProducts(userID, nil, nil)
+// GetProductV2 returns a product of the market's catalog priced for the party in the market's currency
+func (svc *ProductService) GetProductV2(productID string, userID *string, market config.MarketConfig, party PriceParty, accessToken string) (model.LatestProductItems, error) {
+	// check product in the market's catalog
+	productsByID, err := svc.getProductsByIDs([]string{productID}, market.CatalogID, accessToken)
 	if err != nil {
 		return model.LatestProductItems{}, err
 	}
+	if _, ok := productsByID[productID]; !ok {
+		return model.LatestProductItems{}, ErrProductNotFound
+	}
 
 	product, err := svc.productRepo.GetProductV2(productID, accessToken)
 	if err != nil {
@@ -461,15 +1425,36 @@ func (svc *ProductService) GetProductV2(productID string, userID *string, access
 	}
 
 	// add isFavorite field to products
-	for _, userProductFavorite := range userProductFavorites {
-		if *product.Product.ID == *userProductFavorite.ProductID {
-			product.Product.IsFavorite = true
+	if product.Product != nil {
+		err = svc.addFavorites(userID, map[string]*model.ProductItem{productID: product.Product})
+		if err != nil {
+			return model.LatestProductItems{}, err
 		}
 	}
 
+	// the middleware's price schedule is not of the market, only the party's schedule in its currency is kept
+	product.PriceSchedule = nil
+	err = svc.addLatestProductPriceSchedules([]*model.LatestProductItems{&product}, market, party, accessToken)
+	if err != nil {
+		return model.LatestProductItems{}, err
+	}
+
 	return product, nil
 }
 
+// getCatalogFacets returns the facets configured for the catalog of the listing, see FacetService.GetFacets. Facets
+// only decorate a listing, so when facet definitions cannot be fetched the facets order cloud returned are kept
+func (svc *ProductService) getCatalogFacets(params repository.ProductParams, facets []*model.ListFacet, accessToken string) []*model.ListFacet {
+	catalogFacets, err := svc.facetService.GetFacets(params.CatalogID, facets, params.ExtraFilters, accessToken)
+	if err != nil {
+		fmt.Printf("error occurred while fetching facets of catalog %s: %v\n", params.CatalogID, err)
+		return facets
+	}
+	return catalogFacets
+}
+
+// Deprecated: GetProductFilterMiddleWare reads a single facet from the seller center middleware, use the Facets
+// listings return, see FacetService
 func (svc *ProductService) GetProductFilterMiddleWare(searchOn string, accessToken string) ([]*model.ProductFilter, error) {
 	// get products filters from .net middleware
 	productFilter, err := svc.productRepo.FetchProductFilters(searchOn, accessToken)
@@ -478,3 +1463,50 @@ func (svc *ProductService) GetProductFilterMiddleWare(searchOn string, accessTok
 	}
 	return productFilter, nil
 }
+
+// mergeRecommendedProducts appends fallback products not recommended yet, up to limit products
+func mergeRecommendedProducts(products, fallbackProducts []*model.LatestProductItems, limit int) []*model.LatestProductItems {
+	seen := make(map[string]bool, len(products))
+	for _, product := range products {
+		seen[GetString(product.ID)] = true
+	}
+
+	for _, product := range fallbackProducts {
+		if len(products) >= limit {
+			break
+		}
+		if seen[GetString(product.ID)] {
+			continue
+		}
+		seen[GetString(product.ID)] = true
+		products = append(products, product)
+	}
+
+	return products
+}
+
+func toRecommendationReason(relation string) model.RecommendationReason {
+	if relation == repository.RelationBoughtTogether {
+		return model.RecommendationReasonBoughtTogether
+	}
+	return model.RecommendationReasonAlsoBought
+}
+
+func toNewProductPriceSchedule(priceSchedule *model.PriceScheduleItem) *model.NewProductPriceSchedule {
+	return &model.NewProductPriceSchedule{
+		ID:                    priceSchedule.ID,
+		OwnerID:               priceSchedule.OwnerID,
+		Name:                  priceSchedule.Name,
+		ApplyTax:              priceSchedule.ApplyTax,
+		ApplyShipping:         priceSchedule.ApplyShipping,
+		MinQuantity:           priceSchedule.MinQuantity,
+		MaxQuantity:           priceSchedule.MaxQuantity,
+		UseCumulativeQuantity: priceSchedule.UseCumulativeQuantity,
+		RestrictedQuantity:    priceSchedule.RestrictedQuantity,
+		Currency:              priceSchedule.Currency,
+		SaleStart:             priceSchedule.SaleStart,
+		SaleEnd:               priceSchedule.SaleEnd,
+		IsOnSale:              priceSchedule.IsOnSale,
+		PriceBreaks:           priceSchedule.PriceBreaks,
+	}
+}
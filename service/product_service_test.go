@@ -1,14 +1,27 @@
 package service
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"mpmy-product-service/client/cache"
+	"mpmy-product-service/client/search"
+	"mpmy-product-service/config"
 	"mpmy-product-service/graph/model"
 	"mpmy-product-service/repository"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dgraph-io/ristretto"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
 )
 
+var testMarket = config.MarketConfig{ID: "my", CatalogID: "zp-my", Currency: "MYR", Locale: "ms-MY", Timezone: "Asia/Kuala_Lumpur"}
+
+var testParty = PriceParty{BuyerID: "zp-buyer", UserGroupIDs: []string{"zp-wholesale"}}
+
 func TestGetRecommendProducts(t *testing.T) {
 
 	//data test
@@ -22,46 +35,52 @@ func TestGetRecommendProducts(t *testing.T) {
 	var pageSize *string
 
 	var latestProductItems []*model.LatestProductItems
-	var priceScheduleItem []*model.PriceScheduleItem
+	var defaultPriceScheduleIDs = map[string]string{}
+	var priceSchedules = map[string]*model.PriceScheduleItem{}
 
-	//create list product id for GetPriceSchedules service
+	//create list product id for ResolvePriceSchedules service
 	//create list product item for GetProductsOrderCloudV2 repo
-	//create list schedule item of product id for GetPriceSchedules service
+	//create price schedule of product id for ResolvePriceSchedules service
 	for i := 0; i < 3; i++ {
 		var id = uuid.New().String()
+		var priceScheduleID = uuid.New().String()
 		productIDs = append(productIDs, id)
 		latestProductItems = append(latestProductItems, &model.LatestProductItems{
-			ID: &id,
-		})
-		priceScheduleItem = append(priceScheduleItem, &model.PriceScheduleItem{
-			ID: &id,
+			ID:      &id,
+			Product: &model.ProductItem{ID: &id, DefaultPriceScheduleID: &priceScheduleID},
 		})
+		defaultPriceScheduleIDs[id] = priceScheduleID
+		priceSchedules[id] = &model.PriceScheduleItem{
+			ID: &priceScheduleID,
+		}
 	}
 
+	var recommendationRepositoryMock = &repository.RecommendationRepositoryMock{}
 	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
 	var productRepositoryMock = &repository.ProductRepositoryMock{}
 	var priceScheduleServiceMock = &PriceScheduleServiceMock{}
 
-	categoryProductRepositoryMock.On("GetCategoryProductByProductID", productID, accessToken).Return(repository.CategoryProductItem{
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, productID, accessToken).Return(repository.CategoryProductItem{
 		ProductID:  productID,
 		CategoryID: categoryID,
 	}, nil)
 
-	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{CategoryID: categoryID, Page: "", PageSize: ""}, accessToken).Return(model.ProductResponseV2{
+	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{CatalogID: testMarket.CatalogID, CategoryID: categoryID, Page: "", PageSize: ""}, accessToken).Return(model.ProductResponseV2{
 		Items: latestProductItems,
 	}, nil)
 
-	priceScheduleServiceMock.On("GetPriceSchedules", productIDs, page, pageSize, accessToken).Return(model.PriceScheduleResponse{
-		Items: priceScheduleItem,
-	}, nil)
+	priceScheduleServiceMock.On("ResolvePriceSchedules", defaultPriceScheduleIDs, testMarket.Currency, testParty, accessToken).Return(priceSchedules, nil)
+
+	recommendationRepositoryMock.On("GetProductRecommendations", productID, MaxRecommendProducts).Return([]repository.ProductRecommendationItem{}, nil)
 
 	productService := &ProductService{
+		recommendationRepo:   recommendationRepositoryMock,
 		productRepo:          productRepositoryMock,
 		categoryProductRepo:  categoryProductRepositoryMock,
 		priceScheduleService: priceScheduleServiceMock,
 	}
 
-	data, err := productService.GetRecommendProducts(productID, page, pageSize, accessToken)
+	data, err := productService.GetRecommendProducts(productID, page, pageSize, testMarket, testParty, accessToken)
 	if err != nil {
 		t.Error("test failed error: ", err)
 	} else if len(data.Items) <= 0 {
@@ -96,23 +115,57 @@ func TestGetRecommendProductsWithErrorCategoryRepo(t *testing.T) {
 	var page *string
 	var pageSize *string
 
+	var recommendationRepositoryMock = &repository.RecommendationRepositoryMock{}
 	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
 
-	categoryProductRepositoryMock.On("GetCategoryProductByProductID", productID, accessToken).Return(repository.CategoryProductItem{
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, productID, accessToken).Return(repository.CategoryProductItem{
 		ProductID:  productID,
 		CategoryID: categoryID,
 	}, errorTest)
 
+	recommendationRepositoryMock.On("GetProductRecommendations", productID, MaxRecommendProducts).Return([]repository.ProductRecommendationItem{}, nil)
+
 	productService := &ProductService{
+		recommendationRepo:  recommendationRepositoryMock,
 		categoryProductRepo: categoryProductRepositoryMock,
 	}
 
-	_, err := productService.GetRecommendProducts(productID, page, pageSize, accessToken)
+	_, err := productService.GetRecommendProducts(productID, page, pageSize, testMarket, testParty, accessToken)
 	if err == nil || err != errorTest {
 		t.Error("test failed error")
 	}
 }
 
+func TestGetRecommendProductsWithErrorRecommendationRepo(t *testing.T) {
+
+	//data test
+	var errorTest = errors.New("new error")
+	var accessToken = uuid.New().String()
+	var productID = uuid.New().String()
+
+	var page *string
+	var pageSize *string
+
+	var recommendationRepositoryMock = &repository.RecommendationRepositoryMock{}
+	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
+
+	// failed recommendations fall back to same category products
+	recommendationRepositoryMock.On("GetProductRecommendations", productID, MaxRecommendProducts).Return([]repository.ProductRecommendationItem(nil), errors.New("recommendations unavailable"))
+
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, productID, accessToken).Return(repository.CategoryProductItem{}, errorTest)
+
+	productService := &ProductService{
+		recommendationRepo:  recommendationRepositoryMock,
+		categoryProductRepo: categoryProductRepositoryMock,
+	}
+
+	_, err := productService.GetRecommendProducts(productID, page, pageSize, testMarket, testParty, accessToken)
+	if err == nil || err != errorTest {
+		t.Error("test failed: same category products must be fetched when recommendations fail")
+	}
+	categoryProductRepositoryMock.AssertExpectations(t)
+}
+
 func TestGetRecommendProductsWithErrorProductRepo(t *testing.T) {
 
 	//data test
@@ -126,24 +179,28 @@ func TestGetRecommendProductsWithErrorProductRepo(t *testing.T) {
 
 	var latestProductItems []*model.LatestProductItems
 
+	var recommendationRepositoryMock = &repository.RecommendationRepositoryMock{}
 	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
 	var productRepositoryMock = &repository.ProductRepositoryMock{}
 
-	categoryProductRepositoryMock.On("GetCategoryProductByProductID", productID, accessToken).Return(repository.CategoryProductItem{
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, productID, accessToken).Return(repository.CategoryProductItem{
 		ProductID:  productID,
 		CategoryID: categoryID,
 	}, nil)
 
-	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{CategoryID: categoryID, Page: "", PageSize: ""}, accessToken).Return(model.ProductResponseV2{
+	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{CatalogID: testMarket.CatalogID, CategoryID: categoryID, Page: "", PageSize: ""}, accessToken).Return(model.ProductResponseV2{
 		Items: latestProductItems,
 	}, errorTest)
 
+	recommendationRepositoryMock.On("GetProductRecommendations", productID, MaxRecommendProducts).Return([]repository.ProductRecommendationItem{}, nil)
+
 	productService := &ProductService{
+		recommendationRepo:  recommendationRepositoryMock,
 		productRepo:         productRepositoryMock,
 		categoryProductRepo: categoryProductRepositoryMock,
 	}
 
-	_, err := productService.GetRecommendProducts(productID, page, pageSize, accessToken)
+	_, err := productService.GetRecommendProducts(productID, page, pageSize, testMarket, testParty, accessToken)
 	if err == nil || err != errorTest {
 		t.Error("test failed error")
 	}
@@ -162,47 +219,824 @@ func TestGetRecommendProductsWithErrorPrinceSchedule(t *testing.T) {
 	var pageSize *string
 
 	var latestProductItems []*model.LatestProductItems
-	var priceScheduleItem []*model.PriceScheduleItem
+	var defaultPriceScheduleIDs = map[string]string{}
+	var priceSchedules = map[string]*model.PriceScheduleItem{}
 
-	//create list product id for GetPriceSchedules service
+	//create list product id for ResolvePriceSchedules service
 	//create list product item for GetProductsOrderCloudV2 repo
-	//create list schedule item of product id for GetPriceSchedules service
+	//create price schedule of product id for ResolvePriceSchedules service
 	for i := 0; i < 3; i++ {
 		var id = uuid.New().String()
+		var priceScheduleID = uuid.New().String()
 		productIDs = append(productIDs, id)
 		latestProductItems = append(latestProductItems, &model.LatestProductItems{
-			ID: &id,
-		})
-		priceScheduleItem = append(priceScheduleItem, &model.PriceScheduleItem{
-			ID: &id,
+			ID:      &id,
+			Product: &model.ProductItem{ID: &id, DefaultPriceScheduleID: &priceScheduleID},
 		})
+		defaultPriceScheduleIDs[id] = priceScheduleID
+		priceSchedules[id] = &model.PriceScheduleItem{
+			ID: &priceScheduleID,
+		}
 	}
 
+	var recommendationRepositoryMock = &repository.RecommendationRepositoryMock{}
 	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
 	var productRepositoryMock = &repository.ProductRepositoryMock{}
 	var priceScheduleServiceMock = &PriceScheduleServiceMock{}
 
-	categoryProductRepositoryMock.On("GetCategoryProductByProductID", productID, accessToken).Return(repository.CategoryProductItem{
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, productID, accessToken).Return(repository.CategoryProductItem{
 		ProductID:  productID,
 		CategoryID: categoryID,
 	}, nil)
 
-	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{CategoryID: categoryID, Page: "", PageSize: ""}, accessToken).Return(model.ProductResponseV2{
+	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{CatalogID: testMarket.CatalogID, CategoryID: categoryID, Page: "", PageSize: ""}, accessToken).Return(model.ProductResponseV2{
 		Items: latestProductItems,
 	}, nil)
 
-	priceScheduleServiceMock.On("GetPriceSchedules", productIDs, page, pageSize, accessToken).Return(model.PriceScheduleResponse{
-		Items: priceScheduleItem,
-	}, errorTest)
+	priceScheduleServiceMock.On("ResolvePriceSchedules", defaultPriceScheduleIDs, testMarket.Currency, testParty, accessToken).Return(priceSchedules, errorTest)
+
+	recommendationRepositoryMock.On("GetProductRecommendations", productID, MaxRecommendProducts).Return([]repository.ProductRecommendationItem{}, nil)
 
 	productService := &ProductService{
+		recommendationRepo:   recommendationRepositoryMock,
 		productRepo:          productRepositoryMock,
 		categoryProductRepo:  categoryProductRepositoryMock,
 		priceScheduleService: priceScheduleServiceMock,
 	}
 
-	_, err := productService.GetRecommendProducts(productID, page, pageSize, accessToken)
+	_, err := productService.GetRecommendProducts(productID, page, pageSize, testMarket, testParty, accessToken)
 	if err == nil || err != errorTest {
 		t.Error("test failed error")
 	}
 }
+
+func TestGetRecommendProductsFromOrderHistory(t *testing.T) {
+
+	//data test
+	var errorTest = errors.New("new error")
+	var accessToken = uuid.New().String()
+	var productID = uuid.New().String()
+	var boughtTogetherID = uuid.New().String()
+	var alsoBoughtID = uuid.New().String()
+	var inactiveID = uuid.New().String()
+	var priceScheduleID = uuid.New().String()
+
+	var page = "1"
+	var pageSize = "2"
+
+	var recommendationRepositoryMock = &repository.RecommendationRepositoryMock{}
+	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	var priceScheduleServiceMock = &PriceScheduleServiceMock{}
+
+	recommendationRepositoryMock.On("GetProductRecommendations", productID, MaxRecommendProducts).Return([]repository.ProductRecommendationItem{
+		{RelatedProductID: boughtTogetherID, Relation: repository.RelationBoughtTogether},
+		{RelatedProductID: inactiveID, Relation: repository.RelationBoughtTogether},
+		{RelatedProductID: boughtTogetherID, Relation: repository.RelationAlsoBought},
+		{RelatedProductID: alsoBoughtID, Relation: repository.RelationAlsoBought},
+	}, nil)
+
+	productRepositoryMock.On("GetProductsOrderCloudV2", repository.ProductParams{
+		CatalogID:    testMarket.CatalogID,
+		ExtraFilters: map[string]interface{}{"ID": boughtTogetherID + "|" + inactiveID + "|" + alsoBoughtID},
+		Page:         "1",
+		PageSize:     "3",
+	}, accessToken).Return(model.ProductResponseV2{
+		Items: []*model.LatestProductItems{{ID: &alsoBoughtID}, {ID: &boughtTogetherID, Product: &model.ProductItem{DefaultPriceScheduleID: &priceScheduleID}}},
+	}, nil)
+
+	priceScheduleServiceMock.On("ResolvePriceSchedules", map[string]string{boughtTogetherID: priceScheduleID, alsoBoughtID: ""}, testMarket.Currency, testParty, accessToken).Return(map[string]*model.PriceScheduleItem{
+		boughtTogetherID: {ID: &priceScheduleID},
+	}, nil)
+
+	// an uncategorized product is recommended from order history only
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, productID, accessToken).Return(repository.CategoryProductItem{}, errorTest)
+
+	productService := &ProductService{
+		productRepo:          productRepositoryMock,
+		categoryProductRepo:  categoryProductRepositoryMock,
+		recommendationRepo:   recommendationRepositoryMock,
+		priceScheduleService: priceScheduleServiceMock,
+	}
+
+	data, err := productService.GetRecommendProducts(productID, &page, &pageSize, testMarket, testParty, accessToken)
+	if err != nil {
+		t.Error("test failed error: ", err)
+	} else if len(data.Items) != 2 {
+		t.Error("test failed: items must return active recommended products, got", len(data.Items))
+	} else {
+		if *data.Items[0].ID != boughtTogetherID || *data.Items[0].Reason != model.RecommendationReasonBoughtTogether {
+			t.Error("test failed: bought together product must come first")
+		}
+		if *data.Items[1].ID != alsoBoughtID || *data.Items[1].Reason != model.RecommendationReasonAlsoBought {
+			t.Error("test failed: also bought product must come second")
+		}
+		if data.Items[0].PriceSchedule == nil || data.Items[1].PriceSchedule != nil {
+			t.Error("test failed: price schedules must be added by the product's default price schedule")
+		}
+		if *data.Meta.TotalCount != 2 {
+			t.Error("test failed: total count must be 2, got", *data.Meta.TotalCount)
+		}
+	}
+}
+
+func TestMergeRecommendedProducts(t *testing.T) {
+
+	//data test
+	var ids = []string{"p1", "p2", "p3", "p4"}
+	var products = []*model.LatestProductItems{{ID: &ids[0]}, {ID: &ids[1]}}
+	var fallbackProducts = []*model.LatestProductItems{{ID: &ids[1]}, {ID: &ids[2]}, {ID: &ids[3]}}
+
+	merged := mergeRecommendedProducts(products, fallbackProducts, 3)
+	if len(merged) != 3 {
+		t.Error("test failed: merged products must be limited to 3, got", len(merged))
+	} else if *merged[0].ID != "p1" || *merged[1].ID != "p2" || *merged[2].ID != "p3" {
+		t.Error("test failed: fallback products must be appended once after recommended products")
+	}
+}
+
+func TestFavoriteProductCursor(t *testing.T) {
+
+	//data test
+
+	var id = 42
+	var createdAt = time.Date(2023, 3, 14, 9, 26, 53, 589793000, time.UTC)
+
+	cursor := encodeFavoriteProductCursor(model.UserProductFavorite{ID: &id, CreatedAt: &createdAt})
+
+	result, err := decodeFavoriteProductCursor(cursor)
+	if err != nil {
+		t.Error("test failed, error should be nil")
+	}
+
+	if result.ID != id || !result.CreatedAt.Equal(createdAt) {
+		t.Error("test failed, cursor should point at the same favorite")
+	}
+
+	for _, invalidCursor := range []string{"", "not a cursor", "MTIz", encodeString("abc:1"), encodeString("1:abc")} {
+		_, err = decodeFavoriteProductCursor(invalidCursor)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("test failed, cursor %q should be invalid", invalidCursor)
+		}
+	}
+}
+
+func TestGetFavoriteProductsWithoutFavorites(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var userID = uuid.New().String()
+	var first = 10
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+
+	productRepositoryMock.On("GetFavoriteProductsAfter", &userID, (*repository.FavoriteProductCursor)(nil), first+1, false).Return([]model.UserProductFavorite{}, nil)
+	productRepositoryMock.On("CountFavoriteProducts", &userID).Return(0, nil)
+
+	svc := ProductService{
+		productRepo: productRepositoryMock,
+	}
+
+	result, err := svc.GetFavoriteProducts(&userID, &first, nil, nil, testMarket, testParty, accessToken)
+	if err != nil {
+		t.Error("test failed, error should be nil")
+	}
+
+	if len(result.Edges) != 0 || len(result.UnavailableProductIDs) != 0 {
+		t.Error("test failed, result should be empty")
+	}
+
+	if result.PageInfo.HasNextPage || result.PageInfo.EndCursor != nil {
+		t.Error("test failed, result should have no next page")
+	}
+}
+
+func TestGetFavoriteProductsWithUnavailableProducts(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var userID = uuid.New().String()
+	var first = 2
+	var availableID, unavailableID, nextID = "p1", "p2", "p3"
+	var availableIDs = []int{1, 2, 3}
+	var priceScheduleID = "ps1"
+	var createdAt = time.Date(2023, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	// one favorite more than requested is read to know a next page follows
+	userProductFavorites := []model.UserProductFavorite{
+		{ID: &availableIDs[0], UserID: &userID, ProductID: &availableID, CreatedAt: &createdAt},
+		{ID: &availableIDs[1], UserID: &userID, ProductID: &unavailableID, CreatedAt: &createdAt},
+		{ID: &availableIDs[2], UserID: &userID, ProductID: &nextID, CreatedAt: &createdAt},
+	}
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	var priceScheduleServiceMock = &PriceScheduleServiceMock{}
+
+	productRepositoryMock.On("GetFavoriteProductsAfter", &userID, (*repository.FavoriteProductCursor)(nil), first+1, false).Return(userProductFavorites, nil)
+	productRepositoryMock.On("CountFavoriteProducts", &userID).Return(3, nil)
+	productRepositoryMock.On("GetProducts", repository.ProductParams{
+		CatalogID:    testMarket.CatalogID,
+		ExtraFilters: map[string]interface{}{"ID": "p1|p2"},
+		Page:         "1",
+		PageSize:     "2",
+	}, accessToken).Return(model.ProductResponse{
+		Items: []*model.ProductItem{{ID: &availableID, DefaultPriceScheduleID: &priceScheduleID}},
+	}, nil)
+	priceScheduleServiceMock.On("ResolvePriceSchedules", map[string]string{availableID: priceScheduleID}, testMarket.Currency, testParty, accessToken).
+		Return(map[string]*model.PriceScheduleItem{availableID: {ID: &priceScheduleID}}, nil)
+
+	svc := ProductService{
+		productRepo:          productRepositoryMock,
+		priceScheduleService: priceScheduleServiceMock,
+	}
+
+	result, err := svc.GetFavoriteProducts(&userID, &first, nil, nil, testMarket, testParty, accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	if len(result.Edges) != 1 || GetString(result.Edges[0].Product.ID) != availableID || !result.Edges[0].Product.IsFavorite {
+		t.Error("test failed, only the available product should be returned as an edge")
+	}
+	if result.Edges[0].Product.PriceSchedule == nil || GetString(result.Edges[0].Product.PriceSchedule.ID) != priceScheduleID {
+		t.Error("test failed, available product should be priced")
+	}
+
+	if len(result.UnavailableProductIDs) != 1 || result.UnavailableProductIDs[0] != unavailableID {
+		t.Errorf("test failed, expected unavailable product %s, got %v", unavailableID, result.UnavailableProductIDs)
+	}
+
+	// the page ends at the unavailable favorite so the next page does not repeat it
+	expectedCursor := encodeFavoriteProductCursor(userProductFavorites[1])
+	if !result.PageInfo.HasNextPage || result.PageInfo.EndCursor == nil || *result.PageInfo.EndCursor != expectedCursor {
+		t.Error("test failed, end cursor should point at the last favorite of the page")
+	}
+	if result.TotalCount != 3 {
+		t.Error("test failed, total count should count every favorite")
+	}
+}
+
+func encodeString(value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func TestLatestFavoriteChanges(t *testing.T) {
+
+	//data test
+
+	var changedAt = time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	changes := []*model.FavoriteChangeInput{
+		{ProductID: "p1", IsFavorite: true, ChangedAt: changedAt},
+		{ProductID: "p2", IsFavorite: true, ChangedAt: changedAt},
+		{ProductID: "p1", IsFavorite: false, ChangedAt: changedAt.Add(time.Minute)},
+		{ProductID: "p2", IsFavorite: false, ChangedAt: changedAt.Add(-time.Minute)},
+		nil,
+	}
+
+	result := latestFavoriteChanges(changes)
+
+	if len(result) != 2 {
+		t.Fatal("test failed, result should have one change per product")
+	}
+
+	if result[0].ProductID != "p1" || result[0].IsFavorite {
+		t.Error("test failed, latest change of p1 should remove it")
+	}
+
+	if result[1].ProductID != "p2" || !result[1].IsFavorite {
+		t.Error("test failed, latest change of p2 should add it")
+	}
+}
+
+func TestPlanFavoriteSync(t *testing.T) {
+
+	//data test
+
+	var now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	var favoritedAt = now.Add(-time.Hour)
+	var keptProductID = "kept"
+	var removedProductID = "removed"
+
+	userProductFavorites := []model.UserProductFavorite{
+		{ProductID: &keptProductID, CreatedAt: &favoritedAt},
+		{ProductID: &removedProductID, CreatedAt: &favoritedAt},
+	}
+
+	changes := []*model.FavoriteChangeInput{
+		// removed offline before it was favorited again on another device
+		{ProductID: keptProductID, IsFavorite: false, ChangedAt: favoritedAt.Add(-time.Minute)},
+		{ProductID: removedProductID, IsFavorite: false, ChangedAt: favoritedAt.Add(time.Minute)},
+		// already favorite
+		{ProductID: keptProductID, IsFavorite: true, ChangedAt: now},
+		{ProductID: "added", IsFavorite: true, ChangedAt: favoritedAt},
+		{ProductID: "future", IsFavorite: true, ChangedAt: now.Add(time.Hour)},
+		{ProductID: "unknown", IsFavorite: false, ChangedAt: now},
+	}
+
+	items, removedProductIDs := planFavoriteSync(userProductFavorites, changes, now)
+
+	if len(removedProductIDs) != 1 || removedProductIDs[0] != removedProductID {
+		t.Errorf("test failed, only %s should be removed, got %v", removedProductID, removedProductIDs)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("test failed, two favorites should be added, got %d", len(items))
+	}
+
+	if items[0].ProductID != "added" || !items[0].CreatedAt.Equal(favoritedAt) {
+		t.Error("test failed, added favorite should keep the time it was made offline")
+	}
+
+	if items[1].ProductID != "future" || !items[1].CreatedAt.Equal(now) {
+		t.Error("test failed, favorite made in the future should be capped to now")
+	}
+}
+
+func TestSetFavoritesRemove(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var userID = uuid.New().String()
+	var productIDs = []string{"p1", "p2", "p1"}
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+
+	productRepositoryMock.On("DeleteFavoriteProducts", &userID, []string{"p1", "p2"}).Return(nil)
+
+	svc := ProductService{
+		productRepo: productRepositoryMock,
+	}
+
+	result, err := svc.SetFavorites(&userID, productIDs, false, testMarket, accessToken)
+	if err != nil {
+		t.Error("test failed, error should be nil")
+	}
+
+	if len(result.Results) != 2 || len(result.RejectedProductIDs) != 0 {
+		t.Error("test failed, every distinct product should be removed")
+	}
+
+	for _, payload := range result.Results {
+		if payload.IsFavorite || payload.Favorite != nil {
+			t.Error("test failed, removed product should not be favorite")
+		}
+	}
+
+	productRepositoryMock.AssertExpectations(t)
+}
+
+func TestSetFavoritesWithTooManyProducts(t *testing.T) {
+
+	//data test
+
+	var userID = uuid.New().String()
+	var productIDs []string
+	for i := 0; i <= MaxBulkFavoriteProducts; i++ {
+		productIDs = append(productIDs, uuid.New().String())
+	}
+
+	svc := ProductService{}
+
+	_, err := svc.SetFavorites(&userID, productIDs, true, testMarket, uuid.New().String())
+	if !errors.Is(err, ErrTooManyFavoriteProducts) {
+		t.Error("test failed, error should be ErrTooManyFavoriteProducts")
+	}
+}
+
+func TestGetFrequentlyPurchasedProductsLimit(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var userID = uuid.New().String()
+	var tooMany = MaxFrequentlyPurchasedLimit + 1
+
+	tests := []struct {
+		first    *int
+		expected int
+	}{
+		{first: nil, expected: DefaultFrequentlyPurchasedLimit},
+		{first: &tooMany, expected: MaxFrequentlyPurchasedLimit},
+	}
+
+	for _, test := range tests {
+		var productRepositoryMock = &repository.ProductRepositoryMock{}
+
+		productRepositoryMock.On("GetFrequentlyPurchasedProducts", &userID, mock.Anything, float64(FrequentlyPurchasedHalfLifeDays), test.expected).Return([]*model.FrequentlyPurchasedProduct{}, nil)
+
+		svc := ProductService{
+			productRepo: productRepositoryMock,
+		}
+
+		result, err := svc.GetFrequentlyPurchasedProducts(&userID, test.first, testMarket, testParty, accessToken)
+		if err != nil {
+			t.Error("test failed, error should be nil")
+		}
+
+		if len(result) != 0 {
+			t.Error("test failed, result should be empty")
+		}
+
+		productRepositoryMock.AssertExpectations(t)
+	}
+}
+
+func TestGroupTrendingProducts(t *testing.T) {
+
+	//data test
+
+	var first, second, third = "p1", "p2", "p3"
+
+	items := []repository.TrendingProductGroupItem{
+		{GroupID: "c1", TrendingProduct: model.TrendingProduct{ProductID: &first}},
+		{GroupID: "c1", TrendingProduct: model.TrendingProduct{ProductID: &second}},
+		{GroupID: "c2", TrendingProduct: model.TrendingProduct{ProductID: &third}},
+	}
+
+	result := groupTrendingProducts(items)
+
+	if len(result) != 2 {
+		t.Fatal("test failed, result should have two groups")
+	}
+
+	if len(result["c1"]) != 2 || *result["c1"][0].ProductID != first || *result["c1"][1].ProductID != second {
+		t.Error("test failed, group c1 should keep rank order")
+	}
+
+	if len(result["c2"]) != 1 || *result["c2"][0].ProductID != third {
+		t.Error("test failed, group c2 should have its product")
+	}
+}
+
+func TestUpdateTrendingProductCacheParams(t *testing.T) {
+
+	//data test
+
+	var trendingConfig = config.TrendingConfig{
+		WindowHours:      72,
+		HalfLifeHours:    12,
+		QuantityExponent: 0.5,
+		Limit:            15,
+		GroupLimit:       5,
+		RefreshDuration:  30,
+	}
+	var expectedErr = errors.New("database unavailable")
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+
+	productRepositoryMock.On("GetTrendingProducts", mock.MatchedBy(func(params repository.TrendingProductParams) bool {
+		window := time.Since(params.Since)
+		return window >= 72*time.Hour && window < 73*time.Hour &&
+			params.HalfLifeHours == trendingConfig.HalfLifeHours &&
+			params.QuantityExponent == trendingConfig.QuantityExponent &&
+			params.Limit == trendingConfig.Limit
+	})).Return([]model.TrendingProduct{}, expectedErr)
+
+	svc := ProductService{
+		productRepo:    productRepositoryMock,
+		trendingConfig: trendingConfig,
+	}
+
+	err := svc.updateTrendingProductCache()
+	if !errors.Is(err, expectedErr) {
+		t.Error("test failed, error should be returned")
+	}
+
+	productRepositoryMock.AssertExpectations(t)
+}
+
+func TestGetTrendingProductsWithCategoryAndSupplier(t *testing.T) {
+
+	//data test
+
+	var categoryID = uuid.New().String()
+	var supplierID = uuid.New().String()
+
+	svc := ProductService{}
+
+	_, err := svc.GetTrendingProducts(&categoryID, &supplierID, nil, nil, nil, testMarket, testParty, uuid.New().String())
+	if !errors.Is(err, ErrTrendingFilterConflict) {
+		t.Error("test failed, error should be ErrTrendingFilterConflict")
+	}
+}
+
+func TestOffsetCursor(t *testing.T) {
+
+	//data test
+
+	for _, offset := range []int{0, 1, 250} {
+		result, err := decodeOffsetCursor(encodeOffsetCursor(offset))
+		if err != nil || result != offset {
+			t.Errorf("test failed, cursor should point at offset %d", offset)
+		}
+	}
+
+	for _, invalidCursor := range []string{"", "not a cursor", encodeString("12"), encodeString("offset:-1"), encodeString("offset:x")} {
+		_, err := decodeOffsetCursor(invalidCursor)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("test failed, cursor %q should be invalid", invalidCursor)
+		}
+	}
+}
+
+func TestGetTrendingProductsAfterLastPage(t *testing.T) {
+
+	//data test
+
+	var categoryID = uuid.New().String()
+	var after = encodeOffsetCursor(2)
+	var trendingProducts []model.TrendingProduct
+	for i := 0; i < 3; i++ {
+		var id = uuid.New().String()
+		trendingProducts = append(trendingProducts, model.TrendingProduct{ProductID: &id})
+	}
+
+	rCache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 100, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheClient := cache.New(rCache)
+	_ = cacheClient.Set(trendingProductGroupCacheKey(TrendingProductCategoryGroup, categoryID), trendingProducts, 0)
+
+	svc := ProductService{
+		cacheClient: cacheClient,
+	}
+
+	result, err := svc.GetTrendingProducts(&categoryID, nil, nil, nil, &after, testMarket, testParty, uuid.New().String())
+	if err != nil {
+		t.Error("test failed, error should be nil")
+	}
+
+	if len(result.Edges) != 0 || result.PageInfo.HasNextPage {
+		t.Error("test failed, page after the last trending product should be empty")
+	}
+
+	if result.TotalCount != len(trendingProducts) {
+		t.Error("test failed, total count should be the number of trending products")
+	}
+}
+
+func TestGetTrendingProductsClampsPageSize(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var first = MaxTrendingProductsPageSize * 10
+	var trendingProducts []model.TrendingProduct
+	for i := 0; i < MaxTrendingProductsPageSize+50; i++ {
+		var id = uuid.New().String()
+		trendingProducts = append(trendingProducts, model.TrendingProduct{ProductID: &id})
+	}
+
+	rCache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 100, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheClient := cache.New(rCache)
+	_ = cacheClient.Set(TrendingProductCacheKey, trendingProducts, 0)
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	productRepositoryMock.On("GetProducts", mock.Anything, accessToken).Return(model.ProductResponse{}, nil)
+
+	svc := ProductService{
+		cacheClient: cacheClient,
+		productRepo: productRepositoryMock,
+	}
+
+	// no user is signed in, so no favorites are read
+	result, err := svc.GetTrendingProducts(nil, nil, nil, &first, nil, testMarket, testParty, accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	if !result.PageInfo.HasNextPage || result.PageInfo.EndCursor == nil || *result.PageInfo.EndCursor != encodeOffsetCursor(MaxTrendingProductsPageSize-1) {
+		t.Error("test failed, page should end at the largest page size")
+	}
+}
+
+func TestGetSimilarProducts(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var categoryID = "analgesics"
+	var pageSize = "2"
+	var similarityConfig = config.SimilarityConfig{
+		TherapeuticClassWeight: 4,
+		BrandWeight:            2,
+		ManufacturerWeight:     0,
+		CandidateLimit:         10,
+	}
+
+	source := similarityTestProduct("source", "Analgesic", "Panadol", 10)
+	source.PriceSchedule = nil
+	sameClass := similarityTestProduct("b-class", "Analgesic", "Nurofen", 10)
+	sameClassAndBrand := similarityTestProduct("c-both", "Analgesic", "Panadol", 10)
+	sameBrand := similarityTestProduct("d-brand", "Antipyretic", "Panadol", 10)
+	unrelated := similarityTestProduct("a-other", "Antacid", "Gaviscon", 10)
+
+	candidateParams := func(extraFilters map[string]interface{}) repository.ProductParams {
+		return repository.ProductParams{
+			CatalogID:    testMarket.CatalogID,
+			CategoryID:   categoryID,
+			SortBy:       "ID",
+			Page:         "1",
+			PageSize:     "10",
+			ExtraFilters: extraFilters,
+		}
+	}
+
+	var categoryProductRepositoryMock = &repository.CategoryProductRepositoryMock{}
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	var priceScheduleServiceMock = &PriceScheduleServiceMock{}
+
+	categoryProductRepositoryMock.On("GetCategoryProductByProductID", testMarket.CatalogID, "source", accessToken).Return(repository.CategoryProductItem{CategoryID: categoryID, ProductID: "source"}, nil)
+	productRepositoryMock.On("GetProduct", "source", accessToken).Return(*source, nil)
+
+	// candidates sharing a weighted attribute are fetched by it, a zero weight attribute is not fetched
+	productRepositoryMock.On("GetProducts", candidateParams(map[string]interface{}{"xp.TherapeuticClass": "Analgesic"}), accessToken).
+		Return(model.ProductResponse{Items: []*model.ProductItem{sameClass, sameClassAndBrand, source}}, nil).Once()
+	productRepositoryMock.On("GetProducts", candidateParams(map[string]interface{}{"xp.Brand": "Panadol"}), accessToken).
+		Return(model.ProductResponse{Items: []*model.ProductItem{sameClassAndBrand, sameBrand}}, nil).Once()
+	productRepositoryMock.On("GetProducts", candidateParams(nil), accessToken).
+		Return(model.ProductResponse{Items: []*model.ProductItem{unrelated, sameClass}}, nil).Once()
+
+	priceScheduleServiceMock.On("ResolvePriceSchedules", mock.Anything, testMarket.Currency, testParty, accessToken).Return(map[string]*model.PriceScheduleItem{}, nil)
+
+	svc := ProductService{
+		categoryProductRepo:  categoryProductRepositoryMock,
+		productRepo:          productRepositoryMock,
+		priceScheduleService: priceScheduleServiceMock,
+		similarityConfig:     similarityConfig,
+	}
+
+	result, err := svc.GetSimilarProducts("source", nil, nil, &pageSize, testMarket, testParty, accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	if result.Meta == nil || GetInt(result.Meta.TotalCount) != 4 {
+		t.Error("test failed, every candidate but the source should be ranked once")
+	}
+	if len(result.Items) != 2 || GetString(result.Items[0].ID) != "c-both" || GetString(result.Items[1].ID) != "b-class" {
+		t.Error("test failed, first page should have the most similar products")
+	}
+
+	productRepositoryMock.AssertExpectations(t)
+	priceScheduleServiceMock.AssertExpectations(t)
+}
+
+func TestGetProductV2(t *testing.T) {
+
+	//data test
+
+	var accessToken = uuid.New().String()
+	var productID, otherProductID = "p1", "p2"
+	var defaultPriceScheduleID, priceScheduleID = "ps-default", "ps-wholesale"
+	var middlewarePriceScheduleID = "ps-other-currency"
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	var priceScheduleServiceMock = &PriceScheduleServiceMock{}
+
+	productParams := func(id string) repository.ProductParams {
+		return repository.ProductParams{CatalogID: testMarket.CatalogID, ExtraFilters: map[string]interface{}{"ID": id}, Page: "1", PageSize: "1"}
+	}
+	productRepositoryMock.On("GetProducts", productParams(productID), accessToken).Return(model.ProductResponse{Items: []*model.ProductItem{{ID: &productID}}}, nil)
+	productRepositoryMock.On("GetProducts", productParams(otherProductID), accessToken).Return(model.ProductResponse{}, nil)
+	productRepositoryMock.On("GetProductV2", productID, accessToken).Return(model.LatestProductItems{
+		ID:            &productID,
+		Product:       &model.ProductItem{ID: &productID, DefaultPriceScheduleID: &defaultPriceScheduleID},
+		PriceSchedule: &model.NewProductPriceSchedule{ID: &middlewarePriceScheduleID},
+	}, nil)
+	priceScheduleServiceMock.On("ResolvePriceSchedules", map[string]string{productID: defaultPriceScheduleID}, testMarket.Currency, testParty, accessToken).
+		Return(map[string]*model.PriceScheduleItem{productID: {ID: &priceScheduleID}}, nil)
+
+	svc := ProductService{
+		productRepo:          productRepositoryMock,
+		priceScheduleService: priceScheduleServiceMock,
+	}
+
+	result, err := svc.GetProductV2(productID, nil, testMarket, testParty, accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+	if result.PriceSchedule == nil || GetString(result.PriceSchedule.ID) != priceScheduleID {
+		t.Error("test failed, product should be priced at the party's schedule in the market's currency")
+	}
+
+	// products outside the market's catalog are not returned
+	_, err = svc.GetProductV2(otherProductID, nil, testMarket, testParty, accessToken)
+	if !errors.Is(err, ErrProductNotFound) {
+		t.Error("test failed, error should be ErrProductNotFound")
+	}
+}
+
+func TestGetCatalogFacetsKeepsFacetsOnError(t *testing.T) {
+
+	//data test
+	var accessToken = uuid.New().String()
+	var facetName = "Brand"
+	var facets = []*model.ListFacet{{Name: &facetName}}
+
+	rCache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 100, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal("test failed: cache not created:", err)
+	}
+
+	var productFacetRepositoryMock = &repository.ProductFacetRepositoryMock{}
+	productFacetRepositoryMock.On("GetProductFacets", accessToken).Return([]repository.ProductFacetDefinition(nil), errors.New("order cloud unavailable"))
+
+	svc := ProductService{
+		facetService: &FacetService{
+			cacheClient:      cache.New(rCache),
+			productFacetRepo: productFacetRepositoryMock,
+		},
+	}
+
+	result := svc.getCatalogFacets(repository.ProductParams{CatalogID: testMarket.CatalogID}, facets, accessToken)
+	if len(result) != 1 || result[0] != facets[0] {
+		t.Error("test failed: facets returned by order cloud must be kept when facet definitions cannot be fetched")
+	}
+
+	productFacetRepositoryMock.AssertExpectations(t)
+}
+
+func TestGetIndexedSearchProducts(t *testing.T) {
+
+	//data test
+	var accessToken = uuid.New().String()
+	var brandFacet = "Brand"
+
+	var documents []search.Document
+	for i := 0; i < 150; i++ {
+		documents = append(documents, search.Document{ID: fmt.Sprintf("p%03d", i), Name: fmt.Sprintf("Panadol %03d", i)})
+	}
+	index := search.New(nil)
+	index.Replace(documents)
+
+	// every batch returns its products along with one facet value for each of them
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	expectBatch := func(ids []string, extraFilters map[string]interface{}) {
+		filters := make(map[string]interface{})
+		for key, value := range extraFilters {
+			filters[key] = value
+		}
+		filters["ID"] = strings.Join(ids, "|")
+
+		var items []*model.ProductItem
+		for i := range ids {
+			items = append(items, &model.ProductItem{ID: &ids[i]})
+		}
+		count, value := len(ids), "Panadol"
+		productRepositoryMock.On("GetProducts", repository.ProductParams{
+			CatalogID:    testMarket.CatalogID,
+			ExtraFilters: filters,
+			Page:         "1",
+			PageSize:     repository.GetStringFromInt(len(ids)),
+		}, accessToken).Return(model.ProductResponse{
+			Meta:  &model.ProductMeta{Facets: []*model.ListFacet{{Name: &brandFacet, Values: []*model.ListFacetValue{{Value: &value, Count: &count}}}}},
+			Items: items,
+		}, nil).Once()
+	}
+
+	var secondPageIDs []string
+	for i := 60; i < 120; i++ {
+		secondPageIDs = append(secondPageIDs, fmt.Sprintf("p%03d", i))
+	}
+	expectBatch(secondPageIDs[:ProductBatchSize], nil)
+	expectBatch(secondPageIDs[ProductBatchSize:], nil)
+
+	// favorites ranked below the first hundred are kept
+	var favoriteFilter = map[string]interface{}{"ID": "p130|p120|missing"}
+	expectBatch([]string{"p120", "p130"}, favoriteFilter)
+
+	svc := ProductService{
+		productRepo:        productRepositoryMock,
+		searchIndexService: &SearchIndexService{enabled: true, indexes: map[string]*search.Index{testMarket.CatalogID: index}},
+	}
+
+	result, err := svc.getIndexedSearchProducts(repository.ProductParams{CatalogID: testMarket.CatalogID, Search: "panadol", Page: "2", PageSize: "60"}, accessToken)
+	if err != nil {
+		t.Fatal("test failed: error should be nil, got", err)
+	}
+	if *result.Meta.TotalCount != 150 || *result.Meta.TotalPages != 3 || len(result.Items) != 60 || GetString(result.Items[0].ID) != "p060" {
+		t.Errorf("test failed: every ranked product must be paged, got %d of %d", len(result.Items), *result.Meta.TotalCount)
+	}
+	if len(result.Meta.Facets) != 1 || *result.Meta.Facets[0].Values[0].Count != 60 {
+		t.Error("test failed: facets of the page's batches must be merged")
+	}
+
+	result, err = svc.getIndexedSearchProducts(repository.ProductParams{CatalogID: testMarket.CatalogID, Search: "panadol", ExtraFilters: favoriteFilter, Page: "1", PageSize: "10"}, accessToken)
+	if err != nil {
+		t.Fatal("test failed: error should be nil, got", err)
+	}
+	if *result.Meta.TotalCount != 2 || len(result.Items) != 2 || GetString(result.Items[0].ID) != "p120" {
+		t.Error("test failed: id filter must be applied to every ranked product before paging")
+	}
+
+	productRepositoryMock.AssertExpectations(t)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+)
+
+// similarProduct is a candidate product with its similarity to the source product
+type similarProduct struct {
+	product *model.ProductItem
+	score   float64
+}
+
+// rankSimilarProducts orders candidates by similarity to source, most similar first. Ties are broken by product
+// id so that pages stay stable, and the source product itself is never returned
+func rankSimilarProducts(source *model.ProductItem, candidates []*model.ProductItem, similarityConfig config.SimilarityConfig) []*model.ProductItem {
+	similarProducts := make([]similarProduct, 0, len(candidates))
+	for _, candidate := range candidates {
+		if GetString(candidate.ID) == GetString(source.ID) {
+			continue
+		}
+		similarProducts = append(similarProducts, similarProduct{
+			product: candidate,
+			score:   scoreSimilarProduct(source, candidate, similarityConfig),
+		})
+	}
+
+	sort.SliceStable(similarProducts, func(i, j int) bool {
+		if similarProducts[i].score != similarProducts[j].score {
+			return similarProducts[i].score > similarProducts[j].score
+		}
+		return GetString(similarProducts[i].product.ID) < GetString(similarProducts[j].product.ID)
+	})
+
+	products := make([]*model.ProductItem, len(similarProducts))
+	for i, similar := range similarProducts {
+		products[i] = similar.product
+	}
+	return products
+}
+
+// similarCandidateFilters returns the product filters candidates of source are fetched with: one per weighted
+// attribute source has, then no filter for the rest of the category. Values order cloud would read as filter
+// operators are left to the unfiltered fetch
+func similarCandidateFilters(source *model.ProductItem, similarityConfig config.SimilarityConfig) []map[string]interface{} {
+	var filters []map[string]interface{}
+
+	if xp := source.Xp; xp != nil {
+		attributes := []struct {
+			field  string
+			value  *string
+			weight float64
+		}{
+			{field: "xp.TherapeuticClass", value: xp.TherapeuticClass, weight: similarityConfig.TherapeuticClassWeight},
+			{field: "xp.Manufacturer", value: xp.Manufacturer, weight: similarityConfig.ManufacturerWeight},
+			{field: "xp.Brand", value: xp.Brand, weight: similarityConfig.BrandWeight},
+			{field: "xp.ProductType", value: xp.ProductType, weight: similarityConfig.ProductTypeWeight},
+		}
+		for _, attribute := range attributes {
+			value := strings.TrimSpace(GetString(attribute.value))
+			if attribute.weight <= 0 || value == "" || strings.ContainsAny(value, "*|!<>") {
+				continue
+			}
+			filters = append(filters, map[string]interface{}{attribute.field: value})
+		}
+	}
+
+	return append(filters, nil)
+}
+
+// scoreSimilarProduct sums the weights of the attributes candidate shares with source
+func scoreSimilarProduct(source, candidate *model.ProductItem, similarityConfig config.SimilarityConfig) float64 {
+	var score float64
+
+	sourceXp, candidateXp := source.Xp, candidate.Xp
+	if sourceXp != nil && candidateXp != nil {
+		if sameAttribute(sourceXp.TherapeuticClass, candidateXp.TherapeuticClass) {
+			score += similarityConfig.TherapeuticClassWeight
+		}
+		if sameAttribute(sourceXp.Manufacturer, candidateXp.Manufacturer) {
+			score += similarityConfig.ManufacturerWeight
+		}
+		if sameAttribute(sourceXp.Brand, candidateXp.Brand) {
+			score += similarityConfig.BrandWeight
+		}
+		if sameAttribute(sourceXp.ProductType, candidateXp.ProductType) {
+			score += similarityConfig.ProductTypeWeight
+		}
+		if sameUnitOfMeasure(sourceXp.UnitOfMeasure, candidateXp.UnitOfMeasure) {
+			score += similarityConfig.UnitOfMeasureWeight
+		}
+	}
+
+	if samePriceBand(source.PriceSchedule, candidate.PriceSchedule, similarityConfig.PriceBandRatio) {
+		score += similarityConfig.PriceBandWeight
+	}
+
+	return score
+}
+
+// sameAttribute reports whether both attributes are set and equal ignoring case and surrounding spaces
+func sameAttribute(a, b *string) bool {
+	valueA := strings.TrimSpace(GetString(a))
+	valueB := strings.TrimSpace(GetString(b))
+	return valueA != "" && strings.EqualFold(valueA, valueB)
+}
+
+func sameUnitOfMeasure(a, b *model.UnitOfMeasure) bool {
+	if a == nil || b == nil || !sameAttribute(a.Unit, b.Unit) {
+		return false
+	}
+	return GetInt(a.Qty) == GetInt(b.Qty)
+}
+
+// samePriceBand reports whether the candidate's unit price is within ratio of the source's unit price
+func samePriceBand(source, candidate *model.PriceScheduleItem, ratio float64) bool {
+	sourcePrice, ok := basePrice(source)
+	if !ok || sourcePrice <= 0 {
+		return false
+	}
+	candidatePrice, ok := basePrice(candidate)
+	if !ok {
+		return false
+	}
+	return math.Abs(candidatePrice-sourcePrice) <= sourcePrice*ratio
+}
+
+// basePrice returns the price of the smallest quantity price break
+func basePrice(priceSchedule *model.PriceScheduleItem) (float64, bool) {
+	if priceSchedule == nil {
+		return 0, false
+	}
+
+	var price *float64
+	minQuantity := math.MaxInt
+	for _, priceBreak := range priceSchedule.PriceBreaks {
+		if priceBreak == nil || priceBreak.Price == nil {
+			continue
+		}
+		if quantity := GetInt(priceBreak.Quantity); quantity < minQuantity {
+			minQuantity = quantity
+			price = priceBreak.Price
+		}
+	}
+	if price == nil {
+		return 0, false
+	}
+	return *price, true
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+)
+
+func similarityTestProduct(id, therapeuticClass, brand string, price float64) *model.ProductItem {
+	quantity := 1
+	unit := "Box"
+	return &model.ProductItem{
+		ID: &id,
+		Xp: &model.ProductXp{
+			TherapeuticClass: &therapeuticClass,
+			Brand:            &brand,
+			UnitOfMeasure:    &model.UnitOfMeasure{Qty: &quantity, Unit: &unit},
+		},
+		PriceSchedule: &model.PriceScheduleItem{
+			PriceBreaks: []*model.PriceBreak{{Quantity: &quantity, Price: &price}},
+		},
+	}
+}
+
+func TestScoreSimilarProduct(t *testing.T) {
+
+	//data test
+	var similarityConfig = config.SimilarityConfig{
+		TherapeuticClassWeight: 4,
+		BrandWeight:            2,
+		UnitOfMeasureWeight:    1,
+		PriceBandWeight:        1,
+		PriceBandRatio:         0.25,
+	}
+	var source = similarityTestProduct("source", "Analgesic", "Panadol", 10)
+
+	var tests = []struct {
+		name      string
+		candidate *model.ProductItem
+		score     float64
+	}{
+		{"all attributes", similarityTestProduct("p1", "analgesic ", "PANADOL", 12.5), 8},
+		{"outside price band", similarityTestProduct("p2", "Analgesic", "Panadol", 12.6), 7},
+		{"different class", similarityTestProduct("p3", "Antibiotic", "Panadol", 10), 4},
+		{"empty attributes", similarityTestProduct("p4", "", "", 10), 2},
+		{"no xp or price", &model.ProductItem{}, 0},
+	}
+
+	for _, test := range tests {
+		score := scoreSimilarProduct(source, test.candidate, similarityConfig)
+		if score != test.score {
+			t.Error("test failed, "+test.name+": expected score", test.score, "got", score)
+		}
+	}
+}
+
+func TestRankSimilarProducts(t *testing.T) {
+
+	//data test
+	var similarityConfig = config.SimilarityConfig{TherapeuticClassWeight: 4, BrandWeight: 2}
+	var source = similarityTestProduct("source", "Analgesic", "Panadol", 10)
+	var candidates = []*model.ProductItem{
+		similarityTestProduct("c", "Antibiotic", "Other", 10),
+		similarityTestProduct("source", "Analgesic", "Panadol", 10),
+		similarityTestProduct("b", "Analgesic", "Other", 10),
+		similarityTestProduct("a", "Antibiotic", "Other", 10),
+		similarityTestProduct("d", "Analgesic", "Panadol", 10),
+	}
+
+	ranked := rankSimilarProducts(source, candidates, similarityConfig)
+
+	expected := []string{"d", "b", "a", "c"}
+	if len(ranked) != len(expected) {
+		t.Error("test failed: source product must be excluded, got", len(ranked), "products")
+		return
+	}
+	for i, id := range expected {
+		if *ranked[i].ID != id {
+			t.Error("test failed: expected", id, "at position", i, "got", *ranked[i].ID)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedExpression is returned for promotion expressions outside the subset evaluated here
+var ErrUnsupportedExpression = errors.New("unsupported promotion expression")
+
+// promotionExpressionEnv is what promotion expressions are evaluated against: an order of the single line being
+// priced, placed by the user of the buyer
+type promotionExpressionEnv struct {
+	ProductID    string
+	SupplierID   string
+	Quantity     int
+	UnitPrice    float64
+	LineSubtotal float64
+	BuyerID      string
+	UserID       string
+}
+
+// evaluatePromotionExpression evaluates the subset of order cloud promotion expressions that can be answered for a
+// single line: number, string and boolean literals, arithmetic, comparisons, and, or, not, the order and item
+// properties of the env and the items.any, all, count, quantity and total functions. Anything else, such as xp
+// properties, returns ErrUnsupportedExpression
+func evaluatePromotionExpression(expression string, env promotionExpressionEnv) (interface{}, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &expressionParser{tokens: tokens, env: env}
+	value, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrUnsupportedExpression, parser.peek().text)
+	}
+
+	return value, nil
+}
+
+const (
+	tokenEOF = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind int
+	text string
+}
+
+// tokenizeExpression splits an expression into numbers, single quoted strings, dotted identifiers and operators
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(expression) && isDigit(expression[i+1])):
+			start := i
+			for i < len(expression) && (isDigit(expression[i]) || expression[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: expression[start:i]})
+		case c == '\'':
+			end := strings.IndexByte(expression[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string", ErrUnsupportedExpression)
+			}
+			tokens = append(tokens, expressionToken{kind: tokenString, text: expression[i+1 : i+1+end]})
+			i += end + 2
+		case isIdentStart(c):
+			start := i
+			for i < len(expression) && (isIdentStart(expression[i]) || isDigit(expression[i]) || expression[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenIdent, text: expression[start:i]})
+		case strings.HasPrefix(expression[i:], "<=") || strings.HasPrefix(expression[i:], ">=") ||
+			strings.HasPrefix(expression[i:], "<>") || strings.HasPrefix(expression[i:], "!="):
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: expression[i : i+2]})
+			i += 2
+		case strings.IndexByte("=<>+-*/(),", c) >= 0:
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q", ErrUnsupportedExpression, string(c))
+		}
+	}
+
+	return append(tokens, expressionToken{kind: tokenEOF}), nil
+}
+
+// expressionParser evaluates while it parses. Conditions of the items functions are evaluated against the single
+// line, so they are parsed with inItems set and read line properties without the item prefix
+type expressionParser struct {
+	tokens  []expressionToken
+	pos     int
+	env     promotionExpressionEnv
+	inItems bool
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() expressionToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// accept consumes the next token when it is the operator or keyword, keywords in any case
+func (p *expressionParser) accept(text string) bool {
+	token := p.peek()
+	if (token.kind == tokenOperator && token.text == text) || (token.kind == tokenIdent && strings.EqualFold(token.text, text)) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(left, right, "or"); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (interface{}, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(left, right, "and"); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (interface{}, error) {
+	if p.accept("not") {
+		value, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: not of a non boolean", ErrUnsupportedExpression)
+		}
+		return !b, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (interface{}, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.peek()
+	if token.kind != tokenOperator {
+		return left, nil
+	}
+	switch token.text {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		p.next()
+	default:
+		return left, nil
+	}
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return compare(left, right, token.text)
+}
+
+func (p *expressionParser) parseAdditive() (interface{}, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := p.peek().text
+		if p.peek().kind != tokenOperator || (operator != "+" && operator != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(left, right, operator); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *expressionParser) parseTerm() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := p.peek().text
+		if p.peek().kind != tokenOperator || (operator != "*" && operator != "/") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(left, right, operator); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *expressionParser) parseUnary() (interface{}, error) {
+	if p.accept("-") {
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic(0.0, value, "-")
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (interface{}, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed number %q", ErrUnsupportedExpression, token.text)
+		}
+		return value, nil
+	case tokenString:
+		return token.text, nil
+	case tokenIdent:
+		if p.accept("(") {
+			return p.parseItemsFunction(token.text)
+		}
+		return p.property(token.text)
+	case tokenOperator:
+		if token.text == "(" {
+			value, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("%w: missing )", ErrUnsupportedExpression)
+			}
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", ErrUnsupportedExpression, token.text)
+}
+
+// parseItemsFunction evaluates an items function over the single line, its optional condition selecting the line
+func (p *expressionParser) parseItemsFunction(name string) (interface{}, error) {
+	if p.inItems {
+		return nil, fmt.Errorf("%w: nested %s", ErrUnsupportedExpression, name)
+	}
+
+	matches := true
+	if !p.accept(")") {
+		p.inItems = true
+		condition, err := p.parseOr()
+		p.inItems = false
+		if err != nil {
+			return nil, err
+		}
+		b, ok := condition.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s condition is not boolean", ErrUnsupportedExpression, name)
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("%w: missing )", ErrUnsupportedExpression)
+		}
+		matches = b
+	}
+
+	switch strings.ToLower(name) {
+	case "items.any", "items.all":
+		return matches, nil
+	case "items.count":
+		if matches {
+			return 1.0, nil
+		}
+	case "items.quantity":
+		if matches {
+			return float64(p.env.Quantity), nil
+		}
+	case "items.total":
+		if matches {
+			return p.env.LineSubtotal, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: function %s", ErrUnsupportedExpression, name)
+	}
+	return 0.0, nil
+}
+
+// property returns a property of the order or its line, line properties are read with the item prefix outside the
+// items functions and without it inside them
+func (p *expressionParser) property(name string) (interface{}, error) {
+	path := strings.ToLower(name)
+	switch path {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	if !p.inItems {
+		switch path {
+		case "order.subtotal":
+			return p.env.LineSubtotal, nil
+		case "order.lineitemcount":
+			return 1.0, nil
+		case "order.fromcompanyid":
+			return p.env.BuyerID, nil
+		case "order.fromuserid":
+			return p.env.UserID, nil
+		}
+		if !strings.HasPrefix(path, "item.") {
+			return nil, fmt.Errorf("%w: property %s", ErrUnsupportedExpression, name)
+		}
+		path = strings.TrimPrefix(path, "item.")
+	}
+
+	switch path {
+	case "productid", "product.id":
+		return p.env.ProductID, nil
+	case "supplierid", "product.defaultsupplierid":
+		return p.env.SupplierID, nil
+	case "quantity":
+		return float64(p.env.Quantity), nil
+	case "unitprice":
+		return p.env.UnitPrice, nil
+	case "linesubtotal":
+		return p.env.LineSubtotal, nil
+	}
+	return nil, fmt.Errorf("%w: property %s", ErrUnsupportedExpression, name)
+}
+
+func logical(left, right interface{}, operator string) (interface{}, error) {
+	l, lok := left.(bool)
+	r, rok := right.(bool)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%w: %s of non booleans", ErrUnsupportedExpression, operator)
+	}
+	if operator == "and" {
+		return l && r, nil
+	}
+	return l || r, nil
+}
+
+// compare compares numbers, or strings case insensitively for equality only
+func compare(left, right interface{}, operator string) (interface{}, error) {
+	if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: string compared to a non string", ErrUnsupportedExpression)
+		}
+		switch operator {
+		case "=":
+			return strings.EqualFold(l, r), nil
+		case "<>", "!=":
+			return !strings.EqualFold(l, r), nil
+		}
+		return nil, fmt.Errorf("%w: strings compared with %s", ErrUnsupportedExpression, operator)
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%w: %s of non numbers", ErrUnsupportedExpression, operator)
+	}
+	switch operator {
+	case "=":
+		return l == r, nil
+	case "<>", "!=":
+		return l != r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	default:
+		return l >= r, nil
+	}
+}
+
+func arithmetic(left, right interface{}, operator string) (interface{}, error) {
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%w: %s of non numbers", ErrUnsupportedExpression, operator)
+	}
+	switch operator {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		if r == 0 {
+			return nil, fmt.Errorf("%w: division by zero", ErrUnsupportedExpression)
+		}
+		return l / r, nil
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
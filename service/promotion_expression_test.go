@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEvaluatePromotionExpression(t *testing.T) {
+
+	//data test
+	var env = promotionExpressionEnv{
+		ProductID:    "p1",
+		SupplierID:   "s1",
+		Quantity:     4,
+		UnitPrice:    2.5,
+		LineSubtotal: 10,
+		BuyerID:      "b1",
+		UserID:       "42",
+	}
+
+	var tests = []struct {
+		expression string
+		expected   interface{}
+		err        error
+	}{
+		{expression: "order.Subtotal >= 10", expected: true},
+		{expression: "order.Subtotal > 10", expected: false},
+		{expression: "item.ProductID = 'P1' and item.Quantity > 3", expected: true},
+		{expression: "item.Product.DefaultSupplierID <> 's1' or order.FromUserID = '42'", expected: true},
+		{expression: "not (order.FromCompanyID = 'b2')", expected: true},
+		{expression: "NOT item.Quantity < 4 AND true", expected: true},
+		{expression: "items.any(ProductID = 'p1')", expected: true},
+		{expression: "items.all(Product.ID = 'p2')", expected: false},
+		{expression: "items.count()", expected: 1.0},
+		{expression: "items.quantity(SupplierID = 's1')", expected: 4.0},
+		{expression: "items.total(ProductID = 'p2')", expected: 0.0},
+		{expression: "items.total() * .1 + 2", expected: 3.0},
+		{expression: "order.Subtotal - item.UnitPrice * 2", expected: 5.0},
+		{expression: "-item.UnitPrice / 2", expected: -1.25},
+		{expression: "order.LineItemCount = 1", expected: true},
+		{expression: "order.xp.Channel = 'web'", err: ErrUnsupportedExpression},
+		{expression: "items.any(item.ProductID = 'p1')", err: ErrUnsupportedExpression},
+		{expression: "items.any(items.any())", err: ErrUnsupportedExpression},
+		{expression: "items.sum(Quantity)", err: ErrUnsupportedExpression},
+		{expression: "order.Subtotal / 0", err: ErrUnsupportedExpression},
+		{expression: "item.ProductID > 'a'", err: ErrUnsupportedExpression},
+		{expression: "item.Quantity = 'four'", err: ErrUnsupportedExpression},
+		{expression: "order.Subtotal and true", err: ErrUnsupportedExpression},
+		{expression: "(order.Subtotal > 1", err: ErrUnsupportedExpression},
+		{expression: "item.ProductID = 'p1", err: ErrUnsupportedExpression},
+		{expression: "order.Subtotal > 1 1", err: ErrUnsupportedExpression},
+		{expression: "1.2.3", err: ErrUnsupportedExpression},
+		{expression: "order.Subtotal ~ 1", err: ErrUnsupportedExpression},
+	}
+
+	for _, test := range tests {
+		result, err := evaluatePromotionExpression(test.expression, env)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test failed: %s, expected error %v, got %v", test.expression, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test failed: %s, error should be nil, got %v", test.expression, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("test failed: %s, expected %v, got %v", test.expression, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// promotion types of ProductPromotionsXp.Type
+	PromotionTypePercentage   = "Percentage"
+	PromotionTypeFixedAmount  = "FixedAmount"
+	PromotionTypeFreeShipping = "FreeShipping"
+	PromotionTypeBOGO         = "BOGO"
+
+	// promotion scopes of ProductPromotionsXp.AppliesTo
+	PromotionAppliesToSpecificSupplier = "SpecificSupplier"
+	PromotionAppliesToSpecificSKUs     = "SpecificSKUs"
+
+	// minimum requirements of ProductPromotionsXpMinReq.Type
+	PromotionMinReqItemQty  = "MinItemQty"
+	PromotionMinReqPurchase = "MinPurchase"
+
+	// BOGO discount types of ProductPromotionsXpBogo.Type
+	BOGOTypePercentage = "Percentage"
+	BOGOTypeAmount     = "Amount"
+)
+
+// PromotionService evaluates the promotions carried in product xp. Eligibility and value are read from the
+// promotion xp and from EligibleExpression and ValueExpression as far as they can be evaluated for the product
+// alone, see evaluatePromotionExpression. Redemptions per user are counted from recorded order events
+type PromotionService struct {
+	promotionRedemptionRepo repository.IPromotionRedemptionRepository
+}
+
+func NewPromotionService(db *sqlx.DB, dbConfig config.DBConfig) *PromotionService {
+	return &PromotionService{
+		promotionRedemptionRepo: repository.NewPromotionRedemptionRepository(db, dbConfig),
+	}
+}
+
+// promotionRedeemer is who promotions are evaluated for, redemptionCounts holding the user's redemptions of
+// promotions limited per user
+type promotionRedeemer struct {
+	userID           string
+	buyerID          string
+	redemptionCounts map[string]int
+}
+
+// GetApplicablePromotions returns the promotions of the product the user of the buyer can redeem on quantity units
+// at the current time in the market, largest discount first. A quantity the product cannot be bought in has none
+func (svc *PromotionService) GetApplicablePromotions(product *model.ProductItem, quantity int, userID, buyerID *string, market config.MarketConfig) ([]*model.PromotionDiscount, error) {
+	redeemer := promotionRedeemer{userID: GetString(userID), buyerID: GetString(buyerID)}
+
+	var limitedPromotionIDs []string
+	if product.Xp != nil && redeemer.userID != "" {
+		for _, promotion := range product.Xp.Promotions {
+			if promotion != nil && GetString(promotion.ID) != "" && GetFloat(promotion.RedemptionLimitPerUser) > 0 {
+				limitedPromotionIDs = append(limitedPromotionIDs, GetString(promotion.ID))
+			}
+		}
+	}
+	if len(limitedPromotionIDs) > 0 {
+		redemptionCounts, err := svc.promotionRedemptionRepo.CountUserPromotionRedemptions(redeemer.userID, limitedPromotionIDs)
+		if err != nil {
+			return nil, err
+		}
+		redeemer.redemptionCounts = redemptionCounts
+	}
+
+	return evaluatePromotions(product, quantity, redeemer, market, time.Now()), nil
+}
+
+// GetBestPromotion returns the applicable promotion with the largest discount, nil when none applies
+func (svc *PromotionService) GetBestPromotion(product *model.ProductItem, quantity int, userID, buyerID *string, market config.MarketConfig) (*model.PromotionDiscount, error) {
+	promotions, err := svc.GetApplicablePromotions(product, quantity, userID, buyerID, market)
+	if err != nil || len(promotions) == 0 {
+		return nil, err
+	}
+	return promotions[0], nil
+}
+
+// evaluatePromotions returns the discount of every promotion applying to quantity units of the product at now, see
+// isPromotionApplicable, ordered by discount descending and then promotion id. Promotions whose expressions cannot
+// be evaluated are left out
+func evaluatePromotions(product *model.ProductItem, quantity int, redeemer promotionRedeemer, market config.MarketConfig, now time.Time) []*model.PromotionDiscount {
+	discounts := []*model.PromotionDiscount{}
+	if product.Xp == nil || len(product.Xp.Promotions) == 0 {
+		return discounts
+	}
+
+	quote, err := quotePrice(product, quantity, market, now)
+	if err != nil {
+		return discounts
+	}
+
+	env := promotionExpressionEnv{
+		ProductID:    GetString(product.ID),
+		SupplierID:   GetString(product.DefaultSupplierID),
+		Quantity:     quote.Quantity,
+		UnitPrice:    quote.UnitPrice,
+		LineSubtotal: quote.ExtendedPrice,
+		BuyerID:      redeemer.buyerID,
+		UserID:       redeemer.userID,
+	}
+
+	for _, promotion := range product.Xp.Promotions {
+		if promotion == nil || !isPromotionApplicable(promotion, product, quote, redeemer, env, market.Location(), now) {
+			continue
+		}
+
+		discount, err := promotionDiscount(promotion, product, quote, env)
+		if err != nil {
+			continue
+		}
+		discountedPrice := discount
+		discountedPrice.MinorUnits = quote.ExtendedPriceMoney.MinorUnits - discount.MinorUnits
+		discounts = append(discounts, &model.PromotionDiscount{
+			Promotion:            promotion,
+			Discount:             discount.Float64(),
+			DiscountedPrice:      discountedPrice.Float64(),
+			RequiresCode:         !GetBool(promotion.Xp.Automatic),
+			DiscountMoney:        &discount,
+			DiscountedPriceMoney: &discountedPrice,
+		})
+	}
+
+	sort.SliceStable(discounts, func(i, j int) bool {
+		if discounts[i].DiscountMoney.MinorUnits != discounts[j].DiscountMoney.MinorUnits {
+			return discounts[i].DiscountMoney.MinorUnits > discounts[j].DiscountMoney.MinorUnits
+		}
+		return GetString(discounts[i].Promotion.ID) < GetString(discounts[j].Promotion.ID)
+	})
+
+	return discounts
+}
+
+// isPromotionApplicable checks, in order, that the promotion is running at now in the market's timezone, has
+// redemptions left overall and for the user, is open to the user's buyer, is scoped to the product's supplier or
+// SKU if at all, that the quote meets its minimum requirement and that its EligibleExpression evaluates to true
+func isPromotionApplicable(promotion *model.ProductPromotions, product *model.ProductItem, quote *model.PriceQuote, redeemer promotionRedeemer, env promotionExpressionEnv, location *time.Location, now time.Time) bool {
+	if promotion.Xp == nil {
+		return false
+	}
+
+	startDate, expirationDate := GetString(promotion.StartDate), GetString(promotion.ExpirationDate)
+	if (startDate != "" || expirationDate != "") && !isWithinWindow(startDate, expirationDate, location, now) {
+		return false
+	}
+
+	if redemptionLimit, err := strconv.Atoi(strings.TrimSpace(GetString(promotion.RedemptionLimit))); err == nil && redemptionLimit > 0 {
+		if GetInt(promotion.RedemptionCount) >= redemptionLimit {
+			return false
+		}
+	}
+
+	if limitPerUser := GetFloat(promotion.RedemptionLimitPerUser); limitPerUser > 0 {
+		if float64(redeemer.redemptionCounts[GetString(promotion.ID)]) >= limitPerUser {
+			return false
+		}
+	}
+
+	if !GetBool(promotion.AllowAllBuyers) && len(promotion.Xp.Buyers) > 0 && !containsString(promotion.Xp.Buyers, redeemer.buyerID) {
+		return false
+	}
+
+	if GetBool(promotion.Xp.ScopeToSupplier) || GetString(promotion.Xp.AppliesTo) == PromotionAppliesToSpecificSupplier {
+		if GetString(promotion.Xp.Supplier) == "" || GetString(promotion.Xp.Supplier) != GetString(product.DefaultSupplierID) {
+			return false
+		}
+	}
+
+	if GetString(promotion.Xp.AppliesTo) == PromotionAppliesToSpecificSKUs || len(promotion.Xp.SKUs) > 0 {
+		if !containsString(promotion.Xp.SKUs, GetString(product.ID)) {
+			return false
+		}
+	}
+
+	if GetString(promotion.Xp.Type) == PromotionTypeBOGO {
+		bogo := promotion.Xp.Bogo
+		if bogo == nil || bogo.BuySku == nil || GetString(bogo.BuySku.Sku) != GetString(product.ID) {
+			return false
+		}
+		if quote.Quantity < getBOGOQuantity(bogo.BuySku) {
+			return false
+		}
+	}
+
+	if minReq := promotion.Xp.MinReq; minReq != nil && minReq.Int != nil {
+		switch GetString(minReq.Type) {
+		case PromotionMinReqItemQty:
+			if quote.Quantity < *minReq.Int {
+				return false
+			}
+		case PromotionMinReqPurchase:
+			if quote.ExtendedPriceMoney.Decimal().Cmp(big.NewRat(int64(*minReq.Int), 1)) < 0 {
+				return false
+			}
+		}
+	}
+
+	if expression := strings.TrimSpace(GetString(promotion.EligibleExpression)); expression != "" {
+		eligible, err := evaluatePromotionExpression(expression, env)
+		if err != nil || eligible != true {
+			return false
+		}
+	}
+
+	return true
+}
+
+// promotionDiscount returns the amount the promotion takes off the quote's extended price, its ValueExpression when
+// it has one. Otherwise free shipping takes nothing off the price, and a BOGO discounts the units got free only when
+// they are of the same product
+func promotionDiscount(promotion *model.ProductPromotions, product *model.ProductItem, quote *model.PriceQuote, env promotionExpressionEnv) (model.Money, error) {
+	discount := new(big.Rat)
+	value := int64(GetInt(promotion.Xp.Value))
+
+	if expression := strings.TrimSpace(GetString(promotion.ValueExpression)); expression != "" {
+		result, err := evaluatePromotionExpression(expression, env)
+		if err != nil {
+			return model.Money{}, err
+		}
+		amount, ok := result.(float64)
+		if !ok {
+			return model.Money{}, fmt.Errorf("%w: value is not a number", ErrUnsupportedExpression)
+		}
+		return discountMoney(decimalFromFloat(amount), quote), nil
+	}
+
+	switch GetString(promotion.Xp.Type) {
+	case PromotionTypePercentage:
+		discount.Mul(quote.ExtendedPriceMoney.Decimal(), big.NewRat(value, 100))
+	case PromotionTypeFixedAmount:
+		discount.SetInt64(value)
+	case PromotionTypeBOGO:
+		discount = bogoDiscount(promotion.Xp.Bogo, product, quote)
+	}
+
+	return discountMoney(discount, quote), nil
+}
+
+// discountMoney rounds the discount to the minor units of the quote's currency, from nothing up to the extended price
+func discountMoney(discount *big.Rat, quote *model.PriceQuote) model.Money {
+	money := moneyFromDecimal(discount, quote.ExtendedPriceMoney.Currency)
+	if money.MinorUnits < 0 {
+		money.MinorUnits = 0
+	}
+	if money.MinorUnits > quote.ExtendedPriceMoney.MinorUnits {
+		money.MinorUnits = quote.ExtendedPriceMoney.MinorUnits
+	}
+	return money
+}
+
+// bogoDiscount discounts the units got for every BuySKU.Qty units bought along with GetSKU.Qty more, by a percentage
+// or an amount of the unit price
+func bogoDiscount(bogo *model.ProductPromotionsXpBogo, product *model.ProductItem, quote *model.PriceQuote) *big.Rat {
+	if bogo.GetSku == nil {
+		return new(big.Rat)
+	}
+	if getSKU := GetString(bogo.GetSku.Sku); getSKU != "" && getSKU != GetString(product.ID) {
+		return new(big.Rat)
+	}
+
+	buyQuantity, getQuantity := getBOGOQuantity(bogo.BuySku), getBOGOQuantity(bogo.GetSku)
+	freeUnits := quote.Quantity / (buyQuantity + getQuantity) * getQuantity
+
+	value, _ := strconv.ParseFloat(strings.TrimSpace(GetString(bogo.Value)), 64)
+	unitPrice := quote.UnitPriceMoney.Decimal()
+	unitDiscount := unitPrice
+	switch GetString(bogo.Type) {
+	case BOGOTypePercentage:
+		unitDiscount = new(big.Rat).Mul(unitPrice, new(big.Rat).Quo(decimalFromFloat(value), big.NewRat(100, 1)))
+	case BOGOTypeAmount:
+		if amount := decimalFromFloat(value); amount.Cmp(unitPrice) < 0 {
+			unitDiscount = amount
+		}
+	}
+
+	return new(big.Rat).Mul(unitDiscount, big.NewRat(int64(freeUnits), 1))
+}
+
+// getBOGOQuantity returns the quantity of a BOGO SKU, 1 when it is not a positive number
+func getBOGOQuantity(sku *model.GetBuySku) int {
+	if sku == nil {
+		return 1
+	}
+	quantity, err := strconv.Atoi(strings.TrimSpace(GetString(sku.Qty)))
+	if err != nil || quantity < 1 {
+		return 1
+	}
+	return quantity
+}
+
+func containsString(values []*string, value string) bool {
+	for _, v := range values {
+		if GetString(v) == value {
+			return true
+		}
+	}
+	return false
+}
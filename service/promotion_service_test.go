@@ -0,0 +1,254 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+func promotionTestPromotion(id, promotionType string, value int) *model.ProductPromotions {
+	automatic := true
+	return &model.ProductPromotions{
+		ID: &id,
+		Xp: &model.ProductPromotionsXp{Type: &promotionType, Value: &value, Automatic: &automatic},
+	}
+}
+
+func TestEvaluatePromotions(t *testing.T) {
+
+	//data test
+	var productID, supplierID, buyerID, otherBuyerID = "p1", "s1", "b1", "b2"
+	var quantity, price = 1, 10.0
+	var now = time.Date(2023, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	var percentage = promotionTestPromotion("percentage", PromotionTypePercentage, 10)
+	var fixedAmount = promotionTestPromotion("fixed", PromotionTypeFixedAmount, 15)
+	var freeShipping = promotionTestPromotion("shipping", PromotionTypeFreeShipping, 0)
+
+	var bogoType, buyQuantity, getQuantity = BOGOTypePercentage, "2", "1"
+	var bogoValue = "50"
+	var bogo = promotionTestPromotion("bogo", PromotionTypeBOGO, 0)
+	bogo.Xp.Bogo = &model.ProductPromotionsXpBogo{
+		Type:   &bogoType,
+		Value:  &bogoValue,
+		BuySku: &model.GetBuySku{Sku: &productID, Qty: &buyQuantity},
+		GetSku: &model.GetBuySku{Sku: &productID, Qty: &getQuantity},
+	}
+
+	var expirationDate = "2023-06-14"
+	var expired = promotionTestPromotion("expired", PromotionTypePercentage, 50)
+	expired.ExpirationDate = &expirationDate
+
+	var redemptionLimit, redemptionCount = "5", 5
+	var redeemed = promotionTestPromotion("redeemed", PromotionTypePercentage, 50)
+	redeemed.RedemptionLimit, redeemed.RedemptionCount = &redemptionLimit, &redemptionCount
+
+	var otherBuyer = promotionTestPromotion("other-buyer", PromotionTypePercentage, 50)
+	otherBuyer.Xp.Buyers = []*string{&otherBuyerID}
+
+	var scopeToSupplier, otherSupplierID = true, "s2"
+	var otherSupplier = promotionTestPromotion("other-supplier", PromotionTypePercentage, 50)
+	otherSupplier.Xp.ScopeToSupplier, otherSupplier.Xp.Supplier = &scopeToSupplier, &otherSupplierID
+
+	var minReqType, minReqQuantity = PromotionMinReqItemQty, 10
+	var minimum = promotionTestPromotion("minimum", PromotionTypePercentage, 50)
+	minimum.Xp.MinReq = &model.ProductPromotionsXpMinReq{Type: &minReqType, Int: &minReqQuantity}
+
+	var product = &model.ProductItem{
+		ID:                &productID,
+		DefaultSupplierID: &supplierID,
+		Xp: &model.ProductXp{
+			Promotions: []*model.ProductPromotions{freeShipping, percentage, fixedAmount, bogo, expired, redeemed, otherBuyer, otherSupplier, minimum},
+		},
+		PriceSchedule: &model.PriceScheduleItem{
+			PriceBreaks: []*model.PriceBreak{{Quantity: &quantity, Price: &price}},
+		},
+	}
+
+	var redeemer = promotionRedeemer{userID: "42", buyerID: buyerID}
+
+	result := evaluatePromotions(product, 6, redeemer, testMarket, now)
+
+	var expected = []struct {
+		id       string
+		discount float64
+	}{
+		{id: "fixed", discount: 15},
+		{id: "bogo", discount: 10},
+		{id: "percentage", discount: 6},
+		{id: "shipping", discount: 0},
+	}
+	if len(result) != len(expected) {
+		t.Fatal("test failed: expected 4 applicable promotions, got", len(result))
+	}
+
+	// largest discount first
+	if GetString(result[0].Promotion.ID) != "fixed" || GetString(result[1].Promotion.ID) != "bogo" || GetString(result[2].Promotion.ID) != "percentage" || GetString(result[3].Promotion.ID) != "shipping" {
+		t.Error("test failed: promotions must be ordered by discount")
+	}
+	for _, promotionDiscount := range result {
+		for _, e := range expected {
+			if GetString(promotionDiscount.Promotion.ID) == e.id && promotionDiscount.Discount != e.discount {
+				t.Errorf("test failed: %s discount expected %v, got %v", e.id, e.discount, promotionDiscount.Discount)
+			}
+		}
+	}
+	if result[0].DiscountedPrice != 45 {
+		t.Error("test failed: discounted price must be the extended price less the discount, got", result[0].DiscountedPrice)
+	}
+
+	// a minimum quantity is met with more units
+	result = evaluatePromotions(product, 10, redeemer, testMarket, now)
+	if GetString(result[0].Promotion.ID) != "minimum" || result[0].Discount != 50 {
+		t.Error("test failed: promotion with met minimum quantity must apply")
+	}
+
+	// a quantity without a price has no promotions
+	product.PriceSchedule.MaxQuantity = &quantity
+	if len(evaluatePromotions(product, 6, redeemer, testMarket, now)) != 0 {
+		t.Error("test failed: promotions must not apply to a quantity that cannot be bought")
+	}
+}
+
+func TestEvaluatePromotionsInMinorUnits(t *testing.T) {
+
+	//data test
+	var productID = "p1"
+	var quantity, price = 1, 999.0
+	var now = time.Date(2023, 6, 15, 10, 0, 0, 0, time.UTC)
+	var market = testMarket
+	market.Currency = "JPY"
+
+	var minReqType, minPurchase = PromotionMinReqPurchase, 1000
+	var minimum = promotionTestPromotion("minimum", PromotionTypeFixedAmount, 100)
+	minimum.Xp.MinReq = &model.ProductPromotionsXpMinReq{Type: &minReqType, Int: &minPurchase}
+
+	var product = &model.ProductItem{
+		ID: &productID,
+		Xp: &model.ProductXp{
+			Promotions: []*model.ProductPromotions{promotionTestPromotion("percentage", PromotionTypePercentage, 15), minimum},
+		},
+		PriceSchedule: &model.PriceScheduleItem{
+			PriceBreaks: []*model.PriceBreak{{Quantity: &quantity, Price: &price}},
+		},
+	}
+
+	// yen have no minor digits, 15% of 999 is rounded to 150
+	result := evaluatePromotions(product, 1, promotionRedeemer{}, market, now)
+	if len(result) != 1 || result[0].Discount != 150 || result[0].DiscountedPrice != 849 {
+		t.Fatalf("test failed: expected a discount of 150 yen only, got %+v", result)
+	}
+	if result[0].DiscountMoney.Amount() != "150" || result[0].DiscountedPriceMoney.Amount() != "849" || result[0].DiscountMoney.Currency != "JPY" {
+		t.Errorf("test failed: expected discount money of 150 and 849 JPY, got %+v and %+v", result[0].DiscountMoney, result[0].DiscountedPriceMoney)
+	}
+
+	// the minimum purchase is compared with the extended price
+	result = evaluatePromotions(product, 2, promotionRedeemer{}, market, now)
+	if len(result) != 2 || GetString(result[0].Promotion.ID) != "percentage" || result[0].Discount != 300 || result[1].Discount != 100 {
+		t.Errorf("test failed: expected the minimum purchase promotion to apply, got %+v", result)
+	}
+}
+
+func TestEvaluatePromotionsWithExpressions(t *testing.T) {
+
+	//data test
+	var productID, buyerID = "p1", "b1"
+	var quantity, price = 1, 10.0
+	var now = time.Date(2023, 6, 15, 10, 0, 0, 0, time.UTC)
+	var redeemer = promotionRedeemer{userID: "42", buyerID: buyerID, redemptionCounts: map[string]int{"limited": 2}}
+
+	newPromotion := func(id, eligibleExpression, valueExpression string) *model.ProductPromotions {
+		promotion := promotionTestPromotion(id, PromotionTypePercentage, 10)
+		if eligibleExpression != "" {
+			promotion.EligibleExpression = &eligibleExpression
+		}
+		if valueExpression != "" {
+			promotion.ValueExpression = &valueExpression
+		}
+		return promotion
+	}
+
+	var limitPerUser, otherLimitPerUser = 2.0, 3.0
+	var limited = newPromotion("limited", "", "")
+	limited.RedemptionLimitPerUser = &limitPerUser
+	var belowLimit = newPromotion("below-limit", "", "")
+	belowLimit.RedemptionLimitPerUser = &otherLimitPerUser
+
+	var product = &model.ProductItem{
+		ID: &productID,
+		Xp: &model.ProductXp{
+			Promotions: []*model.ProductPromotions{
+				newPromotion("eligible", "items.quantity(ProductID = 'P1') >= 5 and order.FromCompanyID = 'b1'", ""),
+				newPromotion("ineligible", "order.Subtotal > 100", ""),
+				newPromotion("valued", "", "items.total(ProductID = 'p1') * .2 + 1"),
+				newPromotion("unsupported-eligible", "order.xp.Channel = 'web'", ""),
+				newPromotion("unsupported-value", "", "item.Product.xp.Points * 2"),
+				newPromotion("not-boolean", "order.Subtotal", ""),
+				limited,
+				belowLimit,
+			},
+		},
+		PriceSchedule: &model.PriceScheduleItem{
+			PriceBreaks: []*model.PriceBreak{{Quantity: &quantity, Price: &price}},
+		},
+	}
+
+	result := evaluatePromotions(product, 5, redeemer, testMarket, now)
+
+	var expected = map[string]float64{"valued": 11, "eligible": 5, "below-limit": 5}
+	if len(result) != len(expected) {
+		t.Fatal("test failed: expected 3 applicable promotions, got", len(result))
+	}
+	for _, promotionDiscount := range result {
+		discount, ok := expected[GetString(promotionDiscount.Promotion.ID)]
+		if !ok || promotionDiscount.Discount != discount {
+			t.Errorf("test failed: %s discount expected %v, got %v", GetString(promotionDiscount.Promotion.ID), discount, promotionDiscount.Discount)
+		}
+	}
+}
+
+func TestGetApplicablePromotionsCountsUserRedemptions(t *testing.T) {
+
+	//data test
+	var productID, userID = "p1", "42"
+	var quantity, price = 1, 10.0
+	var limitPerUser = 1.0
+
+	var limited = promotionTestPromotion("limited", PromotionTypePercentage, 10)
+	limited.RedemptionLimitPerUser = &limitPerUser
+	var unlimited = promotionTestPromotion("unlimited", PromotionTypePercentage, 5)
+
+	var product = &model.ProductItem{
+		ID: &productID,
+		Xp: &model.ProductXp{Promotions: []*model.ProductPromotions{limited, unlimited}},
+		PriceSchedule: &model.PriceScheduleItem{
+			PriceBreaks: []*model.PriceBreak{{Quantity: &quantity, Price: &price}},
+		},
+	}
+
+	// only promotions limited per user are counted
+	var promotionRedemptionRepositoryMock = &repository.PromotionRedemptionRepositoryMock{}
+	promotionRedemptionRepositoryMock.On("CountUserPromotionRedemptions", userID, []string{"limited"}).Return(map[string]int{"limited": 1}, nil).Once()
+
+	svc := PromotionService{
+		promotionRedemptionRepo: promotionRedemptionRepositoryMock,
+	}
+
+	result, err := svc.GetApplicablePromotions(product, 1, &userID, nil, testMarket)
+	if err != nil {
+		t.Fatal("test failed: error should be nil, got", err)
+	}
+	if len(result) != 1 || GetString(result[0].Promotion.ID) != "unlimited" {
+		t.Error("test failed: promotion redeemed up to the user's limit must not apply")
+	}
+
+	// without a user nothing is redeemed yet
+	result, err = svc.GetApplicablePromotions(product, 1, nil, nil, testMarket)
+	if err != nil || len(result) != 2 {
+		t.Error("test failed: both promotions must apply without a user")
+	}
+
+	promotionRedemptionRepositoryMock.AssertExpectations(t)
+}
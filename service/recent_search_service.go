@@ -1,19 +1,31 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"mpmy-product-service/config"
 	"mpmy-product-service/graph/model"
 	"mpmy-product-service/repository"
 )
 
+// ErrRecentSearchNotFound error returned when the recent search does not exist for the user
+var ErrRecentSearchNotFound = errors.New("recent search not found")
+
 type RecentSearchService struct {
-	recentSearchesRepo   *repository.RecentSearchesRepository
+	recentSearchesRepo *repository.RecentSearchesRepository
+	retentionDays      int
+	pruneDuration      int
 }
 
-func NewRecentSearchService(db *sqlx.DB, dbConfig config.DBConfig) *RecentSearchService {
+func NewRecentSearchService(db *sqlx.DB, dbConfig config.DBConfig, searchConfig config.SearchConfig) *RecentSearchService {
 	return &RecentSearchService{
 		recentSearchesRepo: repository.NewRecentSearchesRepository(db, dbConfig),
+		retentionDays:      searchConfig.RecentSearchRetentionDays,
+		pruneDuration:      searchConfig.RecentSearchPruneDuration,
 	}
 }
 
@@ -24,4 +36,75 @@ func (s *RecentSearchService) GetRecentSearches(userID, page, pageSize *string)
 	}
 
 	return recentSearches, nil
-}
\ No newline at end of file
+}
+
+func (s *RecentSearchService) DeleteRecentSearch(userID *string, id int) (bool, error) {
+	deleted, err := s.recentSearchesRepo.DeleteRecentSearch(userID, id)
+	if err != nil {
+		return false, err
+	}
+
+	if !deleted {
+		return false, ErrRecentSearchNotFound
+	}
+
+	return true, nil
+}
+
+func (s *RecentSearchService) ClearRecentSearches(userID *string) (bool, error) {
+	err := s.recentSearchesRepo.ClearRecentSearches(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *RecentSearchService) StartRecentSearchPruner(ctx context.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println("panic occurred:", err)
+		}
+	}()
+
+	fmt.Println("starting recent search pruner")
+
+	// prune old searches initially
+	err := s.pruneRecentSearches()
+	if err != nil {
+		fmt.Println("error occurred while pruning recent searches initially:", err)
+	}
+
+	pullDuration := time.Duration(s.pruneDuration) * time.Minute
+	tick := time.NewTicker(pullDuration)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("stopped recent search pruner")
+			return
+		case <-tick.C:
+			fmt.Println("pruning recent searches")
+
+			// prune old searches periodically
+			err := s.pruneRecentSearches()
+			if err != nil {
+				continue
+			}
+		}
+	}
+}
+
+func (s *RecentSearchService) pruneRecentSearches() error {
+	before := time.Now().UTC().AddDate(0, 0, -s.retentionDays)
+
+	deleted, err := s.recentSearchesRepo.DeleteRecentSearchesBefore(before)
+	if err != nil {
+		fmt.Println("error occurred while deleting old recent searches:", err)
+		return err
+	}
+
+	fmt.Printf("deleted %d recent searches last used before %s\n", deleted, before.Format(time.RFC3339))
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/repository"
+)
+
+// RecommendationService periodically rebuilds product recommendations from order history
+type RecommendationService struct {
+	recommendationRepo   *repository.RecommendationRepository
+	recommendationConfig config.RecommendationConfig
+}
+
+func NewRecommendationService(db *sqlx.DB, dbConfig config.DBConfig, recommendationConfig config.RecommendationConfig) *RecommendationService {
+	return &RecommendationService{
+		recommendationRepo:   repository.NewRecommendationRepository(db, dbConfig),
+		recommendationConfig: recommendationConfig,
+	}
+}
+
+// StartRecommendationBuilder rebuilds recommendations on start and then every refresh duration until ctx is cancelled
+func (svc *RecommendationService) StartRecommendationBuilder(ctx context.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println("panic occurred:", err)
+		}
+	}()
+
+	fmt.Println("starting recommendation builder")
+
+	err := svc.buildRecommendations()
+	if err != nil {
+		fmt.Println("error occurred while building recommendations initially:", err)
+	}
+
+	tick := time.NewTicker(time.Duration(svc.recommendationConfig.RefreshDuration) * time.Minute)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("stopped recommendation builder")
+			return
+		case <-tick.C:
+			err := svc.buildRecommendations()
+			if err != nil {
+				fmt.Println("error occurred while building recommendations:", err)
+			}
+		}
+	}
+}
+
+func (svc *RecommendationService) buildRecommendations() error {
+	written, err := svc.recommendationRepo.RebuildProductRecommendations(repository.RecommendationBuildParams{
+		Since:         time.Now().UTC().AddDate(0, 0, -svc.recommendationConfig.WindowDays),
+		MinSupport:    svc.recommendationConfig.MinSupport,
+		MaxPerProduct: svc.recommendationConfig.MaxPerProduct,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("built %d product recommendations\n", written)
+	return nil
+}
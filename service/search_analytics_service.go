@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// DefaultSearchAnalyticsLimit is the number of queries listed per report when no limit is requested
+	DefaultSearchAnalyticsLimit = 20
+
+	// MaxProductIDLength is the longest id order cloud allows
+	MaxProductIDLength = 100
+)
+
+var (
+	// ErrInvalidAnalyticsPeriod error returned when the report period ends before it starts
+	ErrInvalidAnalyticsPeriod = errors.New("from must be before to")
+
+	// ErrInvalidSearchID error returned when a click is recorded against an id that was not issued for a search
+	ErrInvalidSearchID = errors.New("invalid search id")
+
+	// ErrInvalidProductID error returned when a click is recorded for a blank or overlong product id
+	ErrInvalidProductID = errors.New("invalid product id")
+)
+
+type SearchAnalyticsService struct {
+	searchAnalyticsRepo *repository.SearchAnalyticsRepository
+}
+
+func NewSearchAnalyticsService(db *sqlx.DB, dbConfig config.DBConfig) *SearchAnalyticsService {
+	return &SearchAnalyticsService{
+		searchAnalyticsRepo: repository.NewSearchAnalyticsRepository(db, dbConfig),
+	}
+}
+
+func (svc *SearchAnalyticsService) GetSearchAnalytics(from, to time.Time, limit *int) (*model.SearchAnalytics, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidAnalyticsPeriod
+	}
+
+	limitVal := DefaultSearchAnalyticsLimit
+	if limit != nil && *limit > 0 {
+		limitVal = *limit
+	}
+
+	analytics, err := svc.searchAnalyticsRepo.GetSearchTotals(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics.TopQueries, err = svc.searchAnalyticsRepo.GetTopQueries(from, to, limitVal)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics.ZeroResultQueries, err = svc.searchAnalyticsRepo.GetZeroResultQueries(from, to, limitVal)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics.From = from
+	analytics.To = to
+
+	return &analytics, nil
+}
+
+func (svc *SearchAnalyticsService) RecordSearchClick(userID *string, productID, searchID string) (bool, error) {
+	err := validateSearchClick(productID, searchID)
+	if err != nil {
+		return false, err
+	}
+
+	err = svc.searchAnalyticsRepo.SaveSearchClick(userID, searchID, productID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// validateSearchClick checks the search id is one LogSearch issues and the product id one order cloud allows
+func validateSearchClick(productID, searchID string) error {
+	if _, err := uuid.Parse(searchID); err != nil {
+		return ErrInvalidSearchID
+	}
+	if strings.TrimSpace(productID) != productID || productID == "" || len(productID) > MaxProductIDLength {
+		return ErrInvalidProductID
+	}
+	return nil
+}
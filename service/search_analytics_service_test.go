@@ -0,0 +1,38 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordSearchClickValidation(t *testing.T) {
+
+	//data test
+	var searchID = "0b7e8d4c-52a4-4bd4-9a61-1f1c2a2d4f10"
+
+	var tests = []struct {
+		name      string
+		productID string
+		searchID  string
+		expected  error
+	}{
+		{name: "search id not issued by search", productID: "PRD-1", searchID: "search-1", expected: ErrInvalidSearchID},
+		{name: "empty search id", productID: "PRD-1", searchID: "", expected: ErrInvalidSearchID},
+		{name: "empty product id", productID: "", searchID: searchID, expected: ErrInvalidProductID},
+		{name: "padded product id", productID: " PRD-1 ", searchID: searchID, expected: ErrInvalidProductID},
+		{name: "overlong product id", productID: strings.Repeat("P", MaxProductIDLength+1), searchID: searchID, expected: ErrInvalidProductID},
+	}
+
+	// invalid clicks are rejected before the repository is reached
+	svc := &SearchAnalyticsService{}
+	for _, test := range tests {
+		recorded, err := svc.RecordSearchClick(nil, test.productID, test.searchID)
+		if recorded || err != test.expected {
+			t.Errorf("test failed: %s, expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+
+	if validateSearchClick("PRD-1", searchID) != nil {
+		t.Error("test failed: valid click must pass validation")
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/client/search"
+	"mpmy-product-service/config"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// SearchIndexSyncPageSize is the page size used while pulling products from order cloud
+	SearchIndexSyncPageSize = 100
+
+	// AccessTokenWaitInterval is how often background jobs check for the first order cloud access token
+	AccessTokenWaitInterval = 5 * time.Second
+)
+
+// SearchIndexService keeps a search index of the products of every market's catalog, so a market is only
+// searched for products it sells
+type SearchIndexService struct {
+	enabled      bool
+	syncDuration int
+	indexes      map[string]*search.Index
+	productRepo  repository.IProductRepository
+}
+
+func NewSearchIndexService(db *sqlx.DB, dbConfig config.DBConfig, orderConfig config.OrderCloudConfig, searchConfig config.SearchConfig, markets config.MarketsConfig) (*SearchIndexService, error) {
+	synonyms, err := search.LoadSynonyms(searchConfig.SynonymsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// markets may share a catalog, and so its index
+	indexes := make(map[string]*search.Index)
+	for _, market := range markets.Markets {
+		if _, ok := indexes[market.CatalogID]; !ok {
+			indexes[market.CatalogID] = search.New(synonyms)
+		}
+	}
+
+	return &SearchIndexService{
+		enabled:      searchConfig.IndexEnabled,
+		syncDuration: searchConfig.IndexSyncDuration,
+		indexes:      indexes,
+		productRepo:  repository.NewProductRepository(db, dbConfig, orderConfig),
+	}, nil
+}
+
+// Enabled reports whether searches of the catalog should be served from its index, order cloud search is used
+// until the index is switched on and populated, and for catalogs of no market
+func (svc *SearchIndexService) Enabled(catalogID string) bool {
+	if svc == nil || !svc.enabled {
+		return false
+	}
+	index, ok := svc.indexes[catalogID]
+	return ok && index.Size() > 0
+}
+
+// Search returns ranked product ids of the catalog matching query
+func (svc *SearchIndexService) Search(catalogID string, query string) []string {
+	index, ok := svc.indexes[catalogID]
+	if !ok {
+		return nil
+	}
+
+	return index.Search(query)
+}
+
+// SuggestProductNames returns indexed products of the catalog whose name starts with prefix
+func (svc *SearchIndexService) SuggestProductNames(catalogID string, prefix string, limit int) []search.Document {
+	index, ok := svc.indexes[catalogID]
+	if !ok {
+		return nil
+	}
+	return index.SuggestNames(prefix, limit)
+}
+
+func (svc *SearchIndexService) StartSearchIndexSyncer(ctx context.Context, loginService *LoginService) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println("panic occurred:", err)
+		}
+	}()
+
+	if !svc.enabled {
+		return
+	}
+
+	fmt.Println("starting search index syncer")
+
+	// wait for the access token fetcher before populating the index
+	for loginService.GetAccessToken() == "" {
+		select {
+		case <-ctx.Done():
+			fmt.Println("stopped search index syncer")
+			return
+		case <-time.After(AccessTokenWaitInterval):
+		}
+	}
+
+	// populate index initially
+	err := svc.syncIndex(loginService.GetAccessToken())
+	if err != nil {
+		fmt.Println("error occurred while populating search index initially:", err)
+	}
+
+	pullDuration := time.Duration(svc.syncDuration) * time.Minute
+	tick := time.NewTicker(pullDuration)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("stopped search index syncer")
+			return
+		case <-tick.C:
+			fmt.Println("syncing search index from order cloud")
+
+			// refresh index periodically
+			err := svc.syncIndex(loginService.GetAccessToken())
+			if err != nil {
+				continue
+			}
+		}
+	}
+}
+
+// syncIndex refreshes the index of every catalog, a catalog that fails to sync keeps its previous index and the
+// first error is returned once the others are synced
+func (svc *SearchIndexService) syncIndex(accessToken string) error {
+	if accessToken == "" {
+		return fmt.Errorf("order cloud access token not fetched yet")
+	}
+
+	catalogIDs := make([]string, 0, len(svc.indexes))
+	for catalogID := range svc.indexes {
+		catalogIDs = append(catalogIDs, catalogID)
+	}
+	sort.Strings(catalogIDs)
+
+	var syncErr error
+	for _, catalogID := range catalogIDs {
+		err := svc.syncCatalogIndex(catalogID, accessToken)
+		if err != nil && syncErr == nil {
+			syncErr = err
+		}
+	}
+
+	return syncErr
+}
+
+func (svc *SearchIndexService) syncCatalogIndex(catalogID string, accessToken string) error {
+	var documents []search.Document
+	for page := 1; ; page++ {
+		products, err := svc.productRepo.GetProducts(repository.ProductParams{
+			CatalogID: catalogID,
+			Page:      repository.GetStringFromInt(page),
+			PageSize:  repository.GetStringFromInt(SearchIndexSyncPageSize),
+		}, accessToken)
+		if err != nil {
+			fmt.Printf("error occurred while fetching products of catalog %s for search index: %v\n", catalogID, err)
+			return err
+		}
+
+		for _, product := range products.Items {
+			if product == nil || product.ID == nil {
+				continue
+			}
+
+			document := search.Document{
+				ID:          GetString(product.ID),
+				Name:        GetString(product.Name),
+				Description: GetString(product.Description),
+			}
+			if product.Xp != nil {
+				document.Brand = GetString(product.Xp.Brand)
+			}
+			documents = append(documents, document)
+		}
+
+		if products.Meta == nil || products.Meta.TotalPages == nil || page >= *products.Meta.TotalPages {
+			break
+		}
+	}
+
+	svc.indexes[catalogID].Replace(documents)
+
+	fmt.Printf("indexed %d products of catalog %s for search\n", len(documents), catalogID)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+
+	"mpmy-product-service/client/search"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+func TestSyncIndexPerCatalog(t *testing.T) {
+
+	//data test
+	var accessToken = "token"
+	var myProductID, sgProductID = "panadol-my", "panadol-sg"
+	var myName, sgName = "Panadol Actifast", "Panadol Extra"
+	var totalPages = 1
+
+	var productRepositoryMock = &repository.ProductRepositoryMock{}
+	productRepositoryMock.On("GetProducts", repository.ProductParams{CatalogID: "zp-my", Page: "1", PageSize: "100"}, accessToken).Return(model.ProductResponse{
+		Meta:  &model.ProductMeta{TotalPages: &totalPages},
+		Items: []*model.ProductItem{{ID: &myProductID, Name: &myName}},
+	}, nil)
+	productRepositoryMock.On("GetProducts", repository.ProductParams{CatalogID: "zp-sg", Page: "1", PageSize: "100"}, accessToken).Return(model.ProductResponse{
+		Meta:  &model.ProductMeta{TotalPages: &totalPages},
+		Items: []*model.ProductItem{{ID: &sgProductID, Name: &sgName}},
+	}, nil)
+
+	svc := &SearchIndexService{
+		enabled:     true,
+		indexes:     map[string]*search.Index{"zp-my": search.New(nil), "zp-sg": search.New(nil)},
+		productRepo: productRepositoryMock,
+	}
+
+	err := svc.syncIndex(accessToken)
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	// every market is searched for its own catalog's products only
+	if ids := svc.Search("zp-my", "panadol"); len(ids) != 1 || ids[0] != myProductID {
+		t.Error("test failed: MY search must return MY products, got", ids)
+	}
+	if ids := svc.Search("zp-sg", "panadol"); len(ids) != 1 || ids[0] != sgProductID {
+		t.Error("test failed: SG search must return SG products, got", ids)
+	}
+	if svc.Enabled("zp-th") || svc.Search("zp-th", "panadol") != nil {
+		t.Error("test failed: catalog of no market must not be served from an index")
+	}
+
+	productRepositoryMock.AssertExpectations(t)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/config"
+	"mpmy-product-service/repository"
+)
+
+// SearchEvent is a single product search made by a user along with its outcome
+type SearchEvent struct {
+	ID          string
+	UserID      string
+	Keyword     string
+	CatalogID   string
+	Filters     map[string]interface{}
+	ResultCount int
+	Latency     time.Duration
+	SearchedAt  time.Time
+}
+
+// SearchLogStats counters of the search log queue
+type SearchLogStats struct {
+	Queued  int
+	Written uint64
+	Dropped uint64
+	Failed  uint64
+}
+
+// SearchLogService records searches asynchronously, events are queued in memory and written in batches
+// so that logging never slows down or fails a product listing
+type SearchLogService struct {
+	recentSearchesRepo  *repository.RecentSearchesRepository
+	searchAnalyticsRepo *repository.SearchAnalyticsRepository
+	events              chan SearchEvent
+	done                chan struct{}
+	batchSize           int
+	flushInterval       time.Duration
+	searchLimit         int
+
+	written uint64
+	dropped uint64
+	failed  uint64
+
+	// drops already reported, only touched by the writer
+	reportedDropped uint64
+}
+
+func NewSearchLogService(db *sqlx.DB, dbConfig config.DBConfig, searchConfig config.SearchConfig) *SearchLogService {
+	return &SearchLogService{
+		recentSearchesRepo:  repository.NewRecentSearchesRepository(db, dbConfig),
+		searchAnalyticsRepo: repository.NewSearchAnalyticsRepository(db, dbConfig),
+		events:              make(chan SearchEvent, searchConfig.SearchLogQueueSize),
+		done:                make(chan struct{}),
+		batchSize:           searchConfig.SearchLogBatchSize,
+		flushInterval:       time.Duration(searchConfig.SearchLogFlushInterval) * time.Second,
+		searchLimit:         searchConfig.RecentSearchLimit,
+	}
+}
+
+// LogSearch queues a search without blocking and returns its id. The event is dropped when the queue is full, and
+// no id is returned then since clicks recorded against it could never be joined to a search
+func (svc *SearchLogService) LogSearch(event SearchEvent) string {
+	event.Keyword = strings.TrimSpace(event.Keyword)
+	if svc == nil || event.UserID == "" || event.Keyword == "" {
+		return ""
+	}
+
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.SearchedAt.IsZero() {
+		event.SearchedAt = time.Now().UTC()
+	}
+
+	select {
+	case svc.events <- event:
+		return event.ID
+	default:
+		atomic.AddUint64(&svc.dropped, 1)
+		return ""
+	}
+}
+
+// Stats returns the current counters of the search log
+func (svc *SearchLogService) Stats() SearchLogStats {
+	return SearchLogStats{
+		Queued:  len(svc.events),
+		Written: atomic.LoadUint64(&svc.written),
+		Dropped: atomic.LoadUint64(&svc.dropped),
+		Failed:  atomic.LoadUint64(&svc.failed),
+	}
+}
+
+// StartSearchLogWriter drains the queue until ctx is cancelled, then flushes what is left
+func (svc *SearchLogService) StartSearchLogWriter(ctx context.Context) {
+	defer close(svc.done)
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println("panic occurred:", err)
+		}
+	}()
+
+	fmt.Println("starting search log writer")
+
+	tick := time.NewTicker(svc.flushInterval)
+	defer tick.Stop()
+
+	batch := make([]SearchEvent, 0, svc.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// flush queued events before stopping
+			for {
+				select {
+				case event := <-svc.events:
+					batch = append(batch, event)
+					if len(batch) >= svc.batchSize {
+						batch = svc.flush(batch)
+					}
+				default:
+					svc.flush(batch)
+					fmt.Printf("stopped search log writer, stats: %+v\n", svc.Stats())
+					return
+				}
+			}
+		case event := <-svc.events:
+			batch = append(batch, event)
+			if len(batch) >= svc.batchSize {
+				batch = svc.flush(batch)
+			}
+		case <-tick.C:
+			batch = svc.flush(batch)
+		}
+	}
+}
+
+// Wait blocks until the writer has flushed and stopped or ctx expires
+func (svc *SearchLogService) Wait(ctx context.Context) error {
+	select {
+	case <-svc.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush writes batch to the database and returns the emptied batch for reuse
+func (svc *SearchLogService) flush(batch []SearchEvent) []SearchEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	items, userIDs := aggregateSearchEvents(batch)
+
+	err := svc.recentSearchesRepo.SaveRecentSearches(items)
+	if err != nil {
+		atomic.AddUint64(&svc.failed, uint64(len(batch)))
+		fmt.Println("error occurred while writing search log batch:", err)
+	} else {
+		atomic.AddUint64(&svc.written, uint64(len(batch)))
+
+		// keep every user within the retention limit
+		for i := range userIDs {
+			err = svc.recentSearchesRepo.TrimRecentSearches(&userIDs[i], svc.searchLimit)
+			if err != nil {
+				fmt.Println("error occurred while trimming recent searches:", err)
+			}
+		}
+	}
+
+	// record outcomes for search analytics, whether or not recent searches were written
+	err = svc.searchAnalyticsRepo.SaveSearchEvents(toSearchEventItems(batch))
+	if err != nil {
+		fmt.Println("error occurred while writing search analytics batch:", err)
+	}
+
+	// report the queue counters whenever searches were dropped since the last flush
+	if stats := svc.Stats(); stats.Dropped > svc.reportedDropped {
+		fmt.Printf("search log queue overflowed, %d searches dropped since last flush, stats: %+v\n", stats.Dropped-svc.reportedDropped, stats)
+		svc.reportedDropped = stats.Dropped
+	}
+
+	return batch[:0]
+}
+
+func toSearchEventItems(events []SearchEvent) []repository.SearchEventItem {
+	items := make([]repository.SearchEventItem, len(events))
+	for i, event := range events {
+		items[i] = repository.SearchEventItem{
+			ID:                event.ID,
+			UserID:            event.UserID,
+			SearchKeyword:     event.Keyword,
+			NormalizedKeyword: repository.NormalizeSearchKeyword(event.Keyword),
+			CatalogID:         event.CatalogID,
+			Filters:           event.Filters,
+			ResultCount:       event.ResultCount,
+			LatencyMs:         int(event.Latency.Milliseconds()),
+			CreatedAt:         event.SearchedAt,
+		}
+	}
+	return items
+}
+
+// aggregateSearchEvents merges events of the same user and keyword so a batch upserts each row once
+func aggregateSearchEvents(events []SearchEvent) ([]repository.RecentSearchBatchItem, []string) {
+	var items []repository.RecentSearchBatchItem
+	var userIDs []string
+
+	itemIndex := make(map[string]int)
+	seenUsers := make(map[string]bool)
+	for _, event := range events {
+		normalizedKeyword := repository.NormalizeSearchKeyword(event.Keyword)
+		key := event.UserID + "\x00" + normalizedKeyword
+
+		if i, ok := itemIndex[key]; ok {
+			items[i].SearchCount++
+			if event.SearchedAt.After(items[i].SearchedAt) {
+				items[i].SearchKeyword = event.Keyword
+				items[i].SearchedAt = event.SearchedAt
+			}
+		} else {
+			itemIndex[key] = len(items)
+			items = append(items, repository.RecentSearchBatchItem{
+				UserID:            event.UserID,
+				SearchKeyword:     event.Keyword,
+				NormalizedKeyword: normalizedKeyword,
+				SearchCount:       1,
+				SearchedAt:        event.SearchedAt,
+			})
+		}
+
+		if !seenUsers[event.UserID] {
+			seenUsers[event.UserID] = true
+			userIDs = append(userIDs, event.UserID)
+		}
+	}
+
+	return items, userIDs
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregateSearchEvents(t *testing.T) {
+
+	//data test
+	now := time.Now().UTC()
+	events := []SearchEvent{
+		{UserID: "1", Keyword: "Panadol", SearchedAt: now},
+		{UserID: "1", Keyword: "panadol ", SearchedAt: now.Add(time.Second)},
+		{UserID: "2", Keyword: "panadol", SearchedAt: now},
+		{UserID: "1", Keyword: "vitamin  c", SearchedAt: now},
+	}
+
+	items, userIDs := aggregateSearchEvents(events)
+	if len(items) != 3 {
+		t.Fatalf("test failed: expected 3 items, got %d", len(items))
+	}
+	if items[0].SearchCount != 2 || items[0].SearchKeyword != "panadol " || !items[0].SearchedAt.Equal(now.Add(time.Second)) {
+		t.Errorf("test failed: repeated searches not merged, got %+v", items[0])
+	}
+	if items[2].NormalizedKeyword != "vitamin c" {
+		t.Errorf("test failed: keyword not normalized, got %q", items[2].NormalizedKeyword)
+	}
+	if len(userIDs) != 2 {
+		t.Errorf("test failed: expected 2 users, got %v", userIDs)
+	}
+}
+
+func TestLogSearchDropsOnOverflow(t *testing.T) {
+
+	//data test
+	event := SearchEvent{UserID: "1", Keyword: "panadol"}
+	searchLogService := &SearchLogService{events: make(chan SearchEvent, 1)}
+
+	if searchLogService.LogSearch(event) == "" {
+		t.Error("test failed: queued search must have an id")
+	}
+	if searchLogService.LogSearch(event) != "" {
+		t.Error("test failed: dropped search must have no id")
+	}
+
+	stats := searchLogService.Stats()
+	if stats.Queued != 1 || stats.Dropped != 1 {
+		t.Errorf("test failed: expected 1 queued and 1 dropped, got %+v", stats)
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mitchellh/mapstructure"
+
+	"mpmy-product-service/client/cache"
+	"mpmy-product-service/config"
+	"mpmy-product-service/constants"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+const (
+	// SearchSuggestionsCacheKeyPrefix is the key prefix for search suggestions cached per prefix
+	SearchSuggestionsCacheKeyPrefix = "search_suggestions:"
+
+	// SearchSuggestionsCacheTTL is the duration suggestions shared by all users are cached for
+	SearchSuggestionsCacheTTL = 5 * time.Minute
+
+	// DefaultSearchSuggestionsLimit is the number of suggestions returned when no limit is requested
+	DefaultSearchSuggestionsLimit = 10
+
+	// MaxSearchSuggestionsLimit is the most suggestions returned, keeping order cloud page sizes and the cached
+	// limits per prefix bounded
+	MaxSearchSuggestionsLimit = 20
+)
+
+type SearchSuggestionService struct {
+	cacheClient        *cache.Cache
+	searchIndexService *SearchIndexService
+	productRepo        repository.IProductRepository
+	categoryRepo       *repository.CategoryRepository
+	recentSearchesRepo *repository.RecentSearchesRepository
+}
+
+func NewSearchSuggestionService(db *sqlx.DB, dbConfig config.DBConfig, orderConfig config.OrderCloudConfig, cacheClient *cache.Cache, searchIndexService *SearchIndexService) *SearchSuggestionService {
+	return &SearchSuggestionService{
+		cacheClient:        cacheClient,
+		searchIndexService: searchIndexService,
+		productRepo:        repository.NewProductRepository(db, dbConfig, orderConfig),
+		categoryRepo:       repository.NewCategoryRepository(orderConfig),
+		recentSearchesRepo: repository.NewRecentSearchesRepository(db, dbConfig),
+	}
+}
+
+func (svc *SearchSuggestionService) GetSearchSuggestions(userID *string, prefix string, limit *int, market config.MarketConfig, accessToken string) ([]*model.SearchSuggestion, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return []*model.SearchSuggestion{}, nil
+	}
+
+	limitVal := DefaultSearchSuggestionsLimit
+	if limit != nil && *limit > 0 {
+		limitVal = minInt(*limit, MaxSearchSuggestionsLimit)
+	}
+
+	// get user's own searches from database
+	recentKeywords, err := svc.recentSearchesRepo.GetRecentSearchKeywords(userID, prefix, limitVal)
+	if err != nil {
+		return nil, err
+	}
+
+	recentSuggestions := make([]*model.SearchSuggestion, len(recentKeywords))
+	for i, keyword := range recentKeywords {
+		recentSuggestions[i] = &model.SearchSuggestion{Type: model.SearchSuggestionTypeRecentSearch, Text: keyword}
+	}
+
+	// get suggestions shared by all users
+	sharedSuggestions, err := svc.getSharedSuggestions(prefix, limitVal, market.CatalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeSuggestions(limitVal, append([][]*model.SearchSuggestion{recentSuggestions}, sharedSuggestions...)...), nil
+}
+
+// getSharedSuggestions returns popular search, product and category suggestions of the catalog, cached per catalog
+// and prefix
+func (svc *SearchSuggestionService) getSharedSuggestions(prefix string, limit int, catalogID string, accessToken string) ([][]*model.SearchSuggestion, error) {
+	cacheKey := SearchSuggestionsCacheKeyPrefix + catalogID + ":" + strings.ToLower(prefix) + ":" + repository.GetStringFromInt(limit)
+
+	cacheResp, err := svc.cacheClient.Get(cacheKey)
+	if err == nil && cacheResp != nil {
+		var suggestions [][]*model.SearchSuggestion
+		err = mapstructure.Decode(cacheResp, &suggestions)
+		if err == nil {
+			return suggestions, nil
+		}
+	}
+
+	popularSuggestions, err := svc.getPopularSearchSuggestions(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	productSuggestions, err := svc.getProductSuggestions(prefix, limit, catalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	categorySuggestions, err := svc.getCategorySuggestions(prefix, limit, catalogID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := [][]*model.SearchSuggestion{popularSuggestions, productSuggestions, categorySuggestions}
+
+	// a failed cache write only costs fetching the suggestions again
+	_ = svc.cacheClient.Set(cacheKey, suggestions, SearchSuggestionsCacheTTL)
+
+	return suggestions, nil
+}
+
+func (svc *SearchSuggestionService) getPopularSearchSuggestions(prefix string, limit int) ([]*model.SearchSuggestion, error) {
+	keywords, err := svc.recentSearchesRepo.GetPopularSearchKeywords(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]*model.SearchSuggestion, len(keywords))
+	for i, keyword := range keywords {
+		suggestions[i] = &model.SearchSuggestion{Type: model.SearchSuggestionTypePopularSearch, Text: keyword}
+	}
+
+	return suggestions, nil
+}
+
+func (svc *SearchSuggestionService) getProductSuggestions(prefix string, limit int, catalogID string, accessToken string) ([]*model.SearchSuggestion, error) {
+	var suggestions []*model.SearchSuggestion
+
+	// match product names from the search index when enabled
+	if svc.searchIndexService.Enabled(catalogID) {
+		for _, document := range svc.searchIndexService.SuggestProductNames(catalogID, prefix, limit) {
+			productID := document.ID
+			suggestions = append(suggestions, &model.SearchSuggestion{Type: model.SearchSuggestionTypeProduct, Text: document.Name, ID: &productID})
+		}
+		return suggestions, nil
+	}
+
+	// get products from order cloud
+	products, err := svc.productRepo.GetProducts(repository.ProductParams{
+		CatalogID: catalogID,
+		Search:    prefix,
+		SearchOn:  "Name",
+		PageSize:  repository.GetStringFromInt(limit),
+	}, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range products.Items {
+		if product == nil || product.ID == nil || product.Name == nil {
+			continue
+		}
+		suggestions = append(suggestions, &model.SearchSuggestion{Type: model.SearchSuggestionTypeProduct, Text: *product.Name, ID: product.ID})
+	}
+
+	return suggestions, nil
+}
+
+func (svc *SearchSuggestionService) getCategorySuggestions(prefix string, limit int, catalogID string, accessToken string) ([]*model.SearchSuggestion, error) {
+	// get categories from order cloud
+	categories, err := svc.categoryRepo.FetchCategories(repository.GetCategoryParams{
+		CatalogID: catalogID,
+		Depth:     constants.Depth,
+	}, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	lowerPrefix := strings.ToLower(prefix)
+
+	var suggestions []*model.SearchSuggestion
+	var walk func(items []*model.CategoryItems)
+	walk = func(items []*model.CategoryItems) {
+		for _, category := range items {
+			if len(suggestions) >= limit {
+				return
+			}
+			if category == nil {
+				continue
+			}
+
+			name := strings.ToLower(category.Name)
+			if strings.HasPrefix(name, lowerPrefix) || strings.Contains(name, " "+lowerPrefix) {
+				categoryID := category.ID
+				suggestions = append(suggestions, &model.SearchSuggestion{Type: model.SearchSuggestionTypeCategory, Text: category.Name, ID: &categoryID})
+			}
+			walk(category.ChildData)
+		}
+	}
+	walk(categories.Items)
+
+	return suggestions, nil
+}
+
+// mergeSuggestions interleaves suggestion groups so every type is represented, dropping repeated texts
+func mergeSuggestions(limit int, groups ...[]*model.SearchSuggestion) []*model.SearchSuggestion {
+	result := []*model.SearchSuggestion{}
+	seen := make(map[string]bool)
+
+	for i := 0; len(result) < limit; i++ {
+		added := false
+		for _, group := range groups {
+			if i >= len(group) {
+				continue
+			}
+			added = true
+
+			key := string(group[i].Type) + ":" + strings.ToLower(group[i].Text)
+			if group[i].Type == model.SearchSuggestionTypeRecentSearch || group[i].Type == model.SearchSuggestionTypePopularSearch {
+				// a popular search already shown as a recent search is not repeated
+				key = strings.ToLower(group[i].Text)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			result = append(result, group[i])
+			if len(result) >= limit {
+				break
+			}
+		}
+
+		if !added {
+			break
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dgraph-io/ristretto"
+	"github.com/jmoiron/sqlx"
+
+	"mpmy-product-service/client/cache"
+	"mpmy-product-service/client/search"
+	"mpmy-product-service/config"
+	"mpmy-product-service/graph/model"
+	"mpmy-product-service/repository"
+)
+
+func suggestionTexts(suggestions []*model.SearchSuggestion) []string {
+	texts := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		texts[i] = string(suggestion.Type) + ":" + suggestion.Text
+	}
+	return texts
+}
+
+func TestMergeSuggestions(t *testing.T) {
+
+	//data test
+	var recent = []*model.SearchSuggestion{
+		{Type: model.SearchSuggestionTypeRecentSearch, Text: "Panadol"},
+		{Type: model.SearchSuggestionTypeRecentSearch, Text: "pantene"},
+	}
+	var popular = []*model.SearchSuggestion{
+		{Type: model.SearchSuggestionTypePopularSearch, Text: "panadol"},
+		{Type: model.SearchSuggestionTypePopularSearch, Text: "pampers"},
+	}
+	var products = []*model.SearchSuggestion{
+		{Type: model.SearchSuggestionTypeProduct, Text: "Panadol"},
+		{Type: model.SearchSuggestionTypeProduct, Text: "Panadol"},
+	}
+	var categories = []*model.SearchSuggestion{
+		{Type: model.SearchSuggestionTypeCategory, Text: "Pain Relief"},
+	}
+
+	var tests = []struct {
+		name     string
+		limit    int
+		groups   [][]*model.SearchSuggestion
+		expected []string
+	}{
+		{name: "groups are interleaved and repeats dropped", limit: 10, groups: [][]*model.SearchSuggestion{recent, popular, products, categories}, expected: []string{"RECENT_SEARCH:Panadol", "PRODUCT:Panadol", "CATEGORY:Pain Relief", "RECENT_SEARCH:pantene", "POPULAR_SEARCH:pampers"}},
+		{name: "limit cuts within a round", limit: 2, groups: [][]*model.SearchSuggestion{recent, popular, products, categories}, expected: []string{"RECENT_SEARCH:Panadol", "PRODUCT:Panadol"}},
+		{name: "popular search without recent searches", limit: 10, groups: [][]*model.SearchSuggestion{nil, popular}, expected: []string{"POPULAR_SEARCH:panadol", "POPULAR_SEARCH:pampers"}},
+		{name: "no suggestions", limit: 10, groups: nil, expected: []string{}},
+	}
+
+	for _, test := range tests {
+		result := suggestionTexts(mergeSuggestions(test.limit, test.groups...))
+		if len(result) != len(test.expected) {
+			t.Errorf("test failed: %s, expected %v, got %v", test.name, test.expected, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != test.expected[i] {
+				t.Errorf("test failed: %s, expected %v, got %v", test.name, test.expected, result)
+				break
+			}
+		}
+	}
+}
+
+func TestGetSearchSuggestions(t *testing.T) {
+
+	//data test
+	var userID = "42"
+	var limit = 3
+
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("test failed: sqlmock not created:", err)
+	}
+	defer db.Close()
+
+	rCache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 100, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal("test failed: cache not created:", err)
+	}
+	cacheClient := cache.New(rCache)
+
+	// shared suggestions are served from cache, only the user's own searches are read
+	sharedSuggestions := [][]*model.SearchSuggestion{
+		{{Type: model.SearchSuggestionTypePopularSearch, Text: "panadol"}, {Type: model.SearchSuggestionTypePopularSearch, Text: "pampers"}},
+		{{Type: model.SearchSuggestionTypeProduct, Text: "Panadol Extra"}},
+		{},
+	}
+	_ = cacheClient.Set(SearchSuggestionsCacheKeyPrefix+testMarket.CatalogID+":pa:3", sharedSuggestions, SearchSuggestionsCacheTTL)
+
+	dbMock.ExpectQuery("SELECT search_keyword FROM product.recent_searches").
+		WithArgs(userID, "pa%", limit).
+		WillReturnRows(sqlmock.NewRows([]string{"search_keyword"}).AddRow("Panadol").AddRow("pantene"))
+
+	svc := &SearchSuggestionService{
+		cacheClient:        cacheClient,
+		recentSearchesRepo: repository.NewRecentSearchesRepository(sqlx.NewDb(db, "postgres"), config.DBConfig{Schema: "product"}),
+	}
+
+	result, err := svc.GetSearchSuggestions(&userID, " Pa ", &limit, testMarket, "")
+	if err != nil {
+		t.Fatal("test failed, error should be nil, got", err)
+	}
+
+	expected := []string{"RECENT_SEARCH:Panadol", "PRODUCT:Panadol Extra", "RECENT_SEARCH:pantene"}
+	texts := suggestionTexts(result)
+	if len(texts) != len(expected) {
+		t.Fatalf("test failed: expected %v, got %v", expected, texts)
+	}
+	for i := range texts {
+		if texts[i] != expected[i] {
+			t.Errorf("test failed: expected %v, got %v", expected, texts)
+			break
+		}
+	}
+
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Error("test failed:", err)
+	}
+
+	result, err = svc.GetSearchSuggestions(&userID, "  ", &limit, testMarket, "")
+	if err != nil || len(result) != 0 {
+		t.Error("test failed: blank prefix must return no suggestions")
+	}
+
+	// oversized limits are clamped before reaching the query and the cache key
+	var largeLimit = 1000
+	_ = cacheClient.Set(SearchSuggestionsCacheKeyPrefix+testMarket.CatalogID+":pa:"+repository.GetStringFromInt(MaxSearchSuggestionsLimit), sharedSuggestions, SearchSuggestionsCacheTTL)
+
+	dbMock.ExpectQuery("SELECT search_keyword FROM product.recent_searches").
+		WithArgs(userID, "pa%", MaxSearchSuggestionsLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"search_keyword"}))
+
+	result, err = svc.GetSearchSuggestions(&userID, "pa", &largeLimit, testMarket, "")
+	if err != nil || len(result) != 3 {
+		t.Errorf("test failed: expected the cached suggestions, got %v, %v", suggestionTexts(result), err)
+	}
+
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Error("test failed:", err)
+	}
+}
+
+func TestGetProductSuggestionsFromIndex(t *testing.T) {
+
+	//data test
+	index := search.New(nil)
+	index.Replace([]search.Document{
+		{ID: "1", Name: "Vitamin C Panadol"},
+		{ID: "2", Name: "Panadol Extra"},
+		{ID: "3", Name: "Panadol Actifast"},
+		{ID: "4", Name: "Nurofen"},
+	})
+
+	svc := &SearchSuggestionService{
+		searchIndexService: &SearchIndexService{enabled: true, indexes: map[string]*search.Index{testMarket.CatalogID: index}},
+	}
+
+	var tests = []struct {
+		name     string
+		prefix   string
+		limit    int
+		expected []string
+	}{
+		{name: "leading names first, then names with the word", prefix: "pan", limit: 10, expected: []string{"3", "2", "1"}},
+		{name: "limited", prefix: "pan", limit: 1, expected: []string{"3"}},
+		{name: "no match", prefix: "ins", limit: 10, expected: []string{}},
+	}
+
+	for _, test := range tests {
+		result, err := svc.getProductSuggestions(test.prefix, test.limit, testMarket.CatalogID, "")
+		if err != nil {
+			t.Errorf("test failed: %s, error should be nil, got %v", test.name, err)
+			continue
+		}
+		if len(result) != len(test.expected) {
+			t.Errorf("test failed: %s, expected %d suggestions, got %d", test.name, len(test.expected), len(result))
+			continue
+		}
+		for i, suggestion := range result {
+			if GetString(suggestion.ID) != test.expected[i] || suggestion.Type != model.SearchSuggestionTypeProduct {
+				t.Errorf("test failed: %s, expected %v, got %s at %d", test.name, test.expected, GetString(suggestion.ID), i)
+			}
+		}
+	}
+}
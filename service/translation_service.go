@@ -0,0 +1,123 @@
+package service
+
+import (
+	"strings"
+
+	"mpmy-product-service/graph/model"
+)
+
+const (
+	// TranslationsXpKey is the xp key of translations by locale
+	TranslationsXpKey = "Translations"
+
+	// translated fields of a locale's translation
+	translatedName           = "Name"
+	translatedDescription    = "Description"
+	translatedTaxDescription = "TaxDescription"
+)
+
+// TranslationService resolves content from the translations kept in order cloud xp, e.g.
+// {"Translations": {"ms": {"Name": "...", "Description": "...", "TaxDescription": "..."}, "zh-CN": {...}}}.
+// Locales are tried in preference order, each followed by its more general locales, see LocaleFallbacks, and
+// content without a translation in any of them is returned as is
+type TranslationService struct{}
+
+func NewTranslationService() *TranslationService {
+	return &TranslationService{}
+}
+
+// ProductName returns the product's name in the first locale it is translated to
+func (svc *TranslationService) ProductName(product *model.ProductItem, locales []string) *string {
+	return translateString(productTranslations(product), locales, translatedName, product.Name)
+}
+
+// ProductDescription returns the product's description in the first locale it is translated to
+func (svc *TranslationService) ProductDescription(product *model.ProductItem, locales []string) *string {
+	return translateString(productTranslations(product), locales, translatedDescription, product.Description)
+}
+
+// ProductTax returns a copy of the product's tax with its description in the first locale it is translated to
+func (svc *TranslationService) ProductTax(xp *model.ProductXp, locales []string) *model.ProductTax {
+	if xp.Tax == nil {
+		return nil
+	}
+
+	tax := *xp.Tax
+	tax.Description = translateString(xp.Translations, locales, translatedTaxDescription, tax.Description)
+	return &tax
+}
+
+// CategoryName returns the category's name in the first locale it is translated to
+func (svc *TranslationService) CategoryName(category *model.CategoryItems, locales []string) string {
+	var translations map[string]interface{}
+	if xp, ok := category.Xp.(map[string]interface{}); ok {
+		translations, _ = xp[TranslationsXpKey].(map[string]interface{})
+	}
+
+	if name, ok := translatedField(translations, locales, translatedName); ok {
+		return name
+	}
+	return category.Name
+}
+
+// LocaleFallbacks expands preferred locales into the order translations are looked up in, every locale followed
+// by its more general locales before the next preference, e.g. "zh-Hant-MY", "en" gives "zh-Hant-MY", "zh-Hant",
+// "zh", "en". Underscores are read as hyphens and repeated locales are dropped
+func LocaleFallbacks(locales []string) []string {
+	var fallbacks []string
+	seen := make(map[string]bool)
+	for _, locale := range locales {
+		locale = strings.Trim(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+		for locale != "" {
+			if !seen[strings.ToLower(locale)] {
+				seen[strings.ToLower(locale)] = true
+				fallbacks = append(fallbacks, locale)
+			}
+
+			end := strings.LastIndex(locale, "-")
+			if end < 0 {
+				break
+			}
+			locale = locale[:end]
+		}
+	}
+	return fallbacks
+}
+
+func productTranslations(product *model.ProductItem) map[string]interface{} {
+	if product.Xp == nil {
+		return nil
+	}
+	return product.Xp.Translations
+}
+
+func translateString(translations map[string]interface{}, locales []string, field string, value *string) *string {
+	if translation, ok := translatedField(translations, locales, field); ok {
+		return &translation
+	}
+	return value
+}
+
+// translatedField returns the field of the first locale translating it, locales are matched case insensitively
+func translatedField(translations map[string]interface{}, locales []string, field string) (string, bool) {
+	if len(translations) == 0 {
+		return "", false
+	}
+
+	translationsByLocale := make(map[string]interface{}, len(translations))
+	for locale, translation := range translations {
+		translationsByLocale[strings.ToLower(strings.ReplaceAll(locale, "_", "-"))] = translation
+	}
+
+	for _, locale := range LocaleFallbacks(locales) {
+		translation, ok := translationsByLocale[strings.ToLower(locale)].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := translation[field].(string); ok && value != "" {
+			return value, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"mpmy-product-service/graph/model"
+)
+
+func TestLocaleFallbacks(t *testing.T) {
+
+	//data test
+	var tests = []struct {
+		name     string
+		locales  []string
+		expected []string
+	}{
+		{name: "general locales follow every preference", locales: []string{"zh-Hant-MY", "en"}, expected: []string{"zh-Hant-MY", "zh-Hant", "zh", "en"}},
+		{name: "repeated locales are dropped", locales: []string{"ms-MY", "ms", "MS-my"}, expected: []string{"ms-MY", "ms"}},
+		{name: "underscores are read as hyphens", locales: []string{"en_GB"}, expected: []string{"en-GB", "en"}},
+		{name: "empty locales are skipped", locales: []string{"", " ", "ms"}, expected: []string{"ms"}},
+	}
+
+	for _, test := range tests {
+		result := LocaleFallbacks(test.locales)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("test failed: %s, expected %v, got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestTranslateProduct(t *testing.T) {
+
+	//data test
+	var name = "Paracetamol 500mg"
+	var description = "Pain relief"
+	var taxDescription = "Sales tax"
+	var product = &model.ProductItem{
+		Name:        &name,
+		Description: &description,
+		Xp: &model.ProductXp{
+			Tax: &model.ProductTax{Description: &taxDescription},
+			Translations: map[string]interface{}{
+				"ms":    map[string]interface{}{"Name": "Parasetamol 500mg", "TaxDescription": "Cukai jualan"},
+				"zh_CN": map[string]interface{}{"Name": "扑热息痛 500mg", "Description": ""},
+			},
+		},
+	}
+
+	svc := NewTranslationService()
+
+	if result := svc.ProductName(product, []string{"ms-MY"}); GetString(result) != "Parasetamol 500mg" {
+		t.Error("test failed: name must fall back from ms-MY to ms, got", GetString(result))
+	}
+	if result := svc.ProductName(product, []string{"zh-cn", "ms"}); GetString(result) != "扑热息痛 500mg" {
+		t.Error("test failed: name must be translated to the preferred locale, got", GetString(result))
+	}
+	if result := svc.ProductDescription(product, []string{"zh-CN", "ms", "en"}); GetString(result) != description {
+		t.Error("test failed: untranslated description must be returned as is, got", GetString(result))
+	}
+	if result := svc.ProductName(product, nil); GetString(result) != name {
+		t.Error("test failed: name must be returned as is without locales, got", GetString(result))
+	}
+
+	tax := svc.ProductTax(product.Xp, []string{"ms"})
+	if GetString(tax.Description) != "Cukai jualan" {
+		t.Error("test failed: tax description must be translated, got", GetString(tax.Description))
+	}
+	if GetString(product.Xp.Tax.Description) != taxDescription {
+		t.Error("test failed: the product's tax must not be changed")
+	}
+}
+
+func TestTranslateCategoryName(t *testing.T) {
+
+	//data test
+	var category = &model.CategoryItems{
+		Name: "Vitamins",
+		Xp: map[string]interface{}{
+			TranslationsXpKey: map[string]interface{}{
+				"ms": map[string]interface{}{"Name": "Vitamin"},
+			},
+		},
+	}
+
+	svc := NewTranslationService()
+
+	if result := svc.CategoryName(category, []string{"ms-MY"}); result != "Vitamin" {
+		t.Error("test failed: category name must be translated, got", result)
+	}
+	if result := svc.CategoryName(category, []string{"en"}); result != "Vitamins" {
+		t.Error("test failed: untranslated category name must be returned as is, got", result)
+	}
+	if result := svc.CategoryName(&model.CategoryItems{Name: "Baby"}, []string{"ms"}); result != "Baby" {
+		t.Error("test failed: category without xp must keep its name, got", result)
+	}
+}